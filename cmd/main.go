@@ -2,58 +2,287 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/history"
 	"github.com/nagahshi/pos_go_weather_otel/internal/infra/otel"
 	"github.com/nagahshi/pos_go_weather_otel/internal/infra/web"
+	"github.com/nagahshi/pos_go_weather_otel/internal/logging"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
+	"github.com/nagahshi/pos_go_weather_otel/internal/stream"
+	"github.com/nagahshi/pos_go_weather_otel/internal/tenant"
 	"github.com/nagahshi/pos_go_weather_otel/internal/usecase"
+	"github.com/nagahshi/pos_go_weather_otel/internal/webhook"
 )
 
+// webhookMaxDeadLetters - entregas que falharam guardadas para consulta e reenvio
+const webhookMaxDeadLetters = 1000
+
+// version - versão do build, informada com -ldflags "-X main.version=..."; vazia, vale a registrada
+// pelo go build (versão do módulo ou revisão do git)
+var version string
+
 func main() {
+	// logs em JSON na saída padrão; após a configuração do OpenTelemetry, também enviados ao collector
+	logLevel, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	slog.SetDefault(logging.New(os.Stdout, logLevel))
+	if err != nil {
+		slog.Error("invalid log level", "env", "LOG_LEVEL", "value", os.Getenv("LOG_LEVEL"))
+		os.Exit(1)
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
-		log.Fatal("server port [PORT] not configured yet")
-		return
+		slog.Error("server port not configured yet", "env", "PORT")
+		os.Exit(1)
+	}
+	// OTEL_SERVICE_NAME é o nome padrão do OpenTelemetry; SERVICE_NAME segue aceito
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = os.Getenv("SERVICE_NAME")
 	}
-	serviceName := os.Getenv("SERVICE_NAME")
 	if serviceName == "" {
-		log.Fatal("service name [SERVICE_NAME] not configured yet")
-		return
+		slog.Error("service name not configured yet", "env", "OTEL_SERVICE_NAME")
+		os.Exit(1)
+	}
+
+	// por quanto tempo uma observação do provedor é reaproveitada; a WeatherAPI atualiza a cada 15 minutos
+	weatherCacheTTL := 15 * time.Minute
+	if value := os.Getenv("WEATHER_CACHE_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			slog.Error("invalid weather cache ttl", "env", "WEATHER_CACHE_TTL", "value", value)
+			os.Exit(1)
+		}
+		weatherCacheTTL = ttl
+	}
+
+	// intervalo entre as consultas compartilhadas pelos assinantes do stream SSE
+	streamInterval := 30 * time.Second
+	if value := os.Getenv("STREAM_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			slog.Error("invalid stream interval", "env", "STREAM_INTERVAL", "value", value)
+			os.Exit(1)
+		}
+		streamInterval = interval
+	}
+
+	// intervalo entre as avaliações das condições das assinaturas de webhook
+	webhookInterval := 5 * time.Minute
+	if value := os.Getenv("WEBHOOK_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			slog.Error("invalid webhook interval", "env", "WEBHOOK_INTERVAL", "value", value)
+			os.Exit(1)
+		}
+		webhookInterval = interval
+	}
+
+	// tentativas de entrega de cada webhook antes da dead letter
+	webhookMaxAttempts := 5
+	if value := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts <= 0 {
+			slog.Error("invalid webhook max attempts", "env", "WEBHOOK_MAX_ATTEMPTS", "value", value)
+			os.Exit(1)
+		}
+		webhookMaxAttempts = attempts
+	}
+
+	// espera antes da segunda tentativa, dobrada a cada nova tentativa
+	webhookRetryBackoff := 2 * time.Second
+	if value := os.Getenv("WEBHOOK_RETRY_BACKOFF"); value != "" {
+		backoff, err := time.ParseDuration(value)
+		if err != nil || backoff <= 0 {
+			slog.Error("invalid webhook retry backoff", "env", "WEBHOOK_RETRY_BACKOFF", "value", value)
+			os.Exit(1)
+		}
+		webhookRetryBackoff = backoff
+	}
+
+	// entregas de webhook à rede interna, para receptores no mesmo ambiente; desligado, só endereços públicos
+	webhookAllowPrivateNetworks := false
+	if value := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"); value != "" {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			slog.Error("invalid webhook private networks flag", "env", "WEBHOOK_ALLOW_PRIVATE_NETWORKS", "value", value)
+			os.Exit(1)
+		}
+		webhookAllowPrivateNetworks = allow
+	}
+
+	// assinaturas e dead letters de webhook, guardadas entre os restarts
+	webhookPath := os.Getenv("WEBHOOK_DB_PATH")
+	if webhookPath == "" {
+		webhookPath = "webhooks.db"
+	}
+	webhookStore, err := webhook.Open(webhookPath, webhookMaxDeadLetters)
+	if err != nil {
+		slog.Error("cant open webhooks", "env", "WEBHOOK_DB_PATH", "error", err)
+		os.Exit(1)
+	}
+	defer webhookStore.Close()
+
+	webhooks := webhook.NewScheduler(
+		webhookStore,
+		webhook.UseCases{
+			GetLatLonByCEP:          *usecase.NewGetLatLonByCEPUseCase(),
+			GetWeatherByCoordinates: *usecase.NewGetWeatherByCoordinatesUseCase(os.Getenv("HOST_SERVICE_B")),
+			GetForecast:             *usecase.NewGetForecastUseCase(os.Getenv("WEATHER_API_KEY")),
+		},
+		webhook.NewDispatcher(webhookStore, webhookMaxAttempts, webhookRetryBackoff, webhookAllowPrivateNetworks),
+		webhookInterval,
+	)
+
+	// CEPs consultados periodicamente e guardados no histórico local; vazio desabilita o histórico
+	var watchlist []string
+	for _, value := range strings.Split(os.Getenv("WATCHLIST_CEPS"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		CEP := strings.NewReplacer("-", "", ".", "").Replace(value)
+		if len(CEP) != 8 || strings.Trim(CEP, "0123456789") != "" {
+			slog.Error("invalid watchlist zipcode", "env", "WATCHLIST_CEPS", "value", value)
+			os.Exit(1)
+		}
+		watchlist = append(watchlist, CEP)
+	}
+
+	// intervalo entre as consultas da watchlist; a WeatherAPI atualiza a cada 15 minutos
+	watchlistInterval := 15 * time.Minute
+	if value := os.Getenv("WATCHLIST_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			slog.Error("invalid watchlist interval", "env", "WATCHLIST_INTERVAL", "value", value)
+			os.Exit(1)
+		}
+		watchlistInterval = interval
+	}
+
+	// por quanto tempo as leituras ficam no histórico; zero guarda para sempre
+	var historyRetention time.Duration
+	if value := os.Getenv("HISTORY_RETENTION"); value != "" {
+		retention, err := time.ParseDuration(value)
+		if err != nil || retention < 0 {
+			slog.Error("invalid history retention", "env", "HISTORY_RETENTION", "value", value)
+			os.Exit(1)
+		}
+		historyRetention = retention
+	}
+
+	var historyStore *history.Store
+	if len(watchlist) > 0 {
+		historyPath := os.Getenv("HISTORY_DB_PATH")
+		if historyPath == "" {
+			historyPath = "history.db"
+		}
+
+		store, err := history.Open(historyPath)
+		if err != nil {
+			slog.Error("cant open history", "env", "HISTORY_DB_PATH", "error", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		historyStore = store
 	}
 
 	handler := web.NewHandler(
 		*usecase.NewGetLatLonByCEPUseCase(),
-		*usecase.NewGetWeatherUseCase(os.Getenv("WEATHER_API_KEY")),
+		*usecase.NewGetWeatherByCEPUseCase(os.Getenv("HOST_SERVICE_B")),
+		*usecase.NewGetWeatherUseCase(os.Getenv("WEATHER_API_KEY"), weatherCacheTTL),
+		*usecase.NewGetAirQualityUseCase(os.Getenv("WEATHER_API_KEY"), os.Getenv("AIR_QUALITY_PROVIDER")),
+		*usecase.NewGetAstronomyUseCase(os.Getenv("WEATHER_API_KEY")),
+		stream.NewHub[dto.WeatherOutput](streamInterval),
+		*usecase.NewGetWeatherByCoordinatesUseCase(os.Getenv("HOST_SERVICE_B")),
+		*usecase.NewGetForecastUseCase(os.Getenv("WEATHER_API_KEY")),
+		webhooks,
+		*usecase.NewGetHistoryByCEPUseCase(historyStore),
 	)
 
+	// limite de assinaturas simultâneas por conexão WebSocket
+	if value := os.Getenv("WS_MAX_SUBSCRIPTIONS"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			slog.Error("invalid websocket subscription limit", "env", "WS_MAX_SUBSCRIPTIONS", "value", value)
+			os.Exit(1)
+		}
+		handler.WebSocketMaxSubscriptions = limit
+	}
+
+	// chaves de API dos clientes e seus tenants; o valor não vai para o log, são segredos
+	apiKeys, err := tenant.ParseAPIKeys(os.Getenv("TENANT_API_KEYS"))
+	if err != nil {
+		slog.Error("invalid tenant api keys", "env", "TENANT_API_KEYS", "error", err)
+		os.Exit(1)
+	}
+	// pares que podem informar o tenant no header X-Tenant-ID ou no baggage (o serviço A, para o B)
+	trustedPeers, err := tenant.ParseTrustedPeers(os.Getenv("TENANT_TRUSTED_PEERS"))
+	if err != nil {
+		slog.Error("invalid tenant trusted peers", "env", "TENANT_TRUSTED_PEERS", "error", err)
+		os.Exit(1)
+	}
+	// tenants com série própria nas métricas: os das chaves de API e os de TENANT_IDS
+	tenantIDs, err := tenant.ParseIDs(os.Getenv("TENANT_IDS"))
+	if err != nil {
+		slog.Error("invalid tenant ids", "env", "TENANT_IDS", "error", err)
+		os.Exit(1)
+	}
+	for _, tenantID := range apiKeys {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	metrics.SetTenants(tenantIDs)
+
 	ctx := context.Background()
 	// Setup OTel SDK
-	otelShutdown, err := otel.SetupOTelSDK(serviceName, ctx)
+	otelShutdown, err := otel.SetupOTelSDK(serviceName, version, ctx)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("cant setup opentelemetry", "error", err)
+		os.Exit(1)
 	}
 	defer otelShutdown(ctx)
 
+	// sem assinaturas a rodada não consulta nada, então o serviço B também pode manter a rotina
+	go webhooks.Run(ctx)
+
+	if historyStore != nil {
+		getWeather := usecase.NewGetWeatherByCEPUseCase(os.Getenv("HOST_SERVICE_B"))
+		poller := history.NewPoller(historyStore, func(ctx context.Context, CEP string) (dto.WeatherOutput, error) {
+			return getWeather.Execute(ctx, dto.WeatherByCEPInput{CEP: CEP, Query: url.Values{"preset": {"metric"}}})
+		}, watchlist, watchlistInterval, historyRetention)
+		go poller.Run(ctx)
+	}
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/cep", handler.GetLocationByCEP)
-	mux.HandleFunc("/weather", handler.GetWeatherByLocal)
+	for _, route := range handler.Routes() {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
 
 	srv := &http.Server{
 		Addr:         ":" + port,
 		BaseContext:  func(_ net.Listener) context.Context { return ctx },
 		ReadTimeout:  time.Second,
 		WriteTimeout: 10 * time.Second,
-		Handler:      otelhttp.NewHandler(mux, "/"),
+		Handler:      otelhttp.NewHandler(web.Tenant(web.Metrics(mux), apiKeys, trustedPeers), "/"),
 	}
 
+	slog.Info("server listening", "service", serviceName, "port", port)
 	err = srv.ListenAndServe()
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("server stopped", "error", err)
+		// os.Exit não roda os defers: envia os traces, métricas e logs pendentes antes de sair
+		otelShutdown(ctx)
+		os.Exit(1)
 	}
 }
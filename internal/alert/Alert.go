@@ -0,0 +1,102 @@
+// Package alert identifica condições de risco nas leituras de clima, com limiares fixos de
+// referência (NOAA, Environment Canada, Beaufort e US EPA).
+package alert
+
+import (
+	"math"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/units"
+)
+
+const (
+	TypeHeat       = "heat"
+	TypeCold       = "cold"
+	TypeWind       = "wind"
+	TypeAirQuality = "air_quality"
+
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// rule - limiares de uma condição; below indica que o risco é abaixo do limiar (frio)
+type rule struct {
+	kind     string
+	warning  float64
+	critical float64
+	below    bool
+	value    func(output dto.WeatherOutput) (float64, bool)
+}
+
+var rules = []rule{
+	{
+		// índice de calor da NOAA: "danger" a partir de 103 °F e "extreme danger" a partir de 125 °F
+		kind: TypeHeat, warning: 39.4, critical: 51.7,
+		value: func(output dto.WeatherOutput) (float64, bool) {
+			return units.TemperatureToCelsius(temperatureUnit(output), output.HeatIndex), true
+		},
+	},
+	{
+		// wind chill da Environment Canada: risco moderado abaixo de -10 °C e congelamento da pele abaixo de -28 °C
+		kind: TypeCold, warning: -10, critical: -28, below: true,
+		value: func(output dto.WeatherOutput) (float64, bool) {
+			return units.TemperatureToCelsius(temperatureUnit(output), output.WindChill), true
+		},
+	},
+	{
+		// escala Beaufort: ventania a partir do grau 8 e tempestade a partir do 10
+		kind: TypeWind, warning: 8, critical: 10,
+		value: func(output dto.WeatherOutput) (float64, bool) {
+			return float64(output.Beaufort), true
+		},
+	},
+	{
+		// índice US EPA: "unhealthy" a partir de 4 e "very unhealthy" a partir de 5
+		kind: TypeAirQuality, warning: 4, critical: 5,
+		value: func(output dto.WeatherOutput) (float64, bool) {
+			if output.AirQuality == nil {
+				return 0, false
+			}
+
+			return float64(output.AirQuality.USEPAIndex), true
+		},
+	},
+}
+
+// temperatureUnit - unidade das temperaturas da leitura; sem Units a leitura está em °C
+func temperatureUnit(output dto.WeatherOutput) units.TemperatureUnit {
+	if output.Units == nil {
+		return units.Celsius
+	}
+
+	return units.TemperatureUnit(output.Units.Temperature)
+}
+
+// Evaluate - alertas ativos na leitura, sem mensagem; a mensagem é traduzida por quem entrega
+func Evaluate(output dto.WeatherOutput) []dto.Alert {
+	var alerts []dto.Alert
+	for _, r := range rules {
+		value, ok := r.value(output)
+		if !ok {
+			continue
+		}
+		value = math.Round(value*100) / 100
+
+		exceeds := func(threshold float64) bool {
+			if r.below {
+				return value <= threshold
+			}
+
+			return value >= threshold
+		}
+
+		switch {
+		case exceeds(r.critical):
+			alerts = append(alerts, dto.Alert{Type: r.kind, Severity: SeverityCritical, Value: value, Threshold: r.critical})
+		case exceeds(r.warning):
+			alerts = append(alerts, dto.Alert{Type: r.kind, Severity: SeverityWarning, Value: value, Threshold: r.warning})
+		}
+	}
+
+	return alerts
+}
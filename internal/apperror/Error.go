@@ -0,0 +1,88 @@
+// Package apperror define o modelo de erros tipados compartilhado pelas camadas de service,
+// usecase e handler. Cada erro carrega um código estável que os clientes podem usar para decidir
+// o que fazer, sem depender do texto da mensagem.
+package apperror
+
+import "errors"
+
+type Code string
+
+const (
+	CodeInvalidRequest      Code = "INVALID_REQUEST"
+	CodeInvalidCEP          Code = "INVALID_CEP"
+	CodeUnauthorized        Code = "UNAUTHORIZED"
+	CodeNotAcceptable       Code = "NOT_ACCEPTABLE"
+	CodeCEPNotFound         Code = "CEP_NOT_FOUND"
+	CodeLocationNotFound    Code = "LOCATION_NOT_FOUND"
+	CodeNotFound            Code = "NOT_FOUND"
+	CodeUpstreamUnavailable Code = "UPSTREAM_UNAVAILABLE"
+	CodeUpstreamTimeout     Code = "UPSTREAM_TIMEOUT"
+	CodeUpstreamRateLimited Code = "UPSTREAM_RATE_LIMITED"
+	CodeQuotaExceeded       Code = "QUOTA_EXCEEDED"
+	CodeMissingKey          Code = "MISSING_API_KEY"
+	CodeInternal            Code = "INTERNAL_ERROR"
+)
+
+// sentinelas para comparação com errors.Is; qualquer Error com o mesmo código é considerado igual,
+// então erros criados com mensagem e causa próprias continuam casando com a sentinela
+var (
+	ErrInvalidRequest      = New(CodeInvalidRequest, "requisição inválida")
+	ErrInvalidCEP          = New(CodeInvalidCEP, "CEP inválido")
+	ErrUnauthorized        = New(CodeUnauthorized, "cliente não identificado")
+	ErrNotAcceptable       = New(CodeNotAcceptable, "formato de resposta não suportado")
+	ErrCEPNotFound         = New(CodeCEPNotFound, "CEP não encontrado")
+	ErrLocationNotFound    = New(CodeLocationNotFound, "local não encontrado")
+	ErrNotFound            = New(CodeNotFound, "recurso não encontrado")
+	ErrUpstreamUnavailable = New(CodeUpstreamUnavailable, "serviço externo indisponível")
+	ErrUpstreamTimeout     = New(CodeUpstreamTimeout, "tempo esgotado ao consultar serviço externo")
+	ErrUpstreamRateLimited = New(CodeUpstreamRateLimited, "limite de requisições do serviço externo atingido")
+	ErrQuotaExceeded       = New(CodeQuotaExceeded, "cota do serviço externo excedida")
+	ErrMissingKey          = New(CodeMissingKey, "chave de acesso não informada")
+	ErrInternal            = New(CodeInternal, "erro interno")
+)
+
+// Error - erro com código estável; Message descreve o problema e Err guarda a causa original
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+// New - cria um erro tipado sem causa
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap - cria um erro tipado preservando a causa, acessível por errors.Unwrap
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is - permite errors.Is(err, apperror.ErrCEPNotFound) comparando pelo código
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+
+	return ok && t.Code == e.Code
+}
+
+// CodeOf - código do primeiro Error na cadeia; erros não tipados viram INTERNAL_ERROR
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+
+	return CodeInternal
+}
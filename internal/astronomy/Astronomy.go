@@ -0,0 +1,130 @@
+// Package astronomy calcula localmente dados de sol e lua a partir de latitude e longitude,
+// sem depender de nenhuma API externa.
+package astronomy
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	j2000         = 2451545.0
+	synodicMonth  = 29.530588853
+	knownNewMoon  = 2451550.1 // lua nova de referência: 2000-01-06 18:14 UTC
+	obliquity     = 23.4397
+	sunriseAltDeg = -0.833 // refração atmosférica + raio aparente do disco solar
+)
+
+// Sun - horários solares de um dia para uma coordenada
+type Sun struct {
+	Sunrise   time.Time
+	Sunset    time.Time
+	SolarNoon time.Time
+	DayLength time.Duration
+	// PolarDay e PolarNight indicam que o sol não nasce ou não se põe no dia
+	PolarDay   bool
+	PolarNight bool
+}
+
+// Moon - fase da lua em um instante
+type Moon struct {
+	Phase        string
+	Illumination float64
+	AgeDays      float64
+}
+
+// SunTimes - calcula nascer, pôr do sol e meio-dia solar pela equação do nascer do sol (NOAA simplificada).
+// A data considerada é o dia civil de date no fuso de date; os horários retornam nesse mesmo fuso.
+func SunTimes(date time.Time, latitude float64, longitude float64) Sun {
+	loc := date.Location()
+	noonUTC := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+
+	n := math.Round(julianDay(noonUTC) - j2000 + 0.0008)
+	jStar := n - longitude/360
+
+	m := normalizeDegrees(357.5291 + 0.98560028*jStar)
+	c := 1.9148*sin(m) + 0.0200*sin(2*m) + 0.0003*sin(3*m)
+	lambda := normalizeDegrees(m + c + 180 + 102.9372)
+	jTransit := j2000 + jStar + 0.0053*sin(m) - 0.0069*sin(2*lambda)
+
+	sinDelta := sin(lambda) * sin(obliquity)
+	cosDelta := math.Cos(math.Asin(sinDelta))
+	cosOmega := (sin(sunriseAltDeg) - sin(latitude)*sinDelta) / (cos(latitude) * cosDelta)
+
+	sun := Sun{SolarNoon: fromJulianDay(jTransit).In(loc)}
+	switch {
+	case cosOmega > 1:
+		sun.PolarNight = true
+		return sun
+	case cosOmega < -1:
+		sun.PolarDay = true
+		sun.DayLength = 24 * time.Hour
+		return sun
+	}
+
+	omega := math.Acos(cosOmega) * 180 / math.Pi
+	sun.Sunrise = fromJulianDay(jTransit - omega/360).In(loc)
+	sun.Sunset = fromJulianDay(jTransit + omega/360).In(loc)
+	sun.DayLength = sun.Sunset.Sub(sun.Sunrise).Round(time.Second)
+
+	return sun
+}
+
+// MoonPhase - calcula idade, iluminação e nome da fase da lua no instante informado
+func MoonPhase(at time.Time) Moon {
+	age := math.Mod(julianDay(at.UTC())-knownNewMoon, synodicMonth)
+	if age < 0 {
+		age += synodicMonth
+	}
+
+	return Moon{
+		Phase:        phaseName(age),
+		Illumination: (1 - math.Cos(2*math.Pi*age/synodicMonth)) / 2,
+		AgeDays:      age,
+	}
+}
+
+// phaseName - divide o mês sinódico em oito fases, centradas nas fases principais
+func phaseName(age float64) string {
+	names := []string{
+		"New Moon",
+		"Waxing Crescent",
+		"First Quarter",
+		"Waxing Gibbous",
+		"Full Moon",
+		"Waning Gibbous",
+		"Last Quarter",
+		"Waning Crescent",
+	}
+
+	index := int(math.Floor(age/synodicMonth*8+0.5)) % 8
+
+	return names[index]
+}
+
+func julianDay(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+func fromJulianDay(jd float64) time.Time {
+	seconds := (jd - 2440587.5) * 86400
+
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC().Round(time.Second)
+}
+
+func normalizeDegrees(d float64) float64 {
+	d = math.Mod(d, 360)
+	if d < 0 {
+		d += 360
+	}
+
+	return d
+}
+
+func sin(deg float64) float64 {
+	return math.Sin(deg * math.Pi / 180)
+}
+
+func cos(deg float64) float64 {
+	return math.Cos(deg * math.Pi / 180)
+}
@@ -0,0 +1,177 @@
+package astronomy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loc
+}
+
+// TestSunTimes - horários de almanaque (timeanddate.com), com tolerância para a equação simplificada
+func TestSunTimes(t *testing.T) {
+	const tolerance = 4 * time.Minute
+
+	tests := []struct {
+		name      string
+		date      time.Time
+		latitude  float64
+		longitude float64
+		sunrise   string
+		sunset    string
+	}{
+		{
+			name:      "Londres, solstício de junho",
+			date:      time.Date(2024, time.June, 20, 0, 0, 0, 0, mustLocation(t, "Europe/London")),
+			latitude:  51.5074,
+			longitude: -0.1278,
+			sunrise:   "04:43",
+			sunset:    "21:21",
+		},
+		{
+			name:      "São Paulo, solstício de junho",
+			date:      time.Date(2024, time.June, 21, 0, 0, 0, 0, mustLocation(t, "America/Sao_Paulo")),
+			latitude:  -23.5505,
+			longitude: -46.6333,
+			sunrise:   "06:47",
+			sunset:    "17:28",
+		},
+		{
+			name:      "Nova York, equinócio de março",
+			date:      time.Date(2024, time.March, 20, 0, 0, 0, 0, mustLocation(t, "America/New_York")),
+			latitude:  40.7128,
+			longitude: -74.0060,
+			sunrise:   "06:59",
+			sunset:    "19:11",
+		},
+		{
+			name:      "Tóquio, solstício de dezembro",
+			date:      time.Date(2024, time.December, 21, 0, 0, 0, 0, mustLocation(t, "Asia/Tokyo")),
+			latitude:  35.6762,
+			longitude: 139.6503,
+			sunrise:   "06:47",
+			sunset:    "16:32",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sun := SunTimes(test.date, test.latitude, test.longitude)
+			if sun.PolarDay || sun.PolarNight {
+				t.Fatalf("dia polar = %v, noite polar = %v, esperado nascer e pôr do sol", sun.PolarDay, sun.PolarNight)
+			}
+
+			for _, clock := range []struct {
+				name string
+				got  time.Time
+				want string
+			}{
+				{"nascer do sol", sun.Sunrise, test.sunrise},
+				{"pôr do sol", sun.Sunset, test.sunset},
+			} {
+				parsed, _ := time.Parse("15:04", clock.want)
+				want := time.Date(test.date.Year(), test.date.Month(), test.date.Day(), parsed.Hour(), parsed.Minute(), 0, 0, test.date.Location())
+				if diff := clock.got.Sub(want); diff < -tolerance || diff > tolerance {
+					t.Errorf("%s = %s, esperado %s (diferença %s)", clock.name, clock.got.Format(time.RFC3339), want.Format(time.RFC3339), diff)
+				}
+				if clock.got.Location() != test.date.Location() {
+					t.Errorf("%s no fuso %s, esperado %s", clock.name, clock.got.Location(), test.date.Location())
+				}
+			}
+
+			if sun.DayLength != sun.Sunset.Sub(sun.Sunrise).Round(time.Second) {
+				t.Errorf("duração do dia = %s, esperado %s", sun.DayLength, sun.Sunset.Sub(sun.Sunrise))
+			}
+			if !sun.SolarNoon.After(sun.Sunrise) || !sun.SolarNoon.Before(sun.Sunset) {
+				t.Errorf("meio-dia solar %s fora do dia", sun.SolarNoon.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestSunTimesPolar(t *testing.T) {
+	oslo := mustLocation(t, "Europe/Oslo")
+
+	tests := []struct {
+		name       string
+		date       time.Time
+		latitude   float64
+		longitude  float64
+		polarDay   bool
+		polarNight bool
+	}{
+		{name: "Tromsø, sol da meia-noite", date: time.Date(2024, time.June, 21, 0, 0, 0, 0, oslo), latitude: 69.6492, longitude: 18.9553, polarDay: true},
+		{name: "Tromsø, noite polar", date: time.Date(2024, time.December, 21, 0, 0, 0, 0, oslo), latitude: 69.6492, longitude: 18.9553, polarNight: true},
+		{name: "Longyearbyen, sol da meia-noite", date: time.Date(2024, time.May, 1, 0, 0, 0, 0, oslo), latitude: 78.2232, longitude: 15.6267, polarDay: true},
+		{name: "Polo Sul, noite polar", date: time.Date(2024, time.June, 21, 0, 0, 0, 0, time.UTC), latitude: -90, longitude: 0, polarNight: true},
+		{name: "Tromsø, equinócio", date: time.Date(2024, time.March, 20, 0, 0, 0, 0, oslo), latitude: 69.6492, longitude: 18.9553},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sun := SunTimes(test.date, test.latitude, test.longitude)
+
+			if sun.PolarDay != test.polarDay || sun.PolarNight != test.polarNight {
+				t.Fatalf("dia polar = %v, noite polar = %v, esperado %v e %v", sun.PolarDay, sun.PolarNight, test.polarDay, test.polarNight)
+			}
+
+			switch {
+			case test.polarDay:
+				if !sun.Sunrise.IsZero() || !sun.Sunset.IsZero() || sun.DayLength != 24*time.Hour {
+					t.Errorf("dia polar com nascer %s, pôr %s e duração %s", sun.Sunrise, sun.Sunset, sun.DayLength)
+				}
+			case test.polarNight:
+				if !sun.Sunrise.IsZero() || !sun.Sunset.IsZero() || sun.DayLength != 0 {
+					t.Errorf("noite polar com nascer %s, pôr %s e duração %s", sun.Sunrise, sun.Sunset, sun.DayLength)
+				}
+			default:
+				if sun.Sunrise.IsZero() || sun.Sunset.IsZero() {
+					t.Error("sem nascer ou pôr do sol fora do período polar")
+				}
+			}
+		})
+	}
+}
+
+// TestMoonPhase - fases principais de junho e julho de 2024 (USNO), em UTC
+func TestMoonPhase(t *testing.T) {
+	tests := []struct {
+		name         string
+		at           time.Time
+		phase        string
+		illumination float64
+	}{
+		{name: "quarto crescente", at: time.Date(2024, time.June, 14, 5, 18, 0, 0, time.UTC), phase: "First Quarter", illumination: 0.5},
+		{name: "lua cheia", at: time.Date(2024, time.June, 22, 1, 8, 0, 0, time.UTC), phase: "Full Moon", illumination: 1},
+		{name: "quarto minguante", at: time.Date(2024, time.June, 28, 21, 53, 0, 0, time.UTC), phase: "Last Quarter", illumination: 0.5},
+		{name: "lua nova", at: time.Date(2024, time.July, 5, 22, 57, 0, 0, time.UTC), phase: "New Moon", illumination: 0},
+		{name: "crescente entre nova e quarto", at: time.Date(2024, time.July, 9, 12, 0, 0, 0, time.UTC), phase: "Waxing Crescent", illumination: 0.15},
+		{name: "gibosa minguante", at: time.Date(2024, time.June, 25, 12, 0, 0, 0, time.UTC), phase: "Waning Gibbous", illumination: 0.8},
+		{name: "antes da lua nova de referência", at: time.Date(1999, time.December, 22, 17, 31, 0, 0, time.UTC), phase: "Full Moon", illumination: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			moon := MoonPhase(test.at)
+
+			if moon.Phase != test.phase {
+				t.Errorf("fase = %q (idade %.2f dias), esperado %q", moon.Phase, moon.AgeDays, test.phase)
+			}
+			if math.Abs(moon.Illumination-test.illumination) > 0.1 {
+				t.Errorf("iluminação = %.2f, esperado %.2f", moon.Illumination, test.illumination)
+			}
+			if moon.AgeDays < 0 || moon.AgeDays >= synodicMonth {
+				t.Errorf("idade = %.2f dias, fora do mês sinódico", moon.AgeDays)
+			}
+		})
+	}
+}
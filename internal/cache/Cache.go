@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// TTL - cache em memória com expiração por entrada, seguro para uso concorrente.
+// Um TTL zero desabilita o cache: Set não guarda nada e Get nunca encontra.
+type TTL[V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]entry[V]
+}
+
+// New - cria um cache em que cada entrada vale por ttl
+func New[V any](ttl time.Duration) *TTL[V] {
+	return &TTL[V]{
+		ttl:     ttl,
+		entries: map[string]entry[V]{},
+	}
+}
+
+// Enabled - indica se o cache guarda entradas
+func (c *TTL[V]) Enabled() bool {
+	return c != nil && c.ttl > 0
+}
+
+// Get - valor ainda válido para a chave e o momento em que expira
+func (c *TTL[V]) Get(key string) (value V, expiresAt time.Time, ok bool) {
+	if !c.Enabled() {
+		return value, expiresAt, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.entries[key]
+	if !ok {
+		return value, expiresAt, false
+	}
+	if !time.Now().Before(cached.expiresAt) {
+		delete(c.entries, key)
+		return value, expiresAt, false
+	}
+
+	return cached.value, cached.expiresAt, true
+}
+
+// Set - guarda o valor pelo TTL do cache e devolve quando ele expira; entradas vencidas são
+// descartadas a cada escrita para o mapa não crescer com localidades que não são mais consultadas
+func (c *TTL[V]) Set(key string, value V) time.Time {
+	if !c.Enabled() {
+		return time.Time{}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, cached := range c.entries {
+		if !now.Before(cached.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry[V]{value: value, expiresAt: expiresAt}
+
+	return expiresAt
+}
@@ -0,0 +1,170 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer - escopo de instrumentação dos lotes dos dataloaders
+var tracer = otel.Tracer("github.com/nagahshi/pos_go_weather_otel/internal/dataloader")
+
+// ErrNotLoaded - a BatchFunc não devolveu resultado para a chave
+var ErrNotLoaded = errors.New("chave não carregada pelo lote")
+
+// Result - valor ou erro de uma chave do lote
+type Result[V any] struct {
+	Value V
+	Err   error
+}
+
+// BatchFunc - carrega as chaves de um lote de uma vez; chaves ausentes do mapa retornam ErrNotLoaded
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) map[K]Result[V]
+
+// call - carga de uma chave, compartilhada por todos que pediram a mesma chave
+type call[V any] struct {
+	done   chan struct{}
+	result Result[V]
+}
+
+// batch - chaves acumuladas até o próximo despacho, com os spans de quem as pediu
+type batch[K comparable, V any] struct {
+	keys  []K
+	calls []*call[V]
+	links []trace.Link
+
+	// o timer e o limite de tamanho podem disparar o mesmo lote; só o primeiro executa
+	dispatched bool
+}
+
+// Loader - agrupa as chaves pedidas dentro de uma janela curta em uma única chamada à BatchFunc e
+// memoriza o resultado de cada chave. É feito para viver durante uma requisição: o cache não expira.
+type Loader[K comparable, V any] struct {
+	ctx      context.Context
+	name     string
+	fetch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	calls   map[K]*call[V]
+	pending *batch[K, V]
+}
+
+// New - cria um loader ligado ao contexto da requisição; wait é a janela de agrupamento e maxBatch
+// o tamanho que despacha o lote antes do fim da janela (zero não limita)
+func New[K comparable, V any](ctx context.Context, name string, fetch BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{
+		ctx:      ctx,
+		name:     name,
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		calls:    map[K]*call[V]{},
+	}
+}
+
+// Load - valor da chave, aguardando o lote em que ela foi incluída
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	c, ok := l.calls[key]
+	if !ok {
+		c = &call[V]{done: make(chan struct{})}
+		l.calls[key] = c
+
+		if l.pending == nil {
+			l.pending = &batch[K, V]{}
+			pending := l.pending
+			time.AfterFunc(l.wait, func() { l.dispatch(pending) })
+		}
+		l.pending.keys = append(l.pending.keys, key)
+		l.pending.calls = append(l.pending.calls, c)
+		l.pending.links = append(l.pending.links, trace.LinkFromContext(ctx))
+
+		if l.maxBatch > 0 && len(l.pending.keys) >= l.maxBatch {
+			pending := l.pending
+			l.pending = nil
+			go l.dispatch(pending)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.result.Value, c.result.Err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch - executa o lote uma única vez, no contexto da requisição e com link para cada pedido
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if b.dispatched {
+		l.mu.Unlock()
+		return
+	}
+	b.dispatched = true
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	ctx, spanBatch := tracer.Start(
+		l.ctx,
+		"dataloader_batch",
+		trace.WithLinks(b.links...),
+		trace.WithAttributes(attribute.String("dataloader", l.name), attribute.Int("batch.size", len(b.keys))),
+	)
+	defer spanBatch.End()
+
+	results := l.fetch(ctx, b.keys)
+	for i, key := range b.keys {
+		result, ok := results[key]
+		if !ok {
+			result = Result[V]{Err: ErrNotLoaded}
+		}
+		b.calls[i].result = result
+		close(b.calls[i].done)
+	}
+
+	spanBatch.AddEvent("batch loaded", trace.WithAttributes(attribute.Int("results", len(results))))
+}
+
+// Concurrent - BatchFunc para fontes sem consulta em lote: as chaves, já sem repetição, são
+// carregadas em paralelo com load, no máximo limit ao mesmo tempo (limit menor que 1 vale 1)
+func Concurrent[K comparable, V any](load func(ctx context.Context, key K) (V, error), limit int) BatchFunc[K, V] {
+	if limit < 1 {
+		limit = 1
+	}
+
+	return func(ctx context.Context, keys []K) map[K]Result[V] {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		results := make(map[K]Result[V], len(keys))
+		semaphore := make(chan struct{}, limit)
+
+		for _, key := range keys {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(key K) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				value, err := load(ctx, key)
+				mu.Lock()
+				results[key] = Result[V]{Value: value, Err: err}
+				mu.Unlock()
+			}(key)
+		}
+		wg.Wait()
+
+		return results
+	}
+}
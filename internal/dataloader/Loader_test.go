@@ -0,0 +1,198 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fetchRecorder - BatchFunc que guarda as chaves de cada lote e devolve o dobro de cada chave,
+// exceto as de missing
+type fetchRecorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	missing map[int]bool
+	err     error
+}
+
+func (f *fetchRecorder) fetch(ctx context.Context, keys []int) map[int]Result[int] {
+	f.mu.Lock()
+	f.batches = append(f.batches, slices.Clone(keys))
+	f.mu.Unlock()
+
+	results := map[int]Result[int]{}
+	for _, key := range keys {
+		if f.missing[key] {
+			continue
+		}
+		results[key] = Result[int]{Value: key * 2, Err: f.err}
+	}
+
+	return results
+}
+
+// loadAll - Load de cada chave em paralelo, com os resultados na ordem das chaves
+func loadAll(loader *Loader[int, int], keys []int) ([]int, []error) {
+	values := make([]int, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i, key int) {
+			defer wg.Done()
+			values[i], errs[i] = loader.Load(context.Background(), key)
+		}(i, key)
+	}
+	wg.Wait()
+
+	return values, errs
+}
+
+func TestLoaderBatchesAndDeduplicates(t *testing.T) {
+	recorder := &fetchRecorder{}
+	loader := New(context.Background(), "test", recorder.fetch, 20*time.Millisecond, 0)
+
+	values, errs := loadAll(loader, []int{1, 2, 3, 2, 1, 1})
+
+	for i, want := range []int{2, 4, 6, 4, 2, 2} {
+		if errs[i] != nil || values[i] != want {
+			t.Errorf("Load %d = %d, %v, esperado %d", i, values[i], errs[i], want)
+		}
+	}
+	if len(recorder.batches) != 1 {
+		t.Fatalf("%d lotes, esperado 1: %v", len(recorder.batches), recorder.batches)
+	}
+	keys := slices.Clone(recorder.batches[0])
+	slices.Sort(keys)
+	if !slices.Equal(keys, []int{1, 2, 3}) {
+		t.Errorf("chaves do lote = %v, esperado cada chave uma vez", recorder.batches[0])
+	}
+
+	// chaves já carregadas vêm do cache, sem novo lote
+	if value, err := loader.Load(context.Background(), 3); err != nil || value != 6 {
+		t.Errorf("Load(3) do cache = %d, %v", value, err)
+	}
+	if len(recorder.batches) != 1 {
+		t.Errorf("%d lotes depois do cache, esperado 1", len(recorder.batches))
+	}
+}
+
+func TestLoaderErrorFanOut(t *testing.T) {
+	errFetch := errors.New("provedor indisponível")
+	recorder := &fetchRecorder{err: errFetch, missing: map[int]bool{3: true}}
+	loader := New(context.Background(), "test", recorder.fetch, 20*time.Millisecond, 0)
+
+	_, errs := loadAll(loader, []int{1, 2, 1, 3})
+
+	for i, want := range []error{errFetch, errFetch, errFetch, ErrNotLoaded} {
+		if !errors.Is(errs[i], want) {
+			t.Errorf("Load %d: err = %v, esperado %v", i, errs[i], want)
+		}
+	}
+	if len(recorder.batches) != 1 {
+		t.Errorf("%d lotes, esperado 1", len(recorder.batches))
+	}
+}
+
+func TestLoaderMaxBatch(t *testing.T) {
+	recorder := &fetchRecorder{}
+	loader := New(context.Background(), "test", recorder.fetch, 20*time.Millisecond, 2)
+
+	keys := []int{1, 2, 3, 4, 5}
+	values, errs := loadAll(loader, keys)
+
+	for i, key := range keys {
+		if errs[i] != nil || values[i] != key*2 {
+			t.Errorf("Load(%d) = %d, %v", key, values[i], errs[i])
+		}
+	}
+	total := 0
+	for _, batch := range recorder.batches {
+		if len(batch) > 2 {
+			t.Errorf("lote %v acima do limite de 2 chaves", batch)
+		}
+		total += len(batch)
+	}
+	if total != len(keys) {
+		t.Errorf("%d chaves carregadas nos lotes %v, esperado %d", total, recorder.batches, len(keys))
+	}
+}
+
+func TestLoaderContextCanceled(t *testing.T) {
+	loader := New(context.Background(), "test", (&fetchRecorder{}).fetch, time.Hour, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := loader.Load(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, esperado context.Canceled", err)
+	}
+}
+
+func TestConcurrentLimit(t *testing.T) {
+	const limit = 3
+
+	var running, peak atomic.Int32
+	fetch := Concurrent(func(ctx context.Context, key int) (int, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			observed := peak.Load()
+			if current <= observed || peak.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if key == 4 {
+			return 0, errors.New("chave 4 falhou")
+		}
+		return key * 2, nil
+	}, limit)
+
+	keys := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	results := fetch(context.Background(), keys)
+
+	if got := peak.Load(); got > limit {
+		t.Errorf("%d cargas simultâneas, esperado no máximo %d", got, limit)
+	}
+	if len(results) != len(keys) {
+		t.Fatalf("%d resultados, esperado %d", len(results), len(keys))
+	}
+	for _, key := range keys {
+		result := results[key]
+		if key == 4 {
+			if result.Err == nil {
+				t.Error("chave 4 sem o erro da carga")
+			}
+			continue
+		}
+		if result.Err != nil || result.Value != key*2 {
+			t.Errorf("chave %d = %+v", key, result)
+		}
+	}
+}
+
+func TestConcurrentLimitBelowOne(t *testing.T) {
+	var running, peak atomic.Int32
+	fetch := Concurrent(func(ctx context.Context, key int) (int, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		if current > peak.Load() {
+			peak.Store(current)
+		}
+		time.Sleep(time.Millisecond)
+		return key, nil
+	}, 0)
+
+	fetch(context.Background(), []int{1, 2, 3, 4})
+
+	if got := peak.Load(); got != 1 {
+		t.Errorf("%d cargas simultâneas com limite 0, esperado 1", got)
+	}
+}
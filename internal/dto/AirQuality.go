@@ -0,0 +1,17 @@
+package dto
+
+type AirQualityInput struct {
+	Latitude  string
+	Longitude string
+}
+
+type AirQualityOutput struct {
+	PM25         float64 `json:"pm2_5" xml:"pm2_5"`
+	PM10         float64 `json:"pm10" xml:"pm10"`
+	O3           float64 `json:"o3" xml:"o3"`
+	NO2          float64 `json:"no2" xml:"no2"`
+	USEPAIndex   int     `json:"us_epa_index" xml:"us_epa_index"`
+	GBDefraIndex int     `json:"gb_defra_index" xml:"gb_defra_index"`
+	Category     string  `json:"category" xml:"category"`
+	Provider     string  `json:"provider" xml:"provider"`
+}
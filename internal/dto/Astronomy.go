@@ -0,0 +1,35 @@
+package dto
+
+import "time"
+
+type AstronomyInput struct {
+	Latitude   string
+	Longitude  string
+	Date       time.Time
+	CrossCheck bool
+}
+
+type AstronomyOutput struct {
+	City             string               `json:"city"`
+	Date             string               `json:"date"`
+	Timezone         string               `json:"timezone"`
+	Sunrise          string               `json:"sunrise,omitempty"`
+	Sunset           string               `json:"sunset,omitempty"`
+	SolarNoon        string               `json:"solar_noon"`
+	DayLength        string               `json:"day_length"`
+	DayLengthSeconds int64                `json:"day_length_seconds"`
+	MoonPhase        string               `json:"moon_phase"`
+	MoonIllumination float64              `json:"moon_illumination"`
+	MoonAgeDays      float64              `json:"moon_age_days"`
+	CrossCheck       *AstronomyCrossCheck `json:"cross_check,omitempty"`
+}
+
+type AstronomyCrossCheck struct {
+	Provider           string  `json:"provider"`
+	Sunrise            string  `json:"sunrise"`
+	Sunset             string  `json:"sunset"`
+	MoonPhase          string  `json:"moon_phase"`
+	MoonIllumination   float64 `json:"moon_illumination"`
+	SunriseDiffMinutes float64 `json:"sunrise_diff_minutes"`
+	SunsetDiffMinutes  float64 `json:"sunset_diff_minutes"`
+}
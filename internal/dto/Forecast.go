@@ -0,0 +1,23 @@
+package dto
+
+type ForecastInput struct {
+	Latitude  string
+	Longitude string
+	Days      int
+	Lang      string
+}
+
+// ForecastDay - resumo diário da previsão; temperaturas em °C, vento em km/h, precipitação em mm e
+// umidade e chance de chuva em %
+type ForecastDay struct {
+	Date          string  `json:"date"`
+	MaxTempC      float64 `json:"max_temp_C"`
+	MinTempC      float64 `json:"min_temp_C"`
+	AvgTempC      float64 `json:"avg_temp_C"`
+	MaxWindSpeed  float64 `json:"max_wind_speed"`
+	Precipitation float64 `json:"precipitation"`
+	Humidity      float64 `json:"humidity"`
+	ChanceOfRain  float64 `json:"chance_of_rain"`
+	UV            float64 `json:"uv"`
+	Condition     string  `json:"condition"`
+}
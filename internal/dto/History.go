@@ -0,0 +1,35 @@
+package dto
+
+import "time"
+
+// HistoryInput - consulta ao histórico do CEP; Step zero devolve as leituras sem agregação
+type HistoryInput struct {
+	CEP  string
+	From time.Time
+	To   time.Time
+	Step time.Duration
+}
+
+// HistoryPoint - leituras de um intervalo agregadas; sem agregação, cada ponto é uma leitura e
+// Count é 1. Temperaturas em °C, vento em km/h e pressão em hPa.
+type HistoryPoint struct {
+	// Time - início do intervalo, ou o momento da observação sem agregação (RFC 3339, UTC)
+	Time         string  `json:"time"`
+	Count        int     `json:"count"`
+	TempCAvg     float64 `json:"temp_C_avg"`
+	TempCMin     float64 `json:"temp_C_min"`
+	TempCMax     float64 `json:"temp_C_max"`
+	HumidityAvg  float64 `json:"humidity_avg"`
+	WindSpeedAvg float64 `json:"wind_speed_avg"`
+	WindSpeedMax float64 `json:"wind_speed_max"`
+	PressureAvg  float64 `json:"pressure_avg"`
+}
+
+// HistoryOutput - série do CEP no período; Step vazio quando não há agregação
+type HistoryOutput struct {
+	CEP    string         `json:"cep"`
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Step   string         `json:"step,omitempty"`
+	Points []HistoryPoint `json:"points"`
+}
@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"encoding/xml"
+	"net/url"
+	"time"
+)
+
 type WeatherInput struct {
 	Logradouro string
 	Bairro     string
@@ -7,11 +13,75 @@ type WeatherInput struct {
 	CIDADE     string
 	Latitude   string
 	Longitude  string
+	// AirQuality - pede a qualidade do ar na mesma consulta ao provedor
+	AirQuality bool
+	// Lang - idioma da descrição da condição no provedor; vazio mantém o inglês
+	Lang string
+}
+
+// WeatherByCEPInput - busca de clima pelo CEP no serviço A; Query carrega as opções repassadas ao
+// serviço B (unidades, qualidade do ar) e Lang o idioma negociado
+type WeatherByCEPInput struct {
+	CEP   string
+	Query url.Values
+	Lang  string
+}
+
+// WeatherByCoordinatesInput - busca de clima por coordenadas no serviço A, repassada ao serviço B
+type WeatherByCoordinatesInput struct {
+	Latitude  string
+	Longitude string
+	Query     url.Values
+	Lang      string
 }
 
 type WeatherOutput struct {
-	City string  `json:"city"`
-	C    float64 `json:"temp_C"`
-	F    float64 `json:"temp_F"`
-	K    float64 `json:"temp_K"`
+	XMLName xml.Name `json:"-" xml:"weather"`
+
+	City string  `json:"city" xml:"city"`
+	C    float64 `json:"temp_C" xml:"temp_C"`
+	F    float64 `json:"temp_F" xml:"temp_F"`
+	K    float64 `json:"temp_K" xml:"temp_K"`
+	R    float64 `json:"temp_R,omitempty" xml:"temp_R,omitempty"`
+
+	// campos brutos do provedor: umidade (%), vento (km/h) e pressão (hPa), salvo quando Units indicar outra unidade
+	Humidity  float64 `json:"humidity" xml:"humidity"`
+	WindSpeed float64 `json:"wind_speed" xml:"wind_speed"`
+	Pressure  float64 `json:"pressure" xml:"pressure"`
+	Condition string  `json:"condition,omitempty" xml:"condition,omitempty"`
+	// ObservedAt - momento da observação no provedor (RFC 3339, UTC)
+	ObservedAt string `json:"observed_at,omitempty" xml:"observed_at,omitempty"`
+
+	// índices derivados localmente pelo pacote meteorology, em °C salvo quando Units indicar outra unidade
+	FeelsLike float64 `json:"feels_like" xml:"feels_like"`
+	HeatIndex float64 `json:"heat_index" xml:"heat_index"`
+	WindChill float64 `json:"wind_chill" xml:"wind_chill"`
+	// DewPoint e Humidex - ausentes quando o provedor não informa a umidade
+	DewPoint            *float64 `json:"dew_point,omitempty" xml:"dew_point,omitempty"`
+	Humidex             *float64 `json:"humidex,omitempty" xml:"humidex,omitempty"`
+	Beaufort            int      `json:"beaufort" xml:"beaufort"`
+	BeaufortDescription string   `json:"beaufort_description" xml:"beaufort_description"`
+
+	AirQuality *AirQualityOutput `json:"air_quality,omitempty" xml:"air_quality,omitempty"`
+	Units      *Units            `json:"units,omitempty" xml:"units,omitempty"`
+
+	// ExpiresAt - até quando o dado é considerado atual, usado no Cache-Control; não faz parte do corpo
+	ExpiresAt time.Time `json:"-" xml:"-"`
+}
+
+// Units - unidades em que a resposta foi expressa
+type Units struct {
+	Temperature string `json:"temperature" xml:"temperature"`
+	Wind        string `json:"wind" xml:"wind"`
+	Pressure    string `json:"pressure" xml:"pressure"`
+}
+
+// Alert - condição de risco identificada em uma leitura; Value e Threshold ficam na unidade de
+// referência da regra (°C, escala Beaufort ou índice US EPA), independente das unidades da resposta
+type Alert struct {
+	Type      string  `json:"type" xml:"type"`
+	Severity  string  `json:"severity" xml:"severity"`
+	Value     float64 `json:"value" xml:"value"`
+	Threshold float64 `json:"threshold" xml:"threshold"`
+	Message   string  `json:"message" xml:"message"`
 }
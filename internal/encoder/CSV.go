@@ -0,0 +1,75 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+// csvHeader - colunas fixas, escritas em toda resposta (header=present): as linhas de respostas diferentes
+// têm as mesmas colunas, mas para juntá-las numa planilha é preciso descartar o cabeçalho das seguintes;
+// os blocos opcionais viram colunas prefixadas e ficam vazios quando ausentes
+var csvHeader = []string{
+	"city", "temp_C", "temp_F", "temp_K", "temp_R",
+	"humidity", "wind_speed", "pressure", "condition", "observed_at",
+	"feels_like", "heat_index", "wind_chill", "dew_point", "humidex", "beaufort", "beaufort_description",
+	"air_quality_pm2_5", "air_quality_pm10", "air_quality_o3", "air_quality_no2",
+	"air_quality_us_epa_index", "air_quality_gb_defra_index", "air_quality_category", "air_quality_provider",
+	"units_temperature", "units_wind", "units_pressure",
+}
+
+// CSV - cabeçalho e uma linha por resposta
+type CSV struct{}
+
+func (CSV) ContentType() string {
+	return "text/csv; charset=utf-8; header=present"
+}
+
+func (CSV) Encode(output dto.WeatherOutput) ([]byte, error) {
+	number := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	tempR := ""
+	if output.R != 0 {
+		tempR = number(output.R)
+	}
+	optional := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return number(*v)
+	}
+
+	row := []string{
+		output.City, number(output.C), number(output.F), number(output.K), tempR,
+		number(output.Humidity), number(output.WindSpeed), number(output.Pressure), output.Condition, output.ObservedAt,
+		number(output.FeelsLike), number(output.HeatIndex), number(output.WindChill), optional(output.DewPoint), optional(output.Humidex),
+		strconv.Itoa(output.Beaufort), output.BeaufortDescription,
+	}
+
+	if aq := output.AirQuality; aq != nil {
+		row = append(row,
+			number(aq.PM25), number(aq.PM10), number(aq.O3), number(aq.NO2),
+			strconv.Itoa(aq.USEPAIndex), strconv.Itoa(aq.GBDefraIndex), aq.Category, aq.Provider,
+		)
+	} else {
+		row = append(row, "", "", "", "", "", "", "", "")
+	}
+
+	if u := output.Units; u != nil {
+		row = append(row, u.Temperature, u.Wind, u.Pressure)
+	} else {
+		row = append(row, "", "", "")
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(csvHeader)
+	writer.Write(row)
+	writer.Flush()
+
+	return buf.Bytes(), writer.Error()
+}
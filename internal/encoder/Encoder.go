@@ -0,0 +1,111 @@
+// Package encoder serializa as respostas de clima no formato negociado pelo header Accept:
+// JSON (padrão), XML, CSV e protobuf.
+package encoder
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+var ErrNotAcceptable = errors.New("nenhum formato suportado no Accept")
+
+// Encoder - serializa a resposta de clima em um formato
+type Encoder interface {
+	// ContentType - valor do header Content-Type da resposta
+	ContentType() string
+	Encode(output dto.WeatherOutput) ([]byte, error)
+}
+
+// encoders - formatos suportados, na ordem de preferência quando o cliente aceita qualquer um
+var encoders = []struct {
+	mediaTypes []string
+	encoder    Encoder
+}{
+	{mediaTypes: []string{"application/json"}, encoder: JSON{}},
+	{mediaTypes: []string{"application/xml", "text/xml"}, encoder: XML{}},
+	{mediaTypes: []string{"text/csv"}, encoder: CSV{}},
+	{mediaTypes: []string{"application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf"}, encoder: Protobuf{}},
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept - faixas do header Accept ordenadas pela qualidade (q), mantendo a ordem do cliente no empate
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	return ranges
+}
+
+// matches - indica se a faixa do Accept (ex.: text/*, */*) cobre o media type
+func matches(mediaRange string, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// Negotiate - escolhe o encoder pelo header Accept; Accept vazio responde JSON e faixas com q=0
+// são recusadas. ErrNotAcceptable quando nenhum formato suportado é aceito.
+func Negotiate(accept string) (Encoder, error) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON{}, nil
+	}
+
+	ranges := parseAccept(accept)
+
+	// media types recusados explicitamente (ex.: application/json;q=0) não são escolhidos nem por */*
+	refused := map[string]bool{}
+	for _, r := range ranges {
+		if r.quality <= 0 {
+			refused[r.mediaType] = true
+		}
+	}
+
+	for _, r := range ranges {
+		if r.quality <= 0 {
+			continue
+		}
+
+		for _, candidate := range encoders {
+			for _, mediaType := range candidate.mediaTypes {
+				if matches(r.mediaType, mediaType) && !refused[mediaType] {
+					return candidate.encoder, nil
+				}
+			}
+		}
+	}
+
+	return nil, ErrNotAcceptable
+}
@@ -0,0 +1,58 @@
+package encoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   Encoder
+	}{
+		{name: "vazio", accept: "", want: JSON{}},
+		{name: "json", accept: "application/json", want: JSON{}},
+		{name: "qualquer formato", accept: "*/*", want: JSON{}},
+		{name: "maiúsculas e parâmetros", accept: "Text/CSV; charset=utf-8", want: CSV{}},
+		{name: "maior qualidade vence", accept: "application/json;q=0.5, text/csv;q=0.9", want: CSV{}},
+		{name: "sem q vale 1", accept: "application/xml;q=0.8, application/x-protobuf", want: Protobuf{}},
+		{name: "empate mantém a ordem do cliente", accept: "text/csv;q=0.7, application/xml;q=0.7", want: CSV{}},
+		{name: "q inválido vale 1", accept: "application/json;q=0.5, text/csv;q=abc", want: CSV{}},
+		{name: "formato não suportado é ignorado", accept: "text/html, application/xml;q=0.1", want: XML{}},
+		{name: "text/xml", accept: "text/xml", want: XML{}},
+		{name: "text/* na ordem de preferência", accept: "text/*", want: XML{}},
+		{name: "text/* sem o recusado", accept: "text/*, text/xml;q=0", want: CSV{}},
+		{name: "*/* sem o json recusado", accept: "*/*, application/json;q=0", want: XML{}},
+		{name: "recusa antes de */*", accept: "application/json;q=0, application/xml;q=0, */*;q=0.1", want: XML{}},
+		{name: "*/* sem json e xml recusados", accept: "*/*;q=0.1, application/json;q=0, application/xml;q=0, text/xml;q=0", want: CSV{}},
+		{name: "faixa específica antes de */*", accept: "*/*;q=0.5, application/protobuf", want: Protobuf{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Negotiate(test.accept)
+			if err != nil {
+				t.Fatalf("Negotiate(%q): %v", test.accept, err)
+			}
+			if got != test.want {
+				t.Errorf("Negotiate(%q) = %T, esperado %T", test.accept, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateNotAcceptable(t *testing.T) {
+	for _, accept := range []string{
+		"text/html",
+		"image/*",
+		"application/json;q=0",
+		"*/*;q=0",
+		"text/*, text/xml;q=0, text/csv;q=0",
+		"application/json;q=0, application/xml;q=0, text/xml;q=0, text/csv;q=0, application/x-protobuf;q=0, application/protobuf;q=0, application/vnd.google.protobuf;q=0, */*",
+	} {
+		if got, err := Negotiate(accept); !errors.Is(err, ErrNotAcceptable) {
+			t.Errorf("Negotiate(%q) = %T, %v, esperado ErrNotAcceptable", accept, got, err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package encoder
+
+import (
+	"encoding/json"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+// JSON - formato padrão das respostas
+type JSON struct{}
+
+func (JSON) ContentType() string {
+	return "application/json"
+}
+
+func (JSON) Encode(output dto.WeatherOutput) ([]byte, error) {
+	return json.Marshal(output)
+}
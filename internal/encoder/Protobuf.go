@@ -0,0 +1,107 @@
+package encoder
+
+import (
+	"math"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Protobuf - mensagem weather.v1.Weather descrita em api/proto/weather.proto, serializada
+// diretamente com protowire para não depender de código gerado
+type Protobuf struct{}
+
+func (Protobuf) ContentType() string {
+	return "application/x-protobuf; messageType=weather.v1.Weather"
+}
+
+func (Protobuf) Encode(output dto.WeatherOutput) ([]byte, error) {
+	var b []byte
+
+	b = appendString(b, 1, output.City)
+	b = appendDouble(b, 2, output.C)
+	b = appendDouble(b, 3, output.F)
+	b = appendDouble(b, 4, output.K)
+	if output.R != 0 {
+		// temp_r é optional no .proto: presente apenas quando pedido em Rankine
+		b = protowire.AppendTag(b, 5, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(output.R))
+	}
+	b = appendDouble(b, 6, output.Humidity)
+	b = appendDouble(b, 7, output.WindSpeed)
+	b = appendDouble(b, 8, output.Pressure)
+	b = appendString(b, 9, output.Condition)
+	b = appendString(b, 10, output.ObservedAt)
+	b = appendDouble(b, 11, output.FeelsLike)
+	b = appendDouble(b, 12, output.HeatIndex)
+	b = appendDouble(b, 13, output.WindChill)
+	// dew_point e humidex são optional no .proto: ausentes sem a umidade
+	b = appendOptionalDouble(b, 14, output.DewPoint)
+	b = appendOptionalDouble(b, 15, output.Humidex)
+	b = appendInt32(b, 16, output.Beaufort)
+	b = appendString(b, 17, output.BeaufortDescription)
+
+	if aq := output.AirQuality; aq != nil {
+		var m []byte
+		m = appendDouble(m, 1, aq.PM25)
+		m = appendDouble(m, 2, aq.PM10)
+		m = appendDouble(m, 3, aq.O3)
+		m = appendDouble(m, 4, aq.NO2)
+		m = appendInt32(m, 5, aq.USEPAIndex)
+		m = appendInt32(m, 6, aq.GBDefraIndex)
+		m = appendString(m, 7, aq.Category)
+		m = appendString(m, 8, aq.Provider)
+		b = protowire.AppendTag(b, 18, protowire.BytesType)
+		b = protowire.AppendBytes(b, m)
+	}
+
+	if u := output.Units; u != nil {
+		var m []byte
+		m = appendString(m, 1, u.Temperature)
+		m = appendString(m, 2, u.Wind)
+		m = appendString(m, 3, u.Pressure)
+		b = protowire.AppendTag(b, 19, protowire.BytesType)
+		b = protowire.AppendBytes(b, m)
+	}
+
+	return b, nil
+}
+
+// appendDouble, appendInt32 e appendString - campos proto3 sem presença explícita: o valor zero
+// não é serializado
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+// appendOptionalDouble - campo optional: presente, mesmo zero, sempre que há valor
+func appendOptionalDouble(b []byte, num protowire.Number, v *float64) []byte {
+	if v == nil {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+
+	return protowire.AppendFixed64(b, math.Float64bits(*v))
+}
+
+func appendInt32(b []byte, num protowire.Number, v int) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+
+	return protowire.AppendVarint(b, uint64(int64(int32(v))))
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendString(b, v)
+}
@@ -0,0 +1,215 @@
+package encoder
+
+import (
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoField - campo declarado no .proto
+type protoField struct {
+	name   string
+	typ    string
+	number protowire.Number
+}
+
+var (
+	protoMessage = regexp.MustCompile(`(?s)message (\w+) \{(.*?)\n\}`)
+	protoLine    = regexp.MustCompile(`(?m)^\s*(?:optional\s+)?(\w+)\s+(\w+)\s*=\s*(\d+);`)
+)
+
+// loadProto - campos de cada mensagem de api/proto/weather.proto
+func loadProto(t *testing.T) map[string][]protoField {
+	t.Helper()
+
+	source, err := os.ReadFile("../../api/proto/weather.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string][]protoField{}
+	for _, message := range protoMessage.FindAllStringSubmatch(string(source), -1) {
+		for _, line := range protoLine.FindAllStringSubmatch(message[2], -1) {
+			number, _ := strconv.Atoi(line[3])
+			messages[message[1]] = append(messages[message[1]], protoField{
+				name:   line[2],
+				typ:    line[1],
+				number: protowire.Number(number),
+			})
+		}
+	}
+
+	return messages
+}
+
+// wireType - tipo de wire do tipo escalar ou mensagem declarado no .proto
+func wireType(typ string) protowire.Type {
+	switch typ {
+	case "double":
+		return protowire.Fixed64Type
+	case "int32":
+		return protowire.VarintType
+	}
+
+	return protowire.BytesType
+}
+
+// decodedField - campo lido da mensagem serializada
+type decodedField struct {
+	typ   protowire.Type
+	value any
+}
+
+// decodeMessage - campos da mensagem pelo número, falhando em repetições e bytes inválidos
+func decodeMessage(t *testing.T, b []byte) map[protowire.Number]decodedField {
+	t.Helper()
+
+	fields := map[protowire.Number]decodedField{}
+	for len(b) > 0 {
+		number, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("tag inválida: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var value any
+		switch typ {
+		case protowire.Fixed64Type:
+			v, m := protowire.ConsumeFixed64(b)
+			value, n = math.Float64frombits(v), m
+		case protowire.VarintType:
+			v, m := protowire.ConsumeVarint(b)
+			value, n = int32(v), m
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(b)
+			value, n = v, m
+		default:
+			t.Fatalf("campo %d com tipo de wire inesperado %d", number, typ)
+		}
+		if n < 0 {
+			t.Fatalf("campo %d inválido: %v", number, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if _, ok := fields[number]; ok {
+			t.Errorf("campo %d serializado mais de uma vez", number)
+		}
+		fields[number] = decodedField{typ: typ, value: value}
+	}
+
+	return fields
+}
+
+// checkMessage - confere os campos decodificados contra a mensagem do .proto e os valores esperados
+// pelo nome do campo; campos sem valor esperado não podem estar presentes
+func checkMessage(t *testing.T, messages map[string][]protoField, message string, b []byte, want map[string]any) {
+	t.Helper()
+
+	fields := decodeMessage(t, b)
+	declared := map[protowire.Number]bool{}
+
+	for _, field := range messages[message] {
+		declared[field.number] = true
+		got, present := fields[field.number]
+		expected, wanted := want[field.name]
+
+		if !wanted {
+			if present {
+				t.Errorf("%s.%s (%d) presente, esperado ausente", message, field.name, field.number)
+			}
+			continue
+		}
+		if !present {
+			t.Errorf("%s.%s (%d) ausente", message, field.name, field.number)
+			continue
+		}
+		if got.typ != wireType(field.typ) {
+			t.Errorf("%s.%s (%d) com tipo de wire %d, o .proto declara %s", message, field.name, field.number, got.typ, field.typ)
+			continue
+		}
+
+		if _, ok := messages[field.typ]; ok {
+			checkMessage(t, messages, field.typ, got.value.([]byte), expected.(map[string]any))
+			continue
+		}
+		if bytes, ok := got.value.([]byte); ok {
+			got.value = string(bytes)
+		}
+		if got.value != expected {
+			t.Errorf("%s.%s (%d) = %v, esperado %v", message, field.name, field.number, got.value, expected)
+		}
+	}
+
+	for number := range fields {
+		if !declared[number] {
+			t.Errorf("%s: campo %d não declarado no .proto", message, number)
+		}
+	}
+}
+
+func TestProtobufMatchesProto(t *testing.T) {
+	messages := loadProto(t)
+	for _, message := range []string{"Weather", "AirQuality", "Units"} {
+		if len(messages[message]) == 0 {
+			t.Fatalf("mensagem %s não encontrada no .proto", message)
+		}
+	}
+
+	dewPoint, humidex, zero := 18.6, 35.2, 0.0
+
+	tests := []struct {
+		name   string
+		output dto.WeatherOutput
+		want   map[string]any
+	}{
+		{
+			name: "todos os campos",
+			output: dto.WeatherOutput{
+				City: "Maringá", C: 27.5, F: 81.5, K: 300.65, R: 541.17,
+				Humidity: 60, WindSpeed: 12, Pressure: 1014, Condition: "Sunny", ObservedAt: "2024-06-20T12:00:00Z",
+				FeelsLike: 28.1, HeatIndex: 28.4, WindChill: 27.5, DewPoint: &dewPoint, Humidex: &humidex,
+				Beaufort: 2, BeaufortDescription: "Light breeze",
+				AirQuality: &dto.AirQualityOutput{PM25: 12.4, PM10: 20.1, O3: 50, NO2: 8.2, USEPAIndex: 2, GBDefraIndex: 3, Category: "Moderate", Provider: "weatherapi"},
+				Units:      &dto.Units{Temperature: "R", Wind: "km/h", Pressure: "hPa"},
+			},
+			want: map[string]any{
+				"city": "Maringá", "temp_c": 27.5, "temp_f": 81.5, "temp_k": 300.65, "temp_r": 541.17,
+				"humidity": 60.0, "wind_speed": 12.0, "pressure": 1014.0, "condition": "Sunny", "observed_at": "2024-06-20T12:00:00Z",
+				"feels_like": 28.1, "heat_index": 28.4, "wind_chill": 27.5, "dew_point": 18.6, "humidex": 35.2,
+				"beaufort": int32(2), "beaufort_description": "Light breeze",
+				"air_quality": map[string]any{
+					"pm2_5": 12.4, "pm10": 20.1, "o3": 50.0, "no2": 8.2,
+					"us_epa_index": int32(2), "gb_defra_index": int32(3), "category": "Moderate", "provider": "weatherapi",
+				},
+				"units": map[string]any{"temperature": "R", "wind": "km/h", "pressure": "hPa"},
+			},
+		},
+		{
+			// proto3 omite o valor zero, exceto nos campos optional com valor
+			name:   "zeros e optional",
+			output: dto.WeatherOutput{C: -3.5, F: 25.7, K: 269.65, DewPoint: &zero},
+			want:   map[string]any{"temp_c": -3.5, "temp_f": 25.7, "temp_k": 269.65, "dew_point": 0.0},
+		},
+		{
+			name:   "beaufort negativo como int32",
+			output: dto.WeatherOutput{Beaufort: -1},
+			want:   map[string]any{"beaufort": int32(-1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := Protobuf{}.Encode(test.output)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			checkMessage(t, messages, "Weather", b, test.want)
+		})
+	}
+}
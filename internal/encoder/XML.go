@@ -0,0 +1,23 @@
+package encoder
+
+import (
+	"encoding/xml"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+// XML - elemento <weather> com os mesmos nomes de campo do JSON
+type XML struct{}
+
+func (XML) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (XML) Encode(output dto.WeatherOutput) ([]byte, error) {
+	payload, err := xml.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), payload...), nil
+}
@@ -0,0 +1,67 @@
+package history
+
+import (
+	"math"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+// Downsample - agrega as leituras, já em ordem cronológica, em intervalos de step alinhados em UTC
+// (time.Truncate: 1h começa na hora cheia, 24h à meia-noite); intervalos sem leitura não geram ponto.
+// Step zero devolve um ponto por leitura.
+func Downsample(readings []Reading, step time.Duration) []dto.HistoryPoint {
+	points := []dto.HistoryPoint{}
+
+	var current *dto.HistoryPoint
+	var start time.Time
+	var sum struct{ temp, humidity, wind, pressure float64 }
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		count := float64(current.Count)
+		current.TempCAvg = round(sum.temp / count)
+		current.HumidityAvg = round(sum.humidity / count)
+		current.WindSpeedAvg = round(sum.wind / count)
+		current.PressureAvg = round(sum.pressure / count)
+		points = append(points, *current)
+	}
+
+	for _, reading := range readings {
+		bucket := reading.ObservedAt.UTC()
+		if step > 0 {
+			bucket = bucket.Truncate(step)
+		}
+
+		if current == nil || !bucket.Equal(start) {
+			flush()
+			start = bucket
+			current = &dto.HistoryPoint{
+				Time:         bucket.Format(time.RFC3339),
+				TempCMin:     reading.TempC,
+				TempCMax:     reading.TempC,
+				WindSpeedMax: reading.WindSpeed,
+			}
+			sum.temp, sum.humidity, sum.wind, sum.pressure = 0, 0, 0, 0
+		}
+
+		current.Count++
+		current.TempCMin = math.Min(current.TempCMin, reading.TempC)
+		current.TempCMax = math.Max(current.TempCMax, reading.TempC)
+		current.WindSpeedMax = math.Max(current.WindSpeedMax, reading.WindSpeed)
+		sum.temp += reading.TempC
+		sum.humidity += reading.Humidity
+		sum.wind += reading.WindSpeed
+		sum.pressure += reading.Pressure
+	}
+	flush()
+
+	return points
+}
+
+// round - médias com duas casas, como as leituras do provedor
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,99 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+func TestDownsample(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	readings := []Reading{
+		{ObservedAt: at("2024-06-20T10:00:00Z"), TempC: 20, Humidity: 70, WindSpeed: 10, Pressure: 1010},
+		{ObservedAt: at("2024-06-20T10:15:00Z"), TempC: 22, Humidity: 60, WindSpeed: 14, Pressure: 1012},
+		{ObservedAt: at("2024-06-20T10:59:59Z"), TempC: 21, Humidity: 65, WindSpeed: 6, Pressure: 1011},
+		// 11h sem leitura, depois 12h em outro fuso: o intervalo é alinhado em UTC
+		{ObservedAt: at("2024-06-20T09:30:00-03:00"), TempC: 25.555, Humidity: 50, WindSpeed: 3, Pressure: 1009},
+		{ObservedAt: at("2024-06-20T23:59:59Z"), TempC: 18, Humidity: 80, WindSpeed: 0, Pressure: 1015},
+		{ObservedAt: at("2024-06-21T00:00:00Z"), TempC: 17, Humidity: 85, WindSpeed: 1, Pressure: 1016},
+	}
+
+	tests := []struct {
+		name string
+		step time.Duration
+		want []dto.HistoryPoint
+	}{
+		{
+			name: "hora cheia",
+			step: time.Hour,
+			want: []dto.HistoryPoint{
+				{Time: "2024-06-20T10:00:00Z", Count: 3, TempCAvg: 21, TempCMin: 20, TempCMax: 22, HumidityAvg: 65, WindSpeedAvg: 10, WindSpeedMax: 14, PressureAvg: 1011},
+				{Time: "2024-06-20T12:00:00Z", Count: 1, TempCAvg: 25.56, TempCMin: 25.555, TempCMax: 25.555, HumidityAvg: 50, WindSpeedAvg: 3, WindSpeedMax: 3, PressureAvg: 1009},
+				{Time: "2024-06-20T23:00:00Z", Count: 1, TempCAvg: 18, TempCMin: 18, TempCMax: 18, HumidityAvg: 80, WindSpeedAvg: 0, WindSpeedMax: 0, PressureAvg: 1015},
+				{Time: "2024-06-21T00:00:00Z", Count: 1, TempCAvg: 17, TempCMin: 17, TempCMax: 17, HumidityAvg: 85, WindSpeedAvg: 1, WindSpeedMax: 1, PressureAvg: 1016},
+			},
+		},
+		{
+			name: "dia à meia-noite UTC",
+			step: 24 * time.Hour,
+			want: []dto.HistoryPoint{
+				{Time: "2024-06-20T00:00:00Z", Count: 5, TempCAvg: 21.31, TempCMin: 18, TempCMax: 25.555, HumidityAvg: 65, WindSpeedAvg: 6.6, WindSpeedMax: 14, PressureAvg: 1011.4},
+				{Time: "2024-06-21T00:00:00Z", Count: 1, TempCAvg: 17, TempCMin: 17, TempCMax: 17, HumidityAvg: 85, WindSpeedAvg: 1, WindSpeedMax: 1, PressureAvg: 1016},
+			},
+		},
+		{
+			name: "intervalo de 15 minutos",
+			step: 15 * time.Minute,
+			want: []dto.HistoryPoint{
+				{Time: "2024-06-20T10:00:00Z", Count: 1, TempCAvg: 20, TempCMin: 20, TempCMax: 20, HumidityAvg: 70, WindSpeedAvg: 10, WindSpeedMax: 10, PressureAvg: 1010},
+				{Time: "2024-06-20T10:15:00Z", Count: 1, TempCAvg: 22, TempCMin: 22, TempCMax: 22, HumidityAvg: 60, WindSpeedAvg: 14, WindSpeedMax: 14, PressureAvg: 1012},
+				{Time: "2024-06-20T10:45:00Z", Count: 1, TempCAvg: 21, TempCMin: 21, TempCMax: 21, HumidityAvg: 65, WindSpeedAvg: 6, WindSpeedMax: 6, PressureAvg: 1011},
+				{Time: "2024-06-20T12:30:00Z", Count: 1, TempCAvg: 25.56, TempCMin: 25.555, TempCMax: 25.555, HumidityAvg: 50, WindSpeedAvg: 3, WindSpeedMax: 3, PressureAvg: 1009},
+				{Time: "2024-06-20T23:45:00Z", Count: 1, TempCAvg: 18, TempCMin: 18, TempCMax: 18, HumidityAvg: 80, WindSpeedAvg: 0, WindSpeedMax: 0, PressureAvg: 1015},
+				{Time: "2024-06-21T00:00:00Z", Count: 1, TempCAvg: 17, TempCMin: 17, TempCMax: 17, HumidityAvg: 85, WindSpeedAvg: 1, WindSpeedMax: 1, PressureAvg: 1016},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Downsample(readings, test.step)
+			if len(got) != len(test.want) {
+				t.Fatalf("%d pontos, esperado %d: %+v", len(got), len(test.want), got)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("ponto %d = %+v, esperado %+v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDownsampleRaw(t *testing.T) {
+	observedAt := time.Date(2024, time.June, 20, 9, 30, 15, 0, time.FixedZone("BRT", -3*60*60))
+	got := Downsample([]Reading{
+		{ObservedAt: observedAt, TempC: -2.5, Humidity: 90, WindSpeed: 5, Pressure: 1020},
+		{ObservedAt: observedAt.Add(time.Second), TempC: -3, Humidity: 91, WindSpeed: 4, Pressure: 1021},
+	}, 0)
+
+	want := []dto.HistoryPoint{
+		{Time: "2024-06-20T12:30:15Z", Count: 1, TempCAvg: -2.5, TempCMin: -2.5, TempCMax: -2.5, HumidityAvg: 90, WindSpeedAvg: 5, WindSpeedMax: 5, PressureAvg: 1020},
+		{Time: "2024-06-20T12:30:16Z", Count: 1, TempCAvg: -3, TempCMin: -3, TempCMax: -3, HumidityAvg: 91, WindSpeedAvg: 4, WindSpeedMax: 4, PressureAvg: 1021},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("pontos = %+v, esperado %+v", got, want)
+	}
+
+	if got := Downsample(nil, time.Hour); got == nil || len(got) != 0 {
+		t.Errorf("sem leituras = %#v, esperado lista vazia", got)
+	}
+}
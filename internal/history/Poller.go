@@ -0,0 +1,114 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer - escopo de instrumentação das rodadas de coleta do histórico
+var tracer = otel.Tracer("github.com/nagahshi/pos_go_weather_otel/internal/history")
+
+// FetchFunc - leitura atual do CEP em unidades métricas
+type FetchFunc func(ctx context.Context, CEP string) (dto.WeatherOutput, error)
+
+// Poller - consulta o clima dos CEPs da watchlist a cada intervalo e guarda as leituras no store;
+// com retention maior que zero, as leituras mais antigas que ela são removidas a cada rodada
+type Poller struct {
+	store     *Store
+	fetch     FetchFunc
+	watchlist []string
+	interval  time.Duration
+	retention time.Duration
+}
+
+// NewPoller - cria o poller dos CEPs de watchlist
+func NewPoller(store *Store, fetch FetchFunc, watchlist []string, interval time.Duration, retention time.Duration) *Poller {
+	return &Poller{
+		store:     store,
+		fetch:     fetch,
+		watchlist: watchlist,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run - consulta de imediato e depois a cada intervalo, até o cancelamento de ctx
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll - uma rodada: consulta os CEPs em sequência, para não disputar a cota do provedor com as
+// requisições dos clientes. Cada rodada é um trace próprio (history_poll).
+func (p *Poller) Poll(ctx context.Context) {
+	ctx, spanPoll := tracer.Start(
+		ctx,
+		"history_poll",
+		trace.WithNewRoot(),
+		trace.WithAttributes(attribute.Int("history.watchlist", len(p.watchlist))),
+	)
+	defer spanPoll.End()
+
+	stored := 0
+	for _, CEP := range p.watchlist {
+		if ctx.Err() != nil {
+			return
+		}
+
+		output, err := p.fetch(ctx, CEP)
+		if err != nil {
+			spanPoll.RecordError(err, trace.WithAttributes(attribute.String("zipcode", CEP)))
+			spanPoll.SetStatus(codes.Error, "error on fetch")
+			continue
+		}
+
+		// a observação do provedor é a chave da série; sem ela, vale o momento da consulta
+		observedAt, err := time.Parse(time.RFC3339, output.ObservedAt)
+		if err != nil {
+			observedAt = time.Now().UTC().Truncate(time.Minute)
+		}
+
+		err = p.store.Append(CEP, Reading{
+			ObservedAt: observedAt,
+			TempC:      output.C,
+			Humidity:   output.Humidity,
+			WindSpeed:  output.WindSpeed,
+			Pressure:   output.Pressure,
+			Condition:  output.Condition,
+		})
+		if err != nil {
+			spanPoll.RecordError(err, trace.WithAttributes(attribute.String("zipcode", CEP)))
+			spanPoll.SetStatus(codes.Error, "error on store")
+			continue
+		}
+		stored++
+	}
+
+	if p.retention > 0 {
+		removed, err := p.store.Prune(time.Now().Add(-p.retention))
+		if err != nil {
+			spanPoll.RecordError(err)
+			spanPoll.SetStatus(codes.Error, "error on prune")
+		} else if removed > 0 {
+			spanPoll.AddEvent("pruned", trace.WithAttributes(attribute.Int("readings", removed)))
+		}
+	}
+
+	spanPoll.AddEvent("poll success", trace.WithAttributes(attribute.Int("stored", stored)))
+}
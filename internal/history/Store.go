@@ -0,0 +1,130 @@
+// Package history guarda as leituras de clima dos CEPs monitorados em uma série temporal local
+// (bbolt) e agrega as consultas em intervalos fixos.
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// readingsBucket - bucket raiz; cada CEP tem um bucket filho com as leituras ordenadas pelo momento da observação
+var readingsBucket = []byte("readings")
+
+// Reading - leitura guardada; unidades métricas (°C, km/h e hPa)
+type Reading struct {
+	ObservedAt time.Time `json:"observed_at"`
+	TempC      float64   `json:"temp_C"`
+	Humidity   float64   `json:"humidity"`
+	WindSpeed  float64   `json:"wind_speed"`
+	Pressure   float64   `json:"pressure"`
+	Condition  string    `json:"condition,omitempty"`
+}
+
+// Store - série temporal em um arquivo bbolt. A chave é o momento da observação, então consultar
+// de novo a mesma observação do provedor sobrescreve a leitura em vez de duplicá-la.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open - abre, ou cria, o arquivo do histórico em path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(readingsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close - fecha o arquivo do histórico
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// key - momento em nanossegundos big-endian, para a ordem das chaves ser a ordem temporal
+func key(t time.Time) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+
+	return k
+}
+
+// Append - guarda a leitura do CEP
+func (s *Store) Append(CEP string, reading Reading) error {
+	value, err := json.Marshal(reading)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(readingsBucket).CreateBucketIfNotExists([]byte(CEP))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(key(reading.ObservedAt), value)
+	})
+}
+
+// Range - leituras do CEP observadas em [from, to), em ordem cronológica; nil quando o CEP não tem histórico
+func (s *Store) Range(CEP string, from time.Time, to time.Time) ([]Reading, error) {
+	var readings []Reading
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(readingsBucket).Bucket([]byte(CEP))
+		if bucket == nil {
+			return nil
+		}
+
+		readings = []Reading{}
+		end := key(to)
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(key(from)); k != nil && bytes.Compare(k, end) < 0; k, v = cursor.Next() {
+			reading := Reading{}
+			if err := json.Unmarshal(v, &reading); err != nil {
+				return err
+			}
+			readings = append(readings, reading)
+		}
+
+		return nil
+	})
+
+	return readings, err
+}
+
+// Prune - remove as leituras observadas antes de before em todos os CEPs; devolve quantas foram removidas
+func (s *Store) Prune(before time.Time) (int, error) {
+	removed := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		end := key(before)
+
+		return tx.Bucket(readingsBucket).ForEachBucket(func(CEP []byte) error {
+			cursor := tx.Bucket(readingsBucket).Bucket(CEP).Cursor()
+			for k, _ := cursor.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = cursor.First() {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				removed++
+			}
+
+			return nil
+		})
+	})
+
+	return removed, err
+}
@@ -0,0 +1,119 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestStore - histórico em um arquivo novo
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestStoreRange(t *testing.T) {
+	store := openTestStore(t)
+	base := time.Date(2024, time.June, 20, 10, 0, 0, 0, time.UTC)
+
+	// fora de ordem e com uma observação repetida, que sobrescreve a anterior
+	for _, minutes := range []int{30, 0, 60, 15, 45} {
+		reading := Reading{ObservedAt: base.Add(time.Duration(minutes) * time.Minute), TempC: float64(minutes)}
+		if err := store.Append("87033080", reading); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := store.Append("87033080", Reading{ObservedAt: base.Add(15 * time.Minute), TempC: 99}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append("01001000", Reading{ObservedAt: base.Add(15 * time.Minute), TempC: -1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want []float64
+	}{
+		{"from incluso e to excluso", base, base.Add(time.Hour), []float64{0, 99, 30, 45}},
+		{"um nanossegundo depois de from", base.Add(time.Nanosecond), base.Add(time.Hour + time.Nanosecond), []float64{99, 30, 45, 60}},
+		{"intervalo vazio", base.Add(time.Minute), base.Add(14 * time.Minute), []float64{}},
+		{"from igual a to", base, base, []float64{}},
+		{"tudo", base.Add(-time.Hour), base.Add(2 * time.Hour), []float64{0, 99, 30, 45, 60}},
+		{"em outro fuso", base.In(time.FixedZone("BRT", -3*60*60)), base.Add(30 * time.Minute), []float64{0, 99}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			readings, err := store.Range("87033080", test.from, test.to)
+			if err != nil {
+				t.Fatalf("Range: %v", err)
+			}
+
+			got := []float64{}
+			for i, reading := range readings {
+				got = append(got, reading.TempC)
+				if i > 0 && !reading.ObservedAt.After(readings[i-1].ObservedAt) {
+					t.Errorf("leitura %d fora da ordem cronológica", i)
+				}
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("temperaturas = %v, esperado %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("temperaturas = %v, esperado %v", got, test.want)
+				}
+			}
+		})
+	}
+
+	readings, err := store.Range("99999999", base, base.Add(time.Hour))
+	if err != nil || readings != nil {
+		t.Errorf("CEP sem histórico = %v, %v, esperado nil", readings, err)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	store := openTestStore(t)
+	base := time.Date(2024, time.June, 20, 0, 0, 0, 0, time.UTC)
+
+	for _, CEP := range []string{"87033080", "01001000"} {
+		for hours := 0; hours < 4; hours++ {
+			if err := store.Append(CEP, Reading{ObservedAt: base.Add(time.Duration(hours) * time.Hour)}); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+		}
+	}
+
+	// before é exclusivo: a leitura das 2h fica
+	removed, err := store.Prune(base.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if removed != 4 {
+		t.Errorf("removidas %d leituras, esperado 4", removed)
+	}
+
+	for _, CEP := range []string{"87033080", "01001000"} {
+		readings, err := store.Range(CEP, base, base.Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(readings) != 2 || !readings[0].ObservedAt.Equal(base.Add(2*time.Hour)) {
+			t.Errorf("%s: leituras restantes = %+v, esperado 2h e 3h", CEP, readings)
+		}
+	}
+
+	if removed, err := store.Prune(base.Add(2 * time.Hour)); err != nil || removed != 0 {
+		t.Errorf("segunda poda removeu %d, err = %v, esperado 0", removed, err)
+	}
+}
@@ -0,0 +1,399 @@
+package i18n
+
+// chaves das mensagens de erro devolvidas aos clientes
+const (
+	ErrDecodeZipcode      = "error.decode_zipcode"
+	ErrInvalidZipcode     = "error.invalid_zipcode"
+	ErrDecodeLocation     = "error.decode_location"
+	ErrInvalidCoordinates = "error.invalid_coordinates"
+	ErrInvalidUnits       = "error.invalid_units"
+	ErrInvalidTimezone    = "error.invalid_timezone"
+	ErrInvalidDate        = "error.invalid_date"
+	ErrLocationNotFound   = "error.location_not_found"
+	ErrAstronomyNotFound  = "error.astronomy_location_not_found"
+	ErrAstronomyCalculate = "error.astronomy_calculate"
+	ErrCreateRequest      = "error.create_request"
+	ErrRequestServiceB    = "error.request_service_b"
+	ErrEncodeResponse     = "error.encode_response"
+	ErrUnexpectedServiceB = "error.unexpected_service_b"
+	ErrNotAcceptable      = "error.not_acceptable"
+
+	ErrInvalidMessage       = "error.invalid_message"
+	ErrSubscriptionExists   = "error.subscription_exists"
+	ErrSubscriptionLimit    = "error.subscription_limit"
+	ErrSubscriptionNotFound = "error.subscription_not_found"
+
+	ErrDecodeGraphQL       = "error.decode_graphql"
+	ErrGraphQLCost         = "error.graphql_cost"
+	ErrInvalidForecastDays = "error.invalid_forecast_days"
+
+	ErrDecodeWebhook        = "error.decode_webhook"
+	ErrInvalidCondition     = "error.invalid_condition"
+	ErrInvalidCallbackURL   = "error.invalid_callback_url"
+	ErrInvalidWebhookSecret = "error.invalid_webhook_secret"
+	ErrWebhookLimit         = "error.webhook_limit"
+	ErrWebhookNotFound      = "error.webhook_not_found"
+	ErrDeadLetterNotFound   = "error.dead_letter_not_found"
+	ErrWebhookTenant        = "error.webhook_tenant"
+
+	ErrInvalidHistoryRange = "error.invalid_history_range"
+	ErrInvalidHistoryStep  = "error.invalid_history_step"
+	ErrHistoryNotFound     = "error.history_not_found"
+	ErrReadHistory         = "error.read_history"
+)
+
+// prefixos das chaves compostas (ex.: beaufort.8, problem.CEP_NOT_FOUND)
+const (
+	AirQualityUnknown = "air_quality.unknown"
+	PrefixAirQuality  = "air_quality."
+	PrefixBeaufort    = "beaufort."
+	PrefixMoonPhase   = "moon_phase."
+	PrefixLabel       = "label."
+	PrefixProblem     = "problem."
+	PrefixAlert       = "alert."
+)
+
+var catalog = map[Lang]map[string]string{
+	EN: {
+		ErrDecodeZipcode:        "cant decode zipcode",
+		ErrInvalidZipcode:       "invalid zipcode",
+		ErrDecodeLocation:       "cant decode location",
+		ErrInvalidCoordinates:   "invalid coordinates, expected numeric lat and lon",
+		ErrInvalidUnits:         "invalid units options",
+		ErrInvalidTimezone:      "invalid timezone",
+		ErrInvalidDate:          "invalid date, expected YYYY-MM-DD",
+		ErrLocationNotFound:     "can not find location to weather",
+		ErrAstronomyNotFound:    "can not find location to astronomy",
+		ErrAstronomyCalculate:   "can not calculate astronomy to location",
+		ErrCreateRequest:        "cant create request",
+		ErrRequestServiceB:      "cant get data",
+		ErrEncodeResponse:       "cant encode response",
+		ErrUnexpectedServiceB:   "unexpected response from weather service",
+		ErrNotAcceptable:        "no supported format in Accept, use application/json, application/xml, text/csv or application/x-protobuf",
+		ErrInvalidMessage:       "invalid message, expected subscribe or unsubscribe with an id",
+		ErrSubscriptionExists:   "subscription id already in use",
+		ErrSubscriptionLimit:    "subscription limit reached for this connection",
+		ErrSubscriptionNotFound: "subscription not found",
+		ErrDecodeGraphQL:        "cant decode graphql request, expected a JSON body with query",
+		ErrGraphQLCost:          "graphql query too expensive, reduce the number of fields and aliases",
+		ErrInvalidForecastDays:  "invalid forecast days, use 1 to 14",
+		ErrDecodeWebhook:        "cant decode webhook subscription, expected a JSON body with cep, condition and callback_url",
+		ErrInvalidCondition:     "invalid condition, use field operator value (e.g. temp_C > 35) or rain expected",
+		ErrInvalidCallbackURL:   "invalid callback_url, expected an absolute http or https URL to a public address",
+		ErrInvalidWebhookSecret: "invalid secret, use at least 16 characters or omit it to get a generated one",
+		ErrWebhookLimit:         "webhook subscription limit reached",
+		ErrWebhookNotFound:      "webhook subscription not found",
+		ErrDeadLetterNotFound:   "dead letter not found",
+		ErrWebhookTenant:        "webhooks belong to a tenant, send a known X-API-Key",
+		ErrInvalidHistoryRange:  "invalid period, use from and to as RFC 3339 or YYYY-MM-DD, with from before to and at most 366 days",
+		ErrInvalidHistoryStep:   "invalid step, use a duration of at least 1m (e.g. 15m, 1h, 1d) or raw",
+		ErrHistoryNotFound:      "no history for this zipcode, only watchlist zipcodes are recorded",
+		ErrReadHistory:          "cant read history",
+
+		"problem.INVALID_REQUEST":       "Invalid request",
+		"problem.INVALID_CEP":           "Invalid zipcode",
+		"problem.UNAUTHORIZED":          "Unauthorized",
+		"problem.NOT_ACCEPTABLE":        "Not acceptable",
+		"problem.CEP_NOT_FOUND":         "Zipcode not found",
+		"problem.LOCATION_NOT_FOUND":    "Location not found",
+		"problem.NOT_FOUND":             "Not found",
+		"problem.UPSTREAM_UNAVAILABLE":  "Upstream service unavailable",
+		"problem.UPSTREAM_TIMEOUT":      "Upstream service timed out",
+		"problem.UPSTREAM_RATE_LIMITED": "Upstream rate limit reached",
+		"problem.QUOTA_EXCEEDED":        "Upstream quota exceeded",
+		"problem.MISSING_API_KEY":       "Upstream API key missing or invalid",
+		"problem.INTERNAL_ERROR":        "Internal error",
+
+		"alert.heat.warning":         "Dangerous heat: heat index above 39.4 °C",
+		"alert.heat.critical":        "Extreme heat danger: heat index above 51.7 °C",
+		"alert.cold.warning":         "Cold risk: wind chill below -10 °C",
+		"alert.cold.critical":        "Frostbite risk: wind chill below -28 °C",
+		"alert.wind.warning":         "Gale: Beaufort 8 or higher",
+		"alert.wind.critical":        "Storm: Beaufort 10 or higher",
+		"alert.air_quality.warning":  "Unhealthy air quality",
+		"alert.air_quality.critical": "Very unhealthy air quality",
+
+		"air_quality.1":   "Good",
+		"air_quality.2":   "Moderate",
+		"air_quality.3":   "Unhealthy for sensitive groups",
+		"air_quality.4":   "Unhealthy",
+		"air_quality.5":   "Very unhealthy",
+		"air_quality.6":   "Hazardous",
+		AirQualityUnknown: "Unknown",
+
+		"beaufort.0":  "Calm",
+		"beaufort.1":  "Light air",
+		"beaufort.2":  "Light breeze",
+		"beaufort.3":  "Gentle breeze",
+		"beaufort.4":  "Moderate breeze",
+		"beaufort.5":  "Fresh breeze",
+		"beaufort.6":  "Strong breeze",
+		"beaufort.7":  "Near gale",
+		"beaufort.8":  "Gale",
+		"beaufort.9":  "Strong gale",
+		"beaufort.10": "Storm",
+		"beaufort.11": "Violent storm",
+		"beaufort.12": "Hurricane force",
+
+		"moon_phase.new_moon":        "New Moon",
+		"moon_phase.waxing_crescent": "Waxing Crescent",
+		"moon_phase.first_quarter":   "First Quarter",
+		"moon_phase.waxing_gibbous":  "Waxing Gibbous",
+		"moon_phase.full_moon":       "Full Moon",
+		"moon_phase.waning_gibbous":  "Waning Gibbous",
+		"moon_phase.last_quarter":    "Last Quarter",
+		"moon_phase.waning_crescent": "Waning Crescent",
+
+		"label.city":                 "City",
+		"label.temp_C":               "Temperature (°C)",
+		"label.temp_F":               "Temperature (°F)",
+		"label.temp_K":               "Temperature (K)",
+		"label.temp_R":               "Temperature (°R)",
+		"label.humidity":             "Humidity",
+		"label.wind_speed":           "Wind speed",
+		"label.pressure":             "Pressure",
+		"label.condition":            "Condition",
+		"label.feels_like":           "Feels like",
+		"label.heat_index":           "Heat index",
+		"label.wind_chill":           "Wind chill",
+		"label.dew_point":            "Dew point",
+		"label.humidex":              "Humidex",
+		"label.beaufort":             "Beaufort scale",
+		"label.beaufort_description": "Wind description",
+		"label.air_quality":          "Air quality",
+		"label.sunrise":              "Sunrise",
+		"label.sunset":               "Sunset",
+		"label.solar_noon":           "Solar noon",
+		"label.day_length":           "Day length",
+		"label.moon_phase":           "Moon phase",
+		"label.moon_illumination":    "Moon illumination",
+	},
+	PT: {
+		ErrDecodeZipcode:        "não foi possível ler o CEP",
+		ErrInvalidZipcode:       "CEP inválido",
+		ErrDecodeLocation:       "não foi possível ler a localização",
+		ErrInvalidCoordinates:   "coordenadas inválidas, informe lat e lon numéricos",
+		ErrInvalidUnits:         "opções de unidade inválidas",
+		ErrInvalidTimezone:      "fuso horário inválido",
+		ErrInvalidDate:          "data inválida, use o formato AAAA-MM-DD",
+		ErrLocationNotFound:     "não foi possível encontrar a localização para o clima",
+		ErrAstronomyNotFound:    "não foi possível encontrar a localização para a astronomia",
+		ErrAstronomyCalculate:   "não foi possível calcular a astronomia para a localização",
+		ErrCreateRequest:        "não foi possível montar a requisição",
+		ErrRequestServiceB:      "não foi possível obter os dados",
+		ErrEncodeResponse:       "não foi possível montar a resposta",
+		ErrUnexpectedServiceB:   "resposta inesperada do serviço de clima",
+		ErrNotAcceptable:        "nenhum formato suportado no Accept, use application/json, application/xml, text/csv ou application/x-protobuf",
+		ErrInvalidMessage:       "mensagem inválida, envie subscribe ou unsubscribe com um id",
+		ErrSubscriptionExists:   "id de assinatura já em uso",
+		ErrSubscriptionLimit:    "limite de assinaturas da conexão atingido",
+		ErrSubscriptionNotFound: "assinatura não encontrada",
+		ErrDecodeGraphQL:        "não foi possível ler a requisição graphql, envie um corpo JSON com query",
+		ErrGraphQLCost:          "consulta graphql cara demais, reduza a quantidade de campos e aliases",
+		ErrInvalidForecastDays:  "quantidade de dias da previsão inválida, use de 1 a 14",
+		ErrDecodeWebhook:        "não foi possível ler a assinatura de webhook, envie um corpo JSON com cep, condition e callback_url",
+		ErrInvalidCondition:     "condição inválida, use campo operador valor (ex.: temp_C > 35) ou rain expected",
+		ErrInvalidCallbackURL:   "callback_url inválida, envie uma URL http ou https absoluta, de um endereço público",
+		ErrInvalidWebhookSecret: "secret inválido, use ao menos 16 caracteres ou omita para receber um gerado",
+		ErrWebhookLimit:         "limite de assinaturas de webhook atingido",
+		ErrWebhookNotFound:      "assinatura de webhook não encontrada",
+		ErrDeadLetterNotFound:   "dead letter não encontrada",
+		ErrWebhookTenant:        "webhooks pertencem a um tenant, envie uma X-API-Key conhecida",
+		ErrInvalidHistoryRange:  "período inválido, use from e to em RFC 3339 ou YYYY-MM-DD, com from antes de to e no máximo 366 dias",
+		ErrInvalidHistoryStep:   "intervalo inválido, use uma duração de ao menos 1m (ex.: 15m, 1h, 1d) ou raw",
+		ErrHistoryNotFound:      "CEP sem histórico, apenas os CEPs da watchlist são registrados",
+		ErrReadHistory:          "não foi possível ler o histórico",
+
+		"problem.INVALID_REQUEST":       "Requisição inválida",
+		"problem.INVALID_CEP":           "CEP inválido",
+		"problem.UNAUTHORIZED":          "Não autorizado",
+		"problem.NOT_ACCEPTABLE":        "Formato não aceito",
+		"problem.CEP_NOT_FOUND":         "CEP não encontrado",
+		"problem.LOCATION_NOT_FOUND":    "Localização não encontrada",
+		"problem.NOT_FOUND":             "Não encontrado",
+		"problem.UPSTREAM_UNAVAILABLE":  "Serviço externo indisponível",
+		"problem.UPSTREAM_TIMEOUT":      "Tempo esgotado no serviço externo",
+		"problem.UPSTREAM_RATE_LIMITED": "Limite de requisições do serviço externo atingido",
+		"problem.QUOTA_EXCEEDED":        "Cota do serviço externo excedida",
+		"problem.MISSING_API_KEY":       "Chave do serviço externo ausente ou inválida",
+		"problem.INTERNAL_ERROR":        "Erro interno",
+
+		"alert.heat.warning":         "Calor perigoso: índice de calor acima de 39,4 °C",
+		"alert.heat.critical":        "Calor extremo: índice de calor acima de 51,7 °C",
+		"alert.cold.warning":         "Risco de frio: sensação térmica abaixo de -10 °C",
+		"alert.cold.critical":        "Risco de congelamento: sensação térmica abaixo de -28 °C",
+		"alert.wind.warning":         "Ventania: Beaufort 8 ou mais",
+		"alert.wind.critical":        "Tempestade: Beaufort 10 ou mais",
+		"alert.air_quality.warning":  "Qualidade do ar insalubre",
+		"alert.air_quality.critical": "Qualidade do ar muito insalubre",
+
+		"air_quality.1":   "Boa",
+		"air_quality.2":   "Moderada",
+		"air_quality.3":   "Insalubre para grupos sensíveis",
+		"air_quality.4":   "Insalubre",
+		"air_quality.5":   "Muito insalubre",
+		"air_quality.6":   "Perigosa",
+		AirQualityUnknown: "Desconhecida",
+
+		"beaufort.0":  "Calmaria",
+		"beaufort.1":  "Bafagem",
+		"beaufort.2":  "Aragem",
+		"beaufort.3":  "Fraco",
+		"beaufort.4":  "Moderado",
+		"beaufort.5":  "Fresco",
+		"beaufort.6":  "Muito fresco",
+		"beaufort.7":  "Forte",
+		"beaufort.8":  "Muito forte",
+		"beaufort.9":  "Duro",
+		"beaufort.10": "Muito duro",
+		"beaufort.11": "Tempestade",
+		"beaufort.12": "Furacão",
+
+		"moon_phase.new_moon":        "Lua nova",
+		"moon_phase.waxing_crescent": "Lua crescente",
+		"moon_phase.first_quarter":   "Quarto crescente",
+		"moon_phase.waxing_gibbous":  "Crescente gibosa",
+		"moon_phase.full_moon":       "Lua cheia",
+		"moon_phase.waning_gibbous":  "Minguante gibosa",
+		"moon_phase.last_quarter":    "Quarto minguante",
+		"moon_phase.waning_crescent": "Lua minguante",
+
+		"label.city":                 "Cidade",
+		"label.temp_C":               "Temperatura (°C)",
+		"label.temp_F":               "Temperatura (°F)",
+		"label.temp_K":               "Temperatura (K)",
+		"label.temp_R":               "Temperatura (°R)",
+		"label.humidity":             "Umidade",
+		"label.wind_speed":           "Velocidade do vento",
+		"label.pressure":             "Pressão",
+		"label.condition":            "Condição",
+		"label.feels_like":           "Sensação térmica",
+		"label.heat_index":           "Índice de calor",
+		"label.wind_chill":           "Resfriamento pelo vento",
+		"label.dew_point":            "Ponto de orvalho",
+		"label.humidex":              "Humidex",
+		"label.beaufort":             "Escala Beaufort",
+		"label.beaufort_description": "Descrição do vento",
+		"label.air_quality":          "Qualidade do ar",
+		"label.sunrise":              "Nascer do sol",
+		"label.sunset":               "Pôr do sol",
+		"label.solar_noon":           "Meio-dia solar",
+		"label.day_length":           "Duração do dia",
+		"label.moon_phase":           "Fase da lua",
+		"label.moon_illumination":    "Iluminação da lua",
+	},
+	ES: {
+		ErrDecodeZipcode:        "no fue posible leer el código postal",
+		ErrInvalidZipcode:       "código postal inválido",
+		ErrDecodeLocation:       "no fue posible leer la ubicación",
+		ErrInvalidCoordinates:   "coordenadas inválidas, indique lat y lon numéricos",
+		ErrInvalidUnits:         "opciones de unidad inválidas",
+		ErrInvalidTimezone:      "zona horaria inválida",
+		ErrInvalidDate:          "fecha inválida, use el formato AAAA-MM-DD",
+		ErrLocationNotFound:     "no fue posible encontrar la ubicación para el clima",
+		ErrAstronomyNotFound:    "no fue posible encontrar la ubicación para la astronomía",
+		ErrAstronomyCalculate:   "no fue posible calcular la astronomía para la ubicación",
+		ErrCreateRequest:        "no fue posible armar la solicitud",
+		ErrRequestServiceB:      "no fue posible obtener los datos",
+		ErrEncodeResponse:       "no fue posible armar la respuesta",
+		ErrUnexpectedServiceB:   "respuesta inesperada del servicio de clima",
+		ErrNotAcceptable:        "ningún formato soportado en Accept, use application/json, application/xml, text/csv o application/x-protobuf",
+		ErrInvalidMessage:       "mensaje inválido, envíe subscribe o unsubscribe con un id",
+		ErrSubscriptionExists:   "id de suscripción ya en uso",
+		ErrSubscriptionLimit:    "límite de suscripciones de la conexión alcanzado",
+		ErrSubscriptionNotFound: "suscripción no encontrada",
+		ErrDecodeGraphQL:        "no se pudo leer la solicitud graphql, envíe un cuerpo JSON con query",
+		ErrGraphQLCost:          "consulta graphql demasiado costosa, reduzca la cantidad de campos y alias",
+		ErrInvalidForecastDays:  "cantidad de días del pronóstico inválida, use de 1 a 14",
+		ErrDecodeWebhook:        "no se pudo leer la suscripción de webhook, envíe un cuerpo JSON con cep, condition y callback_url",
+		ErrInvalidCondition:     "condición inválida, use campo operador valor (ej.: temp_C > 35) o rain expected",
+		ErrInvalidCallbackURL:   "callback_url inválida, envíe una URL http o https absoluta, de una dirección pública",
+		ErrInvalidWebhookSecret: "secret inválido, use al menos 16 caracteres u omítalo para recibir uno generado",
+		ErrWebhookLimit:         "límite de suscripciones de webhook alcanzado",
+		ErrWebhookNotFound:      "suscripción de webhook no encontrada",
+		ErrDeadLetterNotFound:   "dead letter no encontrada",
+		ErrWebhookTenant:        "los webhooks pertenecen a un tenant, envíe una X-API-Key conocida",
+		ErrInvalidHistoryRange:  "período inválido, use from y to en RFC 3339 o YYYY-MM-DD, con from antes de to y como máximo 366 días",
+		ErrInvalidHistoryStep:   "intervalo inválido, use una duración de al menos 1m (ej.: 15m, 1h, 1d) o raw",
+		ErrHistoryNotFound:      "código postal sin historial, solo se registran los de la watchlist",
+		ErrReadHistory:          "no se pudo leer el historial",
+
+		"problem.INVALID_REQUEST":       "Solicitud inválida",
+		"problem.INVALID_CEP":           "Código postal inválido",
+		"problem.UNAUTHORIZED":          "No autorizado",
+		"problem.NOT_ACCEPTABLE":        "Formato no aceptable",
+		"problem.CEP_NOT_FOUND":         "Código postal no encontrado",
+		"problem.LOCATION_NOT_FOUND":    "Ubicación no encontrada",
+		"problem.NOT_FOUND":             "No encontrado",
+		"problem.UPSTREAM_UNAVAILABLE":  "Servicio externo no disponible",
+		"problem.UPSTREAM_TIMEOUT":      "Tiempo agotado en el servicio externo",
+		"problem.UPSTREAM_RATE_LIMITED": "Límite de solicitudes del servicio externo alcanzado",
+		"problem.QUOTA_EXCEEDED":        "Cuota del servicio externo excedida",
+		"problem.MISSING_API_KEY":       "Clave del servicio externo ausente o inválida",
+		"problem.INTERNAL_ERROR":        "Error interno",
+
+		"alert.heat.warning":         "Calor peligroso: índice de calor superior a 39,4 °C",
+		"alert.heat.critical":        "Calor extremo: índice de calor superior a 51,7 °C",
+		"alert.cold.warning":         "Riesgo de frío: sensación térmica inferior a -10 °C",
+		"alert.cold.critical":        "Riesgo de congelación: sensación térmica inferior a -28 °C",
+		"alert.wind.warning":         "Temporal: Beaufort 8 o más",
+		"alert.wind.critical":        "Tormenta: Beaufort 10 o más",
+		"alert.air_quality.warning":  "Calidad del aire insalubre",
+		"alert.air_quality.critical": "Calidad del aire muy insalubre",
+
+		"air_quality.1":   "Buena",
+		"air_quality.2":   "Moderada",
+		"air_quality.3":   "Dañina para grupos sensibles",
+		"air_quality.4":   "Dañina",
+		"air_quality.5":   "Muy dañina",
+		"air_quality.6":   "Peligrosa",
+		AirQualityUnknown: "Desconocida",
+
+		"beaufort.0":  "Calma",
+		"beaufort.1":  "Ventolina",
+		"beaufort.2":  "Flojito",
+		"beaufort.3":  "Flojo",
+		"beaufort.4":  "Bonancible",
+		"beaufort.5":  "Fresquito",
+		"beaufort.6":  "Fresco",
+		"beaufort.7":  "Frescachón",
+		"beaufort.8":  "Temporal",
+		"beaufort.9":  "Temporal fuerte",
+		"beaufort.10": "Temporal duro",
+		"beaufort.11": "Temporal muy duro",
+		"beaufort.12": "Temporal huracanado",
+
+		"moon_phase.new_moon":        "Luna nueva",
+		"moon_phase.waxing_crescent": "Luna creciente",
+		"moon_phase.first_quarter":   "Cuarto creciente",
+		"moon_phase.waxing_gibbous":  "Creciente gibosa",
+		"moon_phase.full_moon":       "Luna llena",
+		"moon_phase.waning_gibbous":  "Menguante gibosa",
+		"moon_phase.last_quarter":    "Cuarto menguante",
+		"moon_phase.waning_crescent": "Luna menguante",
+
+		"label.city":                 "Ciudad",
+		"label.temp_C":               "Temperatura (°C)",
+		"label.temp_F":               "Temperatura (°F)",
+		"label.temp_K":               "Temperatura (K)",
+		"label.temp_R":               "Temperatura (°R)",
+		"label.humidity":             "Humedad",
+		"label.wind_speed":           "Velocidad del viento",
+		"label.pressure":             "Presión",
+		"label.condition":            "Condición",
+		"label.feels_like":           "Sensación térmica",
+		"label.heat_index":           "Índice de calor",
+		"label.wind_chill":           "Enfriamiento por viento",
+		"label.dew_point":            "Punto de rocío",
+		"label.humidex":              "Humidex",
+		"label.beaufort":             "Escala Beaufort",
+		"label.beaufort_description": "Descripción del viento",
+		"label.air_quality":          "Calidad del aire",
+		"label.sunrise":              "Amanecer",
+		"label.sunset":               "Atardecer",
+		"label.solar_noon":           "Mediodía solar",
+		"label.day_length":           "Duración del día",
+		"label.moon_phase":           "Fase lunar",
+		"label.moon_illumination":    "Iluminación lunar",
+	},
+}
@@ -0,0 +1,135 @@
+// Package i18n contém o catálogo de mensagens (pt-BR, en, es) e a negociação de idioma pelo
+// header Accept-Language.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	PT Lang = "pt-BR"
+	EN Lang = "en"
+	ES Lang = "es"
+
+	// Default - idioma usado quando o cliente não informa Accept-Language ou não há correspondência;
+	// inglês preserva as mensagens que os clientes atuais já conhecem
+	Default = EN
+)
+
+// Negotiate - escolhe o idioma suportado de maior peso no header Accept-Language.
+// Variantes regionais caem no idioma base (pt-PT usa pt-BR, es-AR e es-UY usam es).
+func Negotiate(acceptLanguage string) Lang {
+	type candidate struct {
+		lang   Lang
+		weight float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if q, ok := strings.CutPrefix(param, "q="); ok {
+				if value, err := strconv.ParseFloat(q, 64); err == nil {
+					weight = value
+				}
+			}
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		lang, ok := match(tag)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, candidate{lang: lang, weight: weight})
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].weight > candidates[j].weight
+	})
+
+	return candidates[0].lang
+}
+
+func match(tag string) (Lang, bool) {
+	base, _, _ := strings.Cut(tag, "-")
+	switch base {
+	case "pt":
+		return PT, true
+	case "en":
+		return EN, true
+	case "es":
+		return ES, true
+	case "*":
+		return Default, true
+	}
+
+	return "", false
+}
+
+// T - mensagem traduzida para a chave; cai para o inglês e, por fim, para a própria chave
+func T(lang Lang, key string, args ...any) string {
+	message, ok := catalog[lang][key]
+	if !ok {
+		message, ok = catalog[Default][key]
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+
+	return message
+}
+
+// AirQualityCategory - rótulo de saúde para o índice US EPA (1 a 6)
+func AirQualityCategory(lang Lang, usEPAIndex int) string {
+	if usEPAIndex < 1 || usEPAIndex > 6 {
+		return T(lang, AirQualityUnknown)
+	}
+
+	return T(lang, PrefixAirQuality+strconv.Itoa(usEPAIndex))
+}
+
+// Labels - rótulos dos campos de resposta no idioma informado
+func Labels(lang Lang) map[string]string {
+	labels := make(map[string]string)
+	for key := range catalog[Default] {
+		if field, ok := strings.CutPrefix(key, "label."); ok {
+			labels[field] = T(lang, key)
+		}
+	}
+
+	return labels
+}
+
+// WeatherAPILang - código de idioma aceito pelo parâmetro lang da WeatherAPI
+func WeatherAPILang(lang Lang) string {
+	switch lang {
+	case PT:
+		return "pt"
+	case ES:
+		return "es"
+	}
+
+	return ""
+}
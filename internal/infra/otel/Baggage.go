@@ -0,0 +1,73 @@
+package otel
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// baggageSpanKeys - baggage keys copied onto the spans, from OTEL_BAGGAGE_SPAN_ATTRIBUTES (comma
+// separated); the tenant and the client by default, and none with "none"
+func baggageSpanKeys() []string {
+	value := strings.TrimSpace(os.Getenv("OTEL_BAGGAGE_SPAN_ATTRIBUTES"))
+	switch strings.ToLower(value) {
+	case "":
+		return []string{tenant.KeyTenant, tenant.KeyClient}
+	case "none":
+		return nil
+	}
+
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// BaggageProcessor - copies the selected baggage members of the parent context onto each span as
+// attributes when it starts, so every span of the request, in this service and in the ones it
+// calls, can be filtered by tenant. The baggage may come from outside: values that are not valid
+// IDs (see tenant.Valid) are not copied, and neither are the members on the server spans, which
+// start before the service decides whether it trusts the caller: the tenant middleware sets them.
+type BaggageProcessor struct {
+	keys []string
+}
+
+// NewBaggageProcessor - processor that copies the baggage members in keys
+func NewBaggageProcessor(keys []string) *BaggageProcessor {
+	return &BaggageProcessor{keys: keys}
+}
+
+func (p *BaggageProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	bag := baggage.FromContext(parent)
+	if bag.Len() == 0 || s.SpanKind() == trace.SpanKindServer {
+		return
+	}
+
+	attributes := make([]attribute.KeyValue, 0, len(p.keys))
+	for _, key := range p.keys {
+		if value := bag.Member(key).Value(); tenant.Valid(value) {
+			attributes = append(attributes, attribute.String(key, value))
+		}
+	}
+	s.SetAttributes(attributes...)
+}
+
+func (p *BaggageProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+func (p *BaggageProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func (p *BaggageProcessor) ForceFlush(context.Context) error {
+	return nil
+}
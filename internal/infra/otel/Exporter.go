@@ -0,0 +1,446 @@
+package otel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporter names accepted in OTEL_TRACES_EXPORTER, OTEL_METRICS_EXPORTER and OTEL_LOGS_EXPORTER,
+// as a comma separated list; otlp is the default of all three. otlp follows
+// OTEL_EXPORTER_OTLP_PROTOCOL (http/protobuf by default); logs have no gRPC exporter.
+const (
+	exporterOTLP     = "otlp"
+	exporterOTLPGRPC = "otlp-grpc"
+	exporterOTLPHTTP = "otlp-http"
+	exporterZipkin   = "zipkin"
+	exporterConsole  = "console"
+	exporterNone     = "none"
+)
+
+// errOTLPEndpoint - the OTLP exporters were selected without an endpoint
+var errOTLPEndpoint = errors.New("OTLP endpoint [OTEL_EXPORTER_OTLP_ENDPOINT or COLLECTOR_ENDPOINT] not configured yet")
+
+// exporterNames - exporters listed in the variable, or fallback when it is empty
+func exporterNames(variable string, fallback string) []string {
+	value := os.Getenv(variable)
+	if strings.TrimSpace(value) == "" {
+		value = fallback
+	}
+
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// resolveExporter - the exporter for name, with otlp resolved by otlpProtocol
+func resolveExporter(name string, signal string) (string, error) {
+	if name != exporterOTLP {
+		return name, nil
+	}
+
+	return otlpProtocol(signal)
+}
+
+// otlpProtocol - exporter for otlp: otlp-grpc for grpc, otlp-http for http/protobuf
+func otlpProtocol(signal string) (string, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+
+	switch protocol {
+	case "", "http/protobuf":
+		return exporterOTLPHTTP, nil
+	case "grpc":
+		return exporterOTLPGRPC, nil
+	}
+
+	return "", fmt.Errorf("unsupported OTLP protocol [OTEL_EXPORTER_OTLP_PROTOCOL]: %q", protocol)
+}
+
+// legacyCollector - COLLECTOR_ENDPOINT (host:port) when no OTEL_EXPORTER_OTLP endpoint is set for
+// the signal; the exporters read those, and the TLS, header, compression and timeout variables,
+// by themselves. The legacy endpoint keeps plain text unless a certificate is configured or
+// OTEL_EXPORTER_OTLP_INSECURE is false.
+func legacyCollector(signal string) (endpoint string, insecure bool, err error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_"+signal+"_ENDPOINT") != "" {
+		return "", false, nil
+	}
+
+	endpoint = os.Getenv("COLLECTOR_ENDPOINT")
+	if endpoint == "" {
+		return "", false, errOTLPEndpoint
+	}
+
+	insecure = true
+	for _, prefix := range []string{"OTEL_EXPORTER_OTLP_", "OTEL_EXPORTER_OTLP_" + signal + "_"} {
+		if os.Getenv(prefix+"CERTIFICATE") != "" || strings.EqualFold(os.Getenv(prefix+"INSECURE"), "false") {
+			insecure = false
+		}
+	}
+
+	return endpoint, insecure, nil
+}
+
+// newSpanProcessor - one processor per exporter in OTEL_TRACES_EXPORTER (otlp by default);
+// console is written as each span ends, the others in batches
+func newSpanProcessor(ctx context.Context) (sdktrace.SpanProcessor, error) {
+	var processors spanProcessors
+	for _, name := range exporterNames("OTEL_TRACES_EXPORTER", exporterOTLP) {
+		name, err := resolveExporter(name, "TRACES")
+		if err != nil {
+			return nil, errors.Join(err, processors.Shutdown(ctx))
+		}
+
+		var exporter sdktrace.SpanExporter
+		switch name {
+		case exporterOTLPHTTP:
+			exporter, err = newOTLPHTTPTraceExporter(ctx)
+		case exporterOTLPGRPC:
+			exporter, err = newOTLPGRPCTraceExporter(ctx)
+		case exporterZipkin:
+			exporter, err = newZipkinExporter()
+		case exporterConsole:
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+			if err == nil {
+				processors = append(processors, sdktrace.NewSimpleSpanProcessor(exporter))
+			}
+			exporter = nil
+		case exporterNone:
+		default:
+			err = fmt.Errorf("unsupported trace exporter [OTEL_TRACES_EXPORTER]: %q", name)
+		}
+		if err != nil {
+			return nil, errors.Join(err, processors.Shutdown(ctx))
+		}
+		if exporter != nil {
+			processors = append(processors, sdktrace.NewBatchSpanProcessor(exporter))
+		}
+	}
+
+	return processors, nil
+}
+
+func newOTLPHTTPTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	endpoint, insecure, err := legacyCollector("TRACES")
+	if err != nil {
+		return nil, err
+	}
+
+	var options []otlptracehttp.Option
+	if endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(endpoint))
+	}
+	if insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP/HTTP trace exporter: %v", err)
+	}
+
+	return exporter, nil
+}
+
+func newOTLPGRPCTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	endpoint, insecure, err := legacyCollector("TRACES")
+	if err != nil {
+		return nil, err
+	}
+
+	var options []otlptracegrpc.Option
+	if endpoint != "" {
+		options = append(options, otlptracegrpc.WithEndpoint(endpoint))
+	}
+	if insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP/gRPC trace exporter: %v", err)
+	}
+
+	return exporter, nil
+}
+
+// newZipkinExporter - spans straight to Zipkin at OTEL_EXPORTER_ZIPKIN_ENDPOINT (or ZIPKIN_ENDPOINT),
+// with TLS and headers from the OTEL_EXPORTER_ZIPKIN_* variables named like the OTLP ones
+func newZipkinExporter() (sdktrace.SpanExporter, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_ZIPKIN_ENDPOINT")
+	if endpoint == "" {
+		endpoint = os.Getenv("ZIPKIN_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil, errors.New("zipkin [OTEL_EXPORTER_ZIPKIN_ENDPOINT] not configured yet")
+	}
+
+	tlsConfig, err := tlsConfigFromEnv("OTEL_EXPORTER_ZIPKIN_")
+	if err != nil {
+		return nil, err
+	}
+	headers, err := headersFromEnv("OTEL_EXPORTER_ZIPKIN_HEADERS")
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	exporter, err := zipkin.New(endpoint, zipkin.WithClient(&http.Client{
+		Transport: headerTransport{headers: headers, next: transport},
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zipkin exporter: %v", err)
+	}
+
+	return exporter, nil
+}
+
+// tlsConfigFromEnv - CA (CERTIFICATE) and client certificate (CLIENT_CERTIFICATE and CLIENT_KEY),
+// PEM files named by the variables with prefix; nil when none is set
+func tlsConfigFromEnv(prefix string) (*tls.Config, error) {
+	caFile := os.Getenv(prefix + "CERTIFICATE")
+	certFile := os.Getenv(prefix + "CLIENT_CERTIFICATE")
+	keyFile := os.Getenv(prefix + "CLIENT_KEY")
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate [%sCERTIFICATE]: %v", prefix, err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in [%sCERTIFICATE]: %s", prefix, caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate [%sCLIENT_CERTIFICATE, %sCLIENT_KEY]: %v", prefix, prefix, err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// headersFromEnv - headers in the OTLP format: key=value pairs separated by commas, with the
+// values URL encoded
+func headersFromEnv(variable string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(variable), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header [%s]: %q", variable, pair)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid header [%s]: %q", variable, pair)
+		}
+		headers[key] = decoded
+	}
+
+	return headers, nil
+}
+
+// headerTransport - adds fixed headers (e.g. authentication) to every request
+type headerTransport struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) > 0 {
+		req = req.Clone(req.Context())
+		for key, value := range t.headers {
+			req.Header.Set(key, value)
+		}
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// spanProcessors - hands every span to each processor, one per exporter
+type spanProcessors []sdktrace.SpanProcessor
+
+func (p spanProcessors) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, processor := range p {
+		processor.OnStart(parent, s)
+	}
+}
+
+func (p spanProcessors) OnEnd(s sdktrace.ReadOnlySpan) {
+	for _, processor := range p {
+		processor.OnEnd(s)
+	}
+}
+
+func (p spanProcessors) Shutdown(ctx context.Context) error {
+	var err error
+	for _, processor := range p {
+		err = errors.Join(err, processor.Shutdown(ctx))
+	}
+
+	return err
+}
+
+func (p spanProcessors) ForceFlush(ctx context.Context) error {
+	var err error
+	for _, processor := range p {
+		err = errors.Join(err, processor.ForceFlush(ctx))
+	}
+
+	return err
+}
+
+// newMetricReaders - one periodic reader per exporter in OTEL_METRICS_EXPORTER (otlp by default)
+func newMetricReaders(ctx context.Context) ([]sdkmetric.Reader, error) {
+	var readers []sdkmetric.Reader
+	for _, name := range exporterNames("OTEL_METRICS_EXPORTER", exporterOTLP) {
+		name, err := resolveExporter(name, "METRICS")
+		if err != nil {
+			return nil, err
+		}
+
+		var exporter sdkmetric.Exporter
+		switch name {
+		case exporterOTLPHTTP:
+			exporter, err = newOTLPHTTPMetricExporter(ctx)
+		case exporterOTLPGRPC:
+			exporter, err = newOTLPGRPCMetricExporter(ctx)
+		case exporterConsole:
+			exporter, err = stdoutmetric.New(stdoutmetric.WithWriter(os.Stdout))
+		case exporterNone:
+		default:
+			err = fmt.Errorf("unsupported metric exporter [OTEL_METRICS_EXPORTER]: %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if exporter != nil {
+			readers = append(readers, sdkmetric.NewPeriodicReader(exporter))
+		}
+	}
+
+	return readers, nil
+}
+
+func newOTLPHTTPMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	endpoint, insecure, err := legacyCollector("METRICS")
+	if err != nil {
+		return nil, err
+	}
+
+	var options []otlpmetrichttp.Option
+	if endpoint != "" {
+		options = append(options, otlpmetrichttp.WithEndpoint(endpoint))
+	}
+	if insecure {
+		options = append(options, otlpmetrichttp.WithInsecure())
+	}
+
+	exporter, err := otlpmetrichttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP/HTTP metric exporter: %v", err)
+	}
+
+	return exporter, nil
+}
+
+func newOTLPGRPCMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	endpoint, insecure, err := legacyCollector("METRICS")
+	if err != nil {
+		return nil, err
+	}
+
+	var options []otlpmetricgrpc.Option
+	if endpoint != "" {
+		options = append(options, otlpmetricgrpc.WithEndpoint(endpoint))
+	}
+	if insecure {
+		options = append(options, otlpmetricgrpc.WithInsecure())
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP/gRPC metric exporter: %v", err)
+	}
+
+	return exporter, nil
+}
+
+// newLogProcessors - one batch processor per exporter in OTEL_LOGS_EXPORTER (otlp by default);
+// console sends nothing, since the records are already written to stdout by the slog handler. The
+// log SDK in use has no gRPC exporter, so otlp-grpc, or otlp with the grpc protocol, is refused.
+func newLogProcessors(ctx context.Context) ([]sdklog.Processor, error) {
+	var processors []sdklog.Processor
+	for _, name := range exporterNames("OTEL_LOGS_EXPORTER", exporterOTLP) {
+		name, err := resolveExporter(name, "LOGS")
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case exporterOTLPHTTP:
+			endpoint, insecure, err := legacyCollector("LOGS")
+			if err != nil {
+				return nil, err
+			}
+			var options []otlploghttp.Option
+			if endpoint != "" {
+				options = append(options, otlploghttp.WithEndpoint(endpoint))
+			}
+			if insecure {
+				options = append(options, otlploghttp.WithInsecure())
+			}
+			exporter, err := otlploghttp.New(ctx, options...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create OTLP/HTTP log exporter: %v", err)
+			}
+			processors = append(processors, sdklog.NewBatchProcessor(exporter))
+		case exporterOTLPGRPC:
+			return nil, errors.New("unsupported log exporter: OTLP/gRPC is not available for logs, use http/protobuf [OTEL_EXPORTER_OTLP_LOGS_PROTOCOL]")
+		case exporterConsole, exporterNone:
+		default:
+			return nil, fmt.Errorf("unsupported log exporter [OTEL_LOGS_EXPORTER]: %q", name)
+		}
+	}
+
+	return processors, nil
+}
@@ -0,0 +1,137 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// clearExporterEnv - unsets the exporter and OTLP variables the tests depend on
+func clearExporterEnv(t *testing.T) {
+	t.Helper()
+
+	for _, variable := range []string{
+		"OTEL_TRACES_EXPORTER", "OTEL_METRICS_EXPORTER", "OTEL_LOGS_EXPORTER",
+		"OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL",
+		"OTEL_EXPORTER_OTLP_METRICS_PROTOCOL", "OTEL_EXPORTER_OTLP_LOGS_PROTOCOL",
+		"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT",
+		"COLLECTOR_ENDPOINT",
+	} {
+		t.Setenv(variable, "")
+	}
+}
+
+func TestResolveExporter(t *testing.T) {
+	tests := []struct {
+		name           string
+		exporter       string
+		protocol       string
+		signalProtocol string
+		want           string
+		err            bool
+	}{
+		{name: "otlp without protocol", exporter: exporterOTLP, want: exporterOTLPHTTP},
+		{name: "otlp over http/protobuf", exporter: exporterOTLP, protocol: "http/protobuf", want: exporterOTLPHTTP},
+		{name: "otlp over grpc", exporter: exporterOTLP, protocol: "grpc", want: exporterOTLPGRPC},
+		{name: "signal protocol wins", exporter: exporterOTLP, protocol: "grpc", signalProtocol: "http/protobuf", want: exporterOTLPHTTP},
+		{name: "explicit exporter ignores the protocol", exporter: exporterOTLPHTTP, protocol: "grpc", want: exporterOTLPHTTP},
+		{name: "other exporters pass through", exporter: exporterConsole, protocol: "grpc", want: exporterConsole},
+		{name: "unsupported protocol", exporter: exporterOTLP, protocol: "http/json", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearExporterEnv(t)
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", test.protocol)
+			t.Setenv("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL", test.signalProtocol)
+
+			got, err := resolveExporter(test.exporter, "METRICS")
+			if (err != nil) != test.err {
+				t.Fatalf("err = %v, want error %v", err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("exporter = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultExportersAreOTLP(t *testing.T) {
+	ctx := context.Background()
+
+	// with no endpoint, the default of every signal is an OTLP exporter that refuses to start
+	clearExporterEnv(t)
+	if _, err := newSpanProcessor(ctx); !errors.Is(err, errOTLPEndpoint) {
+		t.Errorf("traces: err = %v, want %v", err, errOTLPEndpoint)
+	}
+	if _, err := newMetricReaders(ctx); !errors.Is(err, errOTLPEndpoint) {
+		t.Errorf("metrics: err = %v, want %v", err, errOTLPEndpoint)
+	}
+	if _, err := newLogProcessors(ctx); !errors.Is(err, errOTLPEndpoint) {
+		t.Errorf("logs: err = %v, want %v", err, errOTLPEndpoint)
+	}
+
+	// the default goes through OTEL_EXPORTER_OTLP_PROTOCOL
+	clearExporterEnv(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	if _, err := newSpanProcessor(ctx); err == nil || !strings.Contains(err.Error(), "unsupported OTLP protocol") {
+		t.Errorf("traces: err = %v, want the unsupported protocol", err)
+	}
+	if _, err := newMetricReaders(ctx); err == nil || !strings.Contains(err.Error(), "unsupported OTLP protocol") {
+		t.Errorf("metrics: err = %v, want the unsupported protocol", err)
+	}
+	if _, err := newLogProcessors(ctx); err == nil || !strings.Contains(err.Error(), "unsupported OTLP protocol") {
+		t.Errorf("logs: err = %v, want the unsupported protocol", err)
+	}
+}
+
+func TestOTLPGRPCExporters(t *testing.T) {
+	ctx := context.Background()
+	clearExporterEnv(t)
+	t.Setenv("COLLECTOR_ENDPOINT", "localhost:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+
+	processor, err := newSpanProcessor(ctx)
+	if err != nil {
+		t.Fatalf("traces over grpc: %v", err)
+	}
+	if processors := processor.(spanProcessors); len(processors) != 1 {
+		t.Errorf("%d span processors, want 1", len(processors))
+	}
+	processor.Shutdown(ctx)
+
+	readers, err := newMetricReaders(ctx)
+	if err != nil {
+		t.Fatalf("metrics over grpc: %v", err)
+	}
+	if len(readers) != 1 {
+		t.Errorf("%d metric readers, want 1", len(readers))
+	}
+	for _, reader := range readers {
+		reader.Shutdown(ctx)
+	}
+
+	// logs have no gRPC exporter: both spellings are refused instead of falling back to HTTP
+	for _, exporter := range []string{exporterOTLP, exporterOTLPGRPC} {
+		t.Setenv("OTEL_LOGS_EXPORTER", exporter)
+		if processors, err := newLogProcessors(ctx); err == nil {
+			t.Errorf("logs with %s over grpc: %d processors, want an error", exporter, len(processors))
+		}
+	}
+
+	// the logs protocol alone brings them back to HTTP
+	t.Setenv("OTEL_LOGS_EXPORTER", exporterOTLP)
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_PROTOCOL", "http/protobuf")
+	processors, err := newLogProcessors(ctx)
+	if err != nil {
+		t.Fatalf("logs over http/protobuf: %v", err)
+	}
+	if len(processors) != 1 {
+		t.Errorf("%d log processors, want 1", len(processors))
+	}
+	for _, processor := range processors {
+		processor.Shutdown(ctx)
+	}
+}
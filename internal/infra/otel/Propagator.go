@@ -0,0 +1,53 @@
+package otel
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Propagator names accepted in OTEL_PROPAGATORS
+const (
+	propagatorTraceContext = "tracecontext"
+	propagatorBaggage      = "baggage"
+	propagatorB3           = "b3"
+	propagatorB3Multi      = "b3multi"
+	propagatorJaeger       = "jaeger"
+	propagatorNone         = "none"
+)
+
+// NewPropagator - propagators listed in OTEL_PROPAGATORS, tracecontext,baggage by default. The
+// context is extracted from the headers of any of them, the last one found winning, and injected
+// in the headers of all of them, so the services called understand at least one: b3 writes the
+// single b3 header, b3multi the X-B3-* headers and jaeger the uber-trace-id header.
+func NewPropagator() (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	seen := map[string]bool{}
+
+	for _, name := range exporterNames("OTEL_PROPAGATORS", propagatorTraceContext+","+propagatorBaggage) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case propagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case propagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case propagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case propagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case propagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		case propagatorNone:
+		default:
+			return nil, fmt.Errorf("unsupported propagator [OTEL_PROPAGATORS]: %q", name)
+		}
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
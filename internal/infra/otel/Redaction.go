@@ -0,0 +1,336 @@
+package otel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Redaction modes accepted in OTEL_REDACTION_POLICY, applied to coordinates, zipcodes and addresses.
+// Secrets in query strings (key=, token=) are always masked, whatever the mode.
+const (
+	redactNone     = "none"
+	redactDrop     = "drop"
+	redactHash     = "hash"
+	redactTruncate = "truncate"
+)
+
+// redacted - placeholder for a value removed from a text
+const redacted = "[REDACTED]"
+
+var (
+	// coordinateKeys - attributes holding a latitude or a longitude
+	coordinateKeys = keySet("latitude", "longitude", "lat", "lon")
+	// zipcodeKeys - attributes holding a CEP
+	zipcodeKeys = keySet("zipcode", "cep")
+	// addressKeys - attributes holding street-level data, which has nothing to truncate to
+	addressKeys = keySet("street", "logradouro", "neighborhood", "bairro")
+	// textKeys - attributes with free text that may embed coordinates or CEPs: locations sent to
+	// the providers, cache and stream keys, URLs and error messages
+	textKeys = keySet(
+		"localidade", "cache_key", "stream.key", "query", "url", "error", "erro", "response",
+		"exception.message", "http.url", "http.target", "url.full", "url.path", "url.query",
+	)
+
+	// secretPattern - credentials sent in query strings, such as the WeatherAPI key
+	secretPattern = regexp.MustCompile(`(?i)([?&](?:key|api_key|apikey|token|access_token)=)[^&\s"']+`)
+	// locationPattern - a coordinate with at least 3 decimals (about 100m) or a CEP, with or without
+	// the dash; the neighbours are checked in Text
+	locationPattern = regexp.MustCompile(`-?\d{1,3}\.\d{3,}|\d{5}-?\d{3}`)
+)
+
+func keySet(keys ...string) map[attribute.Key]bool {
+	set := make(map[attribute.Key]bool, len(keys))
+	for _, key := range keys {
+		set[attribute.Key(key)] = true
+	}
+
+	return set
+}
+
+// RedactionPolicy - how personal data is removed from spans before export (LGPD): drop removes the
+// attribute, hash replaces the value by an HMAC-SHA256 of it, and truncate keeps coordinates with
+// Decimals decimals and the first 5 digits of CEPs; street-level data is dropped by truncate.
+type RedactionPolicy struct {
+	Mode     string
+	Decimals int
+	hashKey  []byte
+}
+
+// loadRedactionPolicy - policy in OTEL_REDACTION_POLICY (truncate by default), with the decimals in
+// OTEL_REDACTION_DECIMALS (2, about 1km, by default) and the HMAC key in OTEL_REDACTION_HASH_KEY.
+// Without a key, a random one is generated: hashes stay comparable within the process only.
+func loadRedactionPolicy() (RedactionPolicy, error) {
+	policy := RedactionPolicy{Mode: redactTruncate, Decimals: 2}
+
+	if value := os.Getenv("OTEL_REDACTION_POLICY"); value != "" {
+		policy.Mode = strings.ToLower(strings.TrimSpace(value))
+	}
+	switch policy.Mode {
+	case redactNone, redactDrop, redactHash, redactTruncate:
+	default:
+		return policy, fmt.Errorf("unsupported redaction policy [OTEL_REDACTION_POLICY]: %q", policy.Mode)
+	}
+
+	if value := os.Getenv("OTEL_REDACTION_DECIMALS"); value != "" {
+		decimals, err := strconv.Atoi(value)
+		if err != nil || decimals < 0 || decimals > 6 {
+			return policy, fmt.Errorf("invalid redaction decimals [OTEL_REDACTION_DECIMALS]: %q", value)
+		}
+		policy.Decimals = decimals
+	}
+
+	policy.hashKey = []byte(os.Getenv("OTEL_REDACTION_HASH_KEY"))
+	if len(policy.hashKey) == 0 {
+		policy.hashKey = make([]byte, 32)
+		if _, err := rand.Read(policy.hashKey); err != nil {
+			return policy, fmt.Errorf("failed to generate redaction hash key: %v", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// Attributes - attributes with the policy applied; dropped ones are left out
+func (p RedactionPolicy) Attributes(kvs []attribute.KeyValue) []attribute.KeyValue {
+	if len(kvs) == 0 {
+		return kvs
+	}
+
+	redactedKVs := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		if kv, ok := p.attribute(kv); ok {
+			redactedKVs = append(redactedKVs, kv)
+		}
+	}
+
+	return redactedKVs
+}
+
+// attribute - the attribute redacted by its key, and false when it must be dropped
+func (p RedactionPolicy) attribute(kv attribute.KeyValue) (attribute.KeyValue, bool) {
+	if kv.Value.Type() != attribute.STRING && kv.Value.Type() != attribute.FLOAT64 {
+		return kv, true
+	}
+
+	if p.Mode != redactNone {
+		switch {
+		case coordinateKeys[kv.Key]:
+			return p.coordinateAttribute(kv)
+		case zipcodeKeys[kv.Key]:
+			value, ok := p.zipcode(kv.Value.Emit())
+			return kv.Key.String(value), ok
+		case addressKeys[kv.Key]:
+			if p.Mode != redactHash {
+				return kv, false
+			}
+			return kv.Key.String(p.hash(kv.Value.Emit())), true
+		}
+	}
+
+	if kv.Value.Type() != attribute.STRING {
+		return kv, true
+	}
+
+	value := secretPattern.ReplaceAllString(kv.Value.AsString(), "${1}"+redacted)
+	if textKeys[kv.Key] {
+		value = p.Text(value)
+	}
+
+	return kv.Key.String(value), true
+}
+
+func (p RedactionPolicy) coordinateAttribute(kv attribute.KeyValue) (attribute.KeyValue, bool) {
+	switch p.Mode {
+	case redactDrop:
+		return kv, false
+	case redactHash:
+		return kv.Key.String(p.hash(kv.Value.Emit())), true
+	}
+
+	if kv.Value.Type() == attribute.FLOAT64 {
+		return kv.Key.Float64(p.truncate(kv.Value.AsFloat64())), true
+	}
+
+	value, ok := p.coordinate(kv.Value.AsString())
+
+	return kv.Key.String(value), ok
+}
+
+// Text - text with the embedded coordinates and CEPs redacted; numbers that are part of a larger
+// one (IP addresses, versions, IDs) are kept
+func (p RedactionPolicy) Text(text string) string {
+	text = secretPattern.ReplaceAllString(text, "${1}"+redacted)
+	if p.Mode == redactNone {
+		return text
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, match := range locationPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		if (start > 0 && isNumeric(text[start-1])) || (end < len(text) && isNumeric(text[end])) {
+			continue
+		}
+
+		value := text[start:end]
+		var replacement string
+		var ok bool
+		if strings.Contains(value, ".") {
+			replacement, ok = p.coordinate(value)
+		} else {
+			replacement, ok = p.zipcode(value)
+		}
+		if !ok {
+			replacement = redacted
+		}
+
+		builder.WriteString(text[last:start])
+		builder.WriteString(replacement)
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	builder.WriteString(text[last:])
+
+	return builder.String()
+}
+
+func isNumeric(c byte) bool {
+	return c == '.' || (c >= '0' && c <= '9')
+}
+
+// coordinate - coordinate redacted by the mode, and false when it must be dropped
+func (p RedactionPolicy) coordinate(value string) (string, bool) {
+	switch p.Mode {
+	case redactDrop:
+		return "", false
+	case redactHash:
+		return p.hash(value), true
+	}
+
+	coordinate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		// not a number: whatever it is, it is not kept
+		return redacted, true
+	}
+
+	return strconv.FormatFloat(p.truncate(coordinate), 'f', p.Decimals, 64), true
+}
+
+// truncate - coordinate with Decimals decimals, truncated towards zero so it is not moved to a
+// neighbouring cell
+func (p RedactionPolicy) truncate(coordinate float64) float64 {
+	scale := math.Pow10(p.Decimals)
+
+	return math.Trunc(coordinate*scale) / scale
+}
+
+// zipcode - CEP redacted by the mode, and false when it must be dropped; truncate keeps the region
+// (the first 5 digits)
+func (p RedactionPolicy) zipcode(value string) (string, bool) {
+	switch p.Mode {
+	case redactDrop:
+		return "", false
+	case redactHash:
+		return p.hash(value), true
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+	if len(digits) <= 5 {
+		return strings.Repeat("*", len(digits)), true
+	}
+
+	return digits[:5] + strings.Repeat("*", len(digits)-5), true
+}
+
+// hash - HMAC-SHA256 of the value, shortened; a plain hash of a CEP or coordinate would be reversed
+// by trying every value
+func (p RedactionPolicy) hash(value string) string {
+	mac := hmac.New(sha256.New, p.hashKey)
+	mac.Write([]byte(value))
+
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// RedactionProcessor - hands next a view of each ended span with the policy applied to its
+// attributes, events and status description, so no exporter receives the raw values
+type RedactionProcessor struct {
+	next   sdktrace.SpanProcessor
+	policy RedactionPolicy
+}
+
+// NewRedactionProcessor - processor that redacts the spans before next
+func NewRedactionProcessor(next sdktrace.SpanProcessor, policy RedactionPolicy) *RedactionProcessor {
+	return &RedactionProcessor{
+		next:   next,
+		policy: policy,
+	}
+}
+
+func (p *RedactionProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *RedactionProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	events := make([]sdktrace.Event, 0, len(s.Events()))
+	for _, event := range s.Events() {
+		event.Attributes = p.policy.Attributes(event.Attributes)
+		events = append(events, event)
+	}
+
+	status := s.Status()
+	status.Description = p.policy.Text(status.Description)
+
+	p.next.OnEnd(redactedSpan{
+		ReadOnlySpan: s,
+		attributes:   p.policy.Attributes(s.Attributes()),
+		events:       events,
+		status:       status,
+	})
+}
+
+func (p *RedactionProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *RedactionProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// redactedSpan - the ended span, with the redacted attributes, events and status
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attributes []attribute.KeyValue
+	events     []sdktrace.Event
+	status     sdktrace.Status
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attributes
+}
+
+func (s redactedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+func (s redactedSpan) Status() sdktrace.Status {
+	return s.status
+}
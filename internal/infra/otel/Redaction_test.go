@@ -0,0 +1,310 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testPolicy - policy with a fixed HMAC key, so hashes are comparable across the test
+func testPolicy(mode string) RedactionPolicy {
+	return RedactionPolicy{Mode: mode, Decimals: 2, hashKey: []byte("redaction-test-key")}
+}
+
+// weatherAPIURL - a WeatherAPI request, with the key and the coordinates in the query string
+const weatherAPIURL = "https://api.weatherapi.com/v1/current.json?key=abc123secret&q=-23.420512,-51.933390&aqi=yes"
+
+func TestRedactionPolicyAttributes(t *testing.T) {
+	input := []attribute.KeyValue{
+		attribute.String("latitude", "-23.420512"),
+		attribute.Float64("longitude", -51.93339),
+		attribute.String("zipcode", "87033-080"),
+		attribute.String("street", "Avenida Brasil"),
+		attribute.String("url.full", weatherAPIURL),
+		attribute.String("city", "87033080"),
+		attribute.Int("http.status_code", 200),
+	}
+
+	hash := testPolicy(redactHash).hash
+	tests := []struct {
+		mode string
+		want map[attribute.Key]attribute.Value
+	}{
+		{
+			mode: redactTruncate,
+			want: map[attribute.Key]attribute.Value{
+				"latitude":         attribute.StringValue("-23.42"),
+				"longitude":        attribute.Float64Value(-51.93),
+				"zipcode":          attribute.StringValue("87033***"),
+				"url.full":         attribute.StringValue("https://api.weatherapi.com/v1/current.json?key=[REDACTED]&q=-23.42,-51.93&aqi=yes"),
+				"city":             attribute.StringValue("87033080"),
+				"http.status_code": attribute.IntValue(200),
+			},
+		},
+		{
+			mode: redactHash,
+			want: map[attribute.Key]attribute.Value{
+				"latitude":         attribute.StringValue(hash("-23.420512")),
+				"longitude":        attribute.StringValue(hash("-51.93339")),
+				"zipcode":          attribute.StringValue(hash("87033-080")),
+				"street":           attribute.StringValue(hash("Avenida Brasil")),
+				"url.full":         attribute.StringValue("https://api.weatherapi.com/v1/current.json?key=[REDACTED]&q=" + hash("-23.420512") + "," + hash("-51.933390") + "&aqi=yes"),
+				"city":             attribute.StringValue("87033080"),
+				"http.status_code": attribute.IntValue(200),
+			},
+		},
+		{
+			mode: redactDrop,
+			want: map[attribute.Key]attribute.Value{
+				"url.full":         attribute.StringValue("https://api.weatherapi.com/v1/current.json?key=[REDACTED]&q=[REDACTED],[REDACTED]&aqi=yes"),
+				"city":             attribute.StringValue("87033080"),
+				"http.status_code": attribute.IntValue(200),
+			},
+		},
+		{
+			mode: redactNone,
+			want: map[attribute.Key]attribute.Value{
+				"latitude":         attribute.StringValue("-23.420512"),
+				"longitude":        attribute.Float64Value(-51.93339),
+				"zipcode":          attribute.StringValue("87033-080"),
+				"street":           attribute.StringValue("Avenida Brasil"),
+				"url.full":         attribute.StringValue("https://api.weatherapi.com/v1/current.json?key=[REDACTED]&q=-23.420512,-51.933390&aqi=yes"),
+				"city":             attribute.StringValue("87033080"),
+				"http.status_code": attribute.IntValue(200),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			got := map[attribute.Key]attribute.Value{}
+			for _, kv := range testPolicy(test.mode).Attributes(input) {
+				got[kv.Key] = kv.Value
+			}
+
+			for key, want := range test.want {
+				if value, ok := got[key]; !ok {
+					t.Errorf("%s dropped, want %s", key, want.Emit())
+				} else if value != want {
+					t.Errorf("%s = %s (%s), want %s (%s)", key, value.Emit(), value.Type(), want.Emit(), want.Type())
+				}
+			}
+			for key, value := range got {
+				if _, ok := test.want[key]; !ok {
+					t.Errorf("%s = %s, want it dropped", key, value.Emit())
+				}
+			}
+		})
+	}
+}
+
+func TestRedactionPolicyText(t *testing.T) {
+	hash := testPolicy(redactHash).hash
+
+	tests := []struct {
+		name     string
+		text     string
+		truncate string
+		hash     string
+		drop     string
+	}{
+		{
+			name:     "zipcode",
+			text:     "CEP 87033080 não encontrado",
+			truncate: "CEP 87033*** não encontrado",
+			hash:     "CEP " + hash("87033080") + " não encontrado",
+			drop:     "CEP [REDACTED] não encontrado",
+		},
+		{
+			name:     "zipcode with dash",
+			text:     "zipcode=87033-080",
+			truncate: "zipcode=87033***",
+			hash:     "zipcode=" + hash("87033-080"),
+			drop:     "zipcode=[REDACTED]",
+		},
+		{
+			name:     "coordinates",
+			text:     "localidade -23.5505,-46.6333",
+			truncate: "localidade -23.55,-46.63",
+			hash:     "localidade " + hash("-23.5505") + "," + hash("-46.6333"),
+			drop:     "localidade [REDACTED],[REDACTED]",
+		},
+		{
+			name:     "coordinates with few decimals",
+			text:     "q=-23.42,-51.9",
+			truncate: "q=-23.42,-51.9",
+			hash:     "q=-23.42,-51.9",
+			drop:     "q=-23.42,-51.9",
+		},
+		{
+			name:     "IP address",
+			text:     "dial tcp 192.168.100.123:443: connect: connection refused",
+			truncate: "dial tcp 192.168.100.123:443: connect: connection refused",
+			hash:     "dial tcp 192.168.100.123:443: connect: connection refused",
+			drop:     "dial tcp 192.168.100.123:443: connect: connection refused",
+		},
+		{
+			name:     "version",
+			text:     "sdk 1.2345.6 and otelhttp v0.53.0",
+			truncate: "sdk 1.2345.6 and otelhttp v0.53.0",
+			hash:     "sdk 1.2345.6 and otelhttp v0.53.0",
+			drop:     "sdk 1.2345.6 and otelhttp v0.53.0",
+		},
+		{
+			name:     "long ID",
+			text:     "trace 12345678901234",
+			truncate: "trace 12345678901234",
+			hash:     "trace 12345678901234",
+			drop:     "trace 12345678901234",
+		},
+		{
+			name:     "secret",
+			text:     "GET /v1/current.json?q=London&token=t0k3n",
+			truncate: "GET /v1/current.json?q=London&token=[REDACTED]",
+			hash:     "GET /v1/current.json?q=London&token=[REDACTED]",
+			drop:     "GET /v1/current.json?q=London&token=[REDACTED]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for mode, want := range map[string]string{redactTruncate: test.truncate, redactHash: test.hash, redactDrop: test.drop} {
+				if got := testPolicy(mode).Text(test.text); got != want {
+					t.Errorf("%s: Text(%q) = %q, want %q", mode, test.text, got, want)
+				}
+			}
+
+			// none only masks the secrets
+			want := secretPattern.ReplaceAllString(test.text, "${1}"+redacted)
+			if got := testPolicy(redactNone).Text(test.text); got != want {
+				t.Errorf("none: Text(%q) = %q, want %q", test.text, got, want)
+			}
+		})
+	}
+}
+
+func TestRedactionPolicyMasksKeys(t *testing.T) {
+	message := `Get "` + weatherAPIURL + `": dial tcp: lookup api.weatherapi.com: no such host`
+
+	for _, mode := range []string{redactNone, redactTruncate, redactHash, redactDrop} {
+		for _, kv := range []attribute.KeyValue{
+			attribute.String("url.full", weatherAPIURL),
+			attribute.String("http.url", weatherAPIURL),
+			attribute.String("exception.message", message),
+		} {
+			redactedKVs := testPolicy(mode).Attributes([]attribute.KeyValue{kv})
+			if len(redactedKVs) != 1 {
+				t.Fatalf("%s: %s dropped, want it masked", mode, kv.Key)
+			}
+			value := redactedKVs[0].Value.AsString()
+			if strings.Contains(value, "abc123secret") || !strings.Contains(value, "?key=[REDACTED]&") {
+				t.Errorf("%s: %s = %q, want the key masked", mode, kv.Key, value)
+			}
+		}
+	}
+}
+
+// TestRedactionProcessorHTTPClientSpan - WeatherAPI-style requests through otelhttp, under a span
+// that records the client error as the services do: the url.Error carries the full URL
+func TestRedactionProcessorHTTPClientSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewRedactionProcessor(recorder, testPolicy(redactTruncate))))
+	defer provider.Shutdown(context.Background())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	client := http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport, otelhttp.WithTracerProvider(provider))}
+	query := "/v1/current.json?key=abc123secret&q=-23.420512,-51.933390&aqi=yes"
+
+	get := func(url string) error {
+		ctx, span := provider.Tracer("test").Start(context.Background(), "service_weatherAPI_request")
+		defer span.End()
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		response.Body.Close()
+		span.AddEvent("response success", trace.WithAttributes(attribute.String("url.full", url)))
+
+		return nil
+	}
+	if err := get(server.URL + query); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(closed.URL + query); err == nil {
+		t.Fatal("request to a closed server succeeded")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("%d spans, want 4", len(spans))
+	}
+
+	for _, span := range spans {
+		texts := []string{span.Status().Description}
+		for _, kv := range span.Attributes() {
+			texts = append(texts, kv.Value.Emit())
+		}
+		for _, event := range span.Events() {
+			for _, kv := range event.Attributes {
+				texts = append(texts, kv.Value.Emit())
+			}
+		}
+
+		for _, text := range texts {
+			for _, raw := range []string{"abc123secret", "-23.420512", "-51.933390"} {
+				if strings.Contains(text, raw) {
+					t.Errorf("span %s exported %q with %s", span.Name(), text, raw)
+				}
+			}
+		}
+	}
+
+	// the spans end child first: client, request, client, request
+	url := ""
+	for _, kv := range spans[0].Attributes() {
+		if kv.Key == "http.url" || kv.Key == "url.full" {
+			url = kv.Value.AsString()
+		}
+	}
+	if want := server.URL + "/v1/current.json?key=[REDACTED]&q=-23.42,-51.93&aqi=yes"; url != want {
+		t.Errorf("client span url = %q, want %q", url, want)
+	}
+
+	failed := spans[3]
+	if failed.Status().Code != codes.Error || !strings.Contains(failed.Status().Description, "key=[REDACTED]&q=-23.42,-51.93") {
+		t.Errorf("status = %v %q, want the error with the URL redacted", failed.Status().Code, failed.Status().Description)
+	}
+	exception := false
+	for _, event := range failed.Events() {
+		for _, kv := range event.Attributes {
+			if kv.Key == "exception.message" && strings.Contains(kv.Value.AsString(), "key=[REDACTED]&q=-23.42,-51.93") {
+				exception = true
+			}
+		}
+	}
+	if !exception {
+		t.Error("failed request span without the redacted exception event")
+	}
+}
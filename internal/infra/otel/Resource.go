@@ -0,0 +1,82 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// newResource - resource shared by traces, metrics and logs: service name and version, SDK, host
+// (host.name is the pod name on Kubernetes), OS, process and container ID. OTEL_RESOURCE_ATTRIBUTES
+// and OTEL_SERVICE_NAME come last, so they override the detected values.
+func newResource(ctx context.Context, serviceName string, serviceVersion string) (*resource.Resource, error) {
+	attributes := []attribute.KeyValue{semconv.ServiceName(serviceName)}
+	if version := buildVersion(serviceVersion); version != "" {
+		attributes = append(attributes, semconv.ServiceVersion(version))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attributes...),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithOS(),
+		// no command line and owner: the arguments may carry secrets, and the owner lookup fails
+		// in images without /etc/passwd
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithContainer(),
+		resource.WithFromEnv(),
+	)
+	if errors.Is(err, resource.ErrPartialResource) {
+		// a detector without data (e.g. no container ID outside a container) keeps the others
+		otel.Handle(err)
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %v", err)
+	}
+
+	return res, nil
+}
+
+// buildVersion - version given at build time (-ldflags -X), or the module version or VCS revision
+// recorded by go build
+func buildVersion(version string) string {
+	if version != "" {
+		return version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+
+	return revision
+}
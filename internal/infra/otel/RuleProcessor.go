@@ -0,0 +1,227 @@
+package otel
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// maxSpansPerTrace - a trace that grows past it (e.g. a long SSE stream) is kept, and its
+	// remaining spans go straight to the exporter
+	maxSpansPerTrace = 1000
+	// maxPendingAge - traces whose local root did not end in this time are dropped
+	maxPendingAge = 10 * time.Minute
+	// decisionTTL - how long a decision is remembered for spans that end after their local root
+	decisionTTL = time.Minute
+)
+
+// RuleProcessor - applies SamplingRules when the trace ends: the spans are held per trace until
+// the local root (the span without a parent in this process) ends, and then all of them are passed
+// on to next or dropped. Healthy traces are kept by their trace ID, like TraceIDRatioBased, so
+// services with the same ratio for a route make the same decision for the same trace.
+type RuleProcessor struct {
+	next  sdktrace.SpanProcessor
+	rules SamplingRules
+
+	mu        sync.Mutex
+	pending   map[trace.TraceID]*pendingTrace
+	decisions map[trace.TraceID]decision
+}
+
+type pendingTrace struct {
+	spans []sdktrace.ReadOnlySpan
+	since time.Time
+}
+
+type decision struct {
+	keep bool
+	at   time.Time
+}
+
+// NewRuleProcessor - processor that decides with rules and hands the kept spans to next
+func NewRuleProcessor(next sdktrace.SpanProcessor, rules SamplingRules) *RuleProcessor {
+	return &RuleProcessor{
+		next:      next,
+		rules:     rules,
+		pending:   map[trace.TraceID]*pendingTrace{},
+		decisions: map[trace.TraceID]decision{},
+	}
+}
+
+func (p *RuleProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *RuleProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+
+	traceID := s.SpanContext().TraceID()
+	now := time.Now()
+
+	p.mu.Lock()
+	p.expire(now)
+
+	if decided, ok := p.decisions[traceID]; ok {
+		p.mu.Unlock()
+		if decided.keep {
+			p.next.OnEnd(s)
+		}
+		return
+	}
+
+	pending, ok := p.pending[traceID]
+	if !ok {
+		pending = &pendingTrace{since: now}
+		p.pending[traceID] = pending
+	}
+	pending.spans = append(pending.spans, s)
+
+	localRoot := !s.Parent().IsValid() || s.Parent().IsRemote()
+	if !localRoot && len(pending.spans) < maxSpansPerTrace {
+		p.mu.Unlock()
+		return
+	}
+
+	keep := !localRoot || p.keep(s, pending.spans)
+	delete(p.pending, traceID)
+	p.decisions[traceID] = decision{keep: keep, at: now}
+	p.mu.Unlock()
+
+	if keep {
+		for _, span := range pending.spans {
+			p.next.OnEnd(span)
+		}
+	}
+}
+
+// expire - forgets old decisions and drops traces whose local root never ended
+func (p *RuleProcessor) expire(now time.Time) {
+	for traceID, decided := range p.decisions {
+		if now.Sub(decided.at) > decisionTTL {
+			delete(p.decisions, traceID)
+		}
+	}
+	for traceID, pending := range p.pending {
+		if now.Sub(pending.since) > maxPendingAge {
+			delete(p.pending, traceID)
+		}
+	}
+}
+
+// keep - errors and slow requests are always kept; healthy traces by the ratio of the root route.
+// A request answered with 4xx failed on the client side: the spans that recorded the failure (e.g.
+// an invalid CEP) do not make it an error trace.
+func (p *RuleProcessor) keep(root sdktrace.ReadOnlySpan, spans []sdktrace.ReadOnlySpan) bool {
+	if status := statusCodeOf(root); status < 400 || status > 499 {
+		for _, span := range spans {
+			if failed(span) {
+				return true
+			}
+		}
+	}
+
+	if threshold := time.Duration(p.rules.SlowThreshold); threshold > 0 && root.EndTime().Sub(root.StartTime()) >= threshold {
+		return true
+	}
+
+	ratio := 1.0
+	if p.rules.DefaultRatio != nil {
+		ratio = *p.rules.DefaultRatio
+	}
+	method, path := requestOf(root)
+	for _, route := range p.rules.Routes {
+		if route.matches(method, path) {
+			ratio = route.Ratio
+			break
+		}
+	}
+
+	result := sdktrace.TraceIDRatioBased(ratio).ShouldSample(sdktrace.SamplingParameters{TraceID: root.SpanContext().TraceID()})
+
+	return result.Decision == sdktrace.RecordAndSample
+}
+
+// failed - span with status Error, a 5xx response or a recorded exception
+func failed(span sdktrace.ReadOnlySpan) bool {
+	if span.Status().Code == codes.Error {
+		return true
+	}
+
+	if statusCodeOf(span) >= 500 {
+		return true
+	}
+
+	for _, event := range span.Events() {
+		if event.Name == "exception" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// statusCodeOf - HTTP status of the response in the span, zero when it has none
+func statusCodeOf(span sdktrace.ReadOnlySpan) int64 {
+	for _, kv := range span.Attributes() {
+		switch kv.Key {
+		case "http.status_code", "http.response.status_code":
+			if kv.Value.Type() == attribute.INT64 {
+				return kv.Value.AsInt64()
+			}
+		}
+	}
+
+	return 0
+}
+
+// requestOf - method and path of the request served by the span: the route when the span has one,
+// otherwise the path without the query string
+func requestOf(span sdktrace.ReadOnlySpan) (method string, path string) {
+	var target string
+	for _, kv := range span.Attributes() {
+		switch kv.Key {
+		case "http.method", "http.request.method":
+			method = kv.Value.AsString()
+		case "http.route":
+			path = kv.Value.AsString()
+		case "url.path", "http.target":
+			target = kv.Value.AsString()
+		}
+	}
+
+	if path == "" {
+		path, _, _ = strings.Cut(target, "?")
+	}
+
+	return method, path
+}
+
+func (r RouteRule) matches(method string, path string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+
+	if prefix, ok := strings.CutSuffix(r.Path, "/*"); ok {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+
+	return path == r.Path
+}
+
+// Shutdown - pending traces are dropped: their local root did not end
+func (p *RuleProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *RuleProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
@@ -0,0 +1,223 @@
+package otel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func ratio(value float64) *float64 {
+	return &value
+}
+
+// request - a server span for method and route with a child span, ended after duration; child
+// changes the child span before it ends
+type request struct {
+	method   string
+	route    string
+	status   int
+	duration time.Duration
+	child    func(trace.Span)
+}
+
+// run - ends the request spans through a RuleProcessor with rules and returns the spans kept
+func (r request) run(t *testing.T, rules SamplingRules) []sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewRuleProcessor(recorder, rules)))
+	tracer := provider.Tracer("test")
+
+	start := time.Now()
+	attributes := []attribute.KeyValue{
+		attribute.String("http.method", r.method),
+		attribute.String("http.route", r.route),
+		attribute.String("http.target", r.route+"?x=1"),
+	}
+	if r.status != 0 {
+		attributes = append(attributes, attribute.Int("http.status_code", r.status))
+	}
+	ctx, root := tracer.Start(context.Background(), r.method+" "+r.route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attributes...),
+	)
+
+	_, child := tracer.Start(ctx, "child", trace.WithTimestamp(start))
+	if r.child != nil {
+		r.child(child)
+	}
+	child.End(trace.WithTimestamp(start.Add(r.duration / 2)))
+	if kept := len(recorder.Ended()); kept != 0 {
+		t.Fatalf("%d spans passed on before the local root ended", kept)
+	}
+	root.End(trace.WithTimestamp(start.Add(r.duration)))
+
+	return recorder.Ended()
+}
+
+func TestRuleProcessorKeep(t *testing.T) {
+	rules := SamplingRules{
+		SlowThreshold: Duration(time.Second),
+		DefaultRatio:  ratio(0),
+		Routes: []RouteRule{
+			{Method: "POST", Path: "/cep", Ratio: 0},
+			{Path: "/v1/weather/*", Ratio: 1},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		kept    bool
+	}{
+		{
+			name:    "healthy request, route at 0%",
+			request: request{method: "POST", route: "/cep", status: 200, duration: time.Millisecond},
+		},
+		{
+			name:    "healthy request, route with prefix at 100%",
+			request: request{method: "GET", route: "/v1/weather/cep/{cep}", status: 200, duration: time.Millisecond},
+			kept:    true,
+		},
+		{
+			name:    "healthy request, other method on a route with method",
+			request: request{method: "GET", route: "/cep", status: 200, duration: time.Millisecond},
+		},
+		{
+			name: "span with status Error",
+			request: request{method: "POST", route: "/cep", status: 200, duration: time.Millisecond, child: func(span trace.Span) {
+				span.SetStatus(codes.Error, "error on search")
+			}},
+			kept: true,
+		},
+		{
+			name: "recorded exception",
+			request: request{method: "POST", route: "/cep", status: 200, duration: time.Millisecond, child: func(span trace.Span) {
+				span.RecordError(context.DeadlineExceeded)
+			}},
+			kept: true,
+		},
+		{
+			name:    "5xx response",
+			request: request{method: "POST", route: "/cep", status: 502, duration: time.Millisecond},
+			kept:    true,
+		},
+		{
+			name: "4xx response with the failure recorded in a span",
+			request: request{method: "POST", route: "/cep", status: 422, duration: time.Millisecond, child: func(span trace.Span) {
+				span.RecordError(context.Canceled)
+				span.SetStatus(codes.Error, "error on check validate zipcode")
+			}},
+		},
+		{
+			name:    "slow request",
+			request: request{method: "POST", route: "/cep", status: 200, duration: 2 * time.Second},
+			kept:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spans := test.request.run(t, rules)
+
+			if test.kept && len(spans) != 2 {
+				t.Errorf("kept %d spans, want the 2 of the trace", len(spans))
+			}
+			if !test.kept && len(spans) != 0 {
+				t.Errorf("kept %d spans, want none", len(spans))
+			}
+		})
+	}
+}
+
+func TestRuleProcessorLateSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewRuleProcessor(recorder, SamplingRules{})))
+	tracer := provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "GET /v1/weather/cep/{cep}/stream")
+	_, late := tracer.Start(ctx, "late")
+	root.End()
+	if kept := len(recorder.Ended()); kept != 1 {
+		t.Fatalf("kept %d spans when the root ended, want 1", kept)
+	}
+
+	// the decision is remembered for the spans that end after the root
+	late.End()
+	if kept := len(recorder.Ended()); kept != 2 {
+		t.Errorf("kept %d spans after the late span ended, want 2", kept)
+	}
+}
+
+func TestRuleProcessorMaxSpansPerTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	rules := SamplingRules{DefaultRatio: ratio(0)}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewRuleProcessor(recorder, rules)))
+	tracer := provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "GET /v1/weather/cep/{cep}/stream")
+	for i := 0; i < maxSpansPerTrace+1; i++ {
+		_, span := tracer.Start(ctx, "poll")
+		span.End()
+	}
+	root.End()
+
+	// the trace that grew past the limit is kept, and the rest goes straight to the exporter
+	if kept := len(recorder.Ended()); kept != maxSpansPerTrace+2 {
+		t.Errorf("kept %d spans, want %d", kept, maxSpansPerTrace+2)
+	}
+}
+
+func TestRuleProcessorExpire(t *testing.T) {
+	processor := NewRuleProcessor(tracetest.NewSpanRecorder(), SamplingRules{})
+	now := time.Now()
+
+	pendingID, decidedID := trace.TraceID{1}, trace.TraceID{2}
+	processor.pending[pendingID] = &pendingTrace{since: now.Add(-maxPendingAge - time.Second)}
+	processor.pending[trace.TraceID{3}] = &pendingTrace{since: now}
+	processor.decisions[decidedID] = decision{keep: true, at: now.Add(-decisionTTL - time.Second)}
+	processor.decisions[trace.TraceID{4}] = decision{keep: true, at: now}
+
+	processor.expire(now)
+
+	if _, ok := processor.pending[pendingID]; ok {
+		t.Error("trace whose root never ended is still pending")
+	}
+	if _, ok := processor.decisions[decidedID]; ok {
+		t.Error("old decision is still remembered")
+	}
+	if len(processor.pending) != 1 || len(processor.decisions) != 1 {
+		t.Errorf("recent entries expired: %d pending, %d decisions", len(processor.pending), len(processor.decisions))
+	}
+}
+
+func TestRouteRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule   RouteRule
+		method string
+		path   string
+		want   bool
+	}{
+		{RouteRule{Method: "POST", Path: "/cep"}, "POST", "/cep", true},
+		{RouteRule{Method: "post", Path: "/cep"}, "POST", "/cep", true},
+		{RouteRule{Method: "POST", Path: "/cep"}, "GET", "/cep", false},
+		{RouteRule{Path: "/cep"}, "GET", "/cep", true},
+		{RouteRule{Path: "/cep"}, "POST", "/cep/astronomy", false},
+		{RouteRule{Path: "/v1/weather/*"}, "GET", "/v1/weather", true},
+		{RouteRule{Path: "/v1/weather/*"}, "GET", "/v1/weather/cep/{cep}", true},
+		{RouteRule{Path: "/v1/weather/*"}, "GET", "/v1/weatherman", false},
+	}
+
+	for _, test := range tests {
+		if got := test.rule.matches(test.method, test.path); got != test.want {
+			t.Errorf("%+v matches(%s %s) = %v, want %v", test.rule, test.method, test.path, got, test.want)
+		}
+	}
+}
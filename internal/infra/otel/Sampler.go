@@ -0,0 +1,249 @@
+package otel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Sampler names accepted in OTEL_TRACES_SAMPLER and in the config file. Besides the SDK ones,
+// ratelimited keeps up to N new traces per second and rules decides when the trace ends (see
+// RuleProcessor).
+const (
+	samplerAlwaysOn               = "always_on"
+	samplerAlwaysOff              = "always_off"
+	samplerTraceIDRatio           = "traceidratio"
+	samplerParentBasedAlwaysOn    = "parentbased_always_on"
+	samplerParentBasedAlwaysOff   = "parentbased_always_off"
+	samplerParentBasedRatio       = "parentbased_traceidratio"
+	samplerRateLimited            = "ratelimited"
+	samplerParentBasedRateLimited = "parentbased_ratelimited"
+	samplerRules                  = "rules"
+)
+
+// SamplingConfig - sampling strategy, read from the JSON file in OTEL_TRACES_SAMPLER_CONFIG;
+// OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG override the file.
+type SamplingConfig struct {
+	// Sampler - one of the sampler names; empty keeps the SDK default (parentbased_always_on)
+	Sampler string `json:"sampler"`
+	// Ratio - share of traces kept by traceidratio and parentbased_traceidratio (0 to 1)
+	Ratio *float64 `json:"ratio,omitempty"`
+	// TracesPerSecond - new traces kept per second by ratelimited and parentbased_ratelimited
+	TracesPerSecond *float64 `json:"traces_per_second,omitempty"`
+	// Rules - decisions of the rules sampler
+	Rules SamplingRules `json:"rules"`
+}
+
+// SamplingRules - the rules sampler always keeps traces with errors (span status Error, HTTP
+// status 5xx or a recorded exception, unless the request was answered with 4xx) and traces whose
+// local root took at least SlowThreshold;
+// the healthy ones are kept at the ratio of the first route matching the root span, or DefaultRatio.
+type SamplingRules struct {
+	SlowThreshold Duration    `json:"slow_threshold"`
+	DefaultRatio  *float64    `json:"default_ratio,omitempty"`
+	Routes        []RouteRule `json:"routes"`
+}
+
+// RouteRule - ratio of healthy traces for a path; a path ending in /* matches the prefix, and an
+// empty method matches any method
+type RouteRule struct {
+	Method string  `json:"method,omitempty"`
+	Path   string  `json:"path"`
+	Ratio  float64 `json:"ratio"`
+}
+
+// Duration - time.Duration written as a Go duration string (e.g. 500ms) in the config file
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// defaultSamplingRules - without a config file the rules sampler keeps errors and requests slower
+// than 1s, samples healthy POST /cep calls at 1% and keeps every other trace
+func defaultSamplingRules() SamplingRules {
+	return SamplingRules{
+		SlowThreshold: Duration(time.Second),
+		Routes:        []RouteRule{{Method: "POST", Path: "/cep", Ratio: 0.01}},
+	}
+}
+
+// loadSamplingConfig - config file, if any, with the OTEL_TRACES_SAMPLER* overrides applied
+func loadSamplingConfig() (SamplingConfig, error) {
+	config := SamplingConfig{Rules: defaultSamplingRules()}
+
+	if path := os.Getenv("OTEL_TRACES_SAMPLER_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("failed to read sampling config [OTEL_TRACES_SAMPLER_CONFIG]: %v", err)
+		}
+
+		config = SamplingConfig{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("invalid sampling config [OTEL_TRACES_SAMPLER_CONFIG]: %v", err)
+		}
+	}
+
+	if value := os.Getenv("OTEL_TRACES_SAMPLER"); value != "" {
+		config.Sampler = value
+	}
+	config.Sampler = strings.ToLower(strings.TrimSpace(config.Sampler))
+
+	if value := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); value != "" {
+		arg, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid sampler argument [OTEL_TRACES_SAMPLER_ARG]: %q", value)
+		}
+
+		switch config.Sampler {
+		case samplerTraceIDRatio, samplerParentBasedRatio:
+			config.Ratio = &arg
+		case samplerRateLimited, samplerParentBasedRateLimited:
+			config.TracesPerSecond = &arg
+		case samplerRules:
+			config.Rules.DefaultRatio = &arg
+		}
+	}
+
+	return config, nil
+}
+
+// sdkUnsupportedSampler - error of the SDK for a sampler in OTEL_TRACES_SAMPLER that only newSampler
+// knows; the SDK keeps its default, and the sampler built by newSampler replaces it
+func sdkUnsupportedSampler(err error) bool {
+	name, ok := strings.CutPrefix(err.Error(), "unsupported sampler: ")
+	if !ok {
+		return false
+	}
+
+	switch name {
+	case samplerRateLimited, samplerParentBasedRateLimited, samplerRules:
+		return true
+	}
+
+	return false
+}
+
+// validRatio - ratio given, or fallback, checked to be between 0 and 1
+func validRatio(ratio *float64, fallback float64, name string) (float64, error) {
+	if ratio == nil {
+		return fallback, nil
+	}
+	if *ratio < 0 || *ratio > 1 {
+		return 0, fmt.Errorf("sampling %s must be between 0 and 1: %v", name, *ratio)
+	}
+
+	return *ratio, nil
+}
+
+// newSampler - head sampler for the config, and the rules to be applied when the trace ends (nil
+// for all samplers but rules). Unset, the SDK default is kept.
+func newSampler(config SamplingConfig) (sdktrace.Sampler, *SamplingRules, error) {
+	switch config.Sampler {
+	case "", samplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil, nil
+	case samplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil, nil
+	case samplerAlwaysOff:
+		return sdktrace.NeverSample(), nil, nil
+	case samplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil, nil
+	case samplerTraceIDRatio, samplerParentBasedRatio:
+		ratio, err := validRatio(config.Ratio, 1, "ratio")
+		if err != nil {
+			return nil, nil, err
+		}
+		if config.Sampler == samplerTraceIDRatio {
+			return sdktrace.TraceIDRatioBased(ratio), nil, nil
+		}
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil, nil
+	case samplerRateLimited, samplerParentBasedRateLimited:
+		if config.TracesPerSecond == nil || *config.TracesPerSecond <= 0 {
+			return nil, nil, fmt.Errorf("sampler %s needs traces_per_second (or OTEL_TRACES_SAMPLER_ARG) greater than zero", config.Sampler)
+		}
+		sampler := newRateLimitedSampler(*config.TracesPerSecond)
+		if config.Sampler == samplerRateLimited {
+			return sampler, nil, nil
+		}
+		return sdktrace.ParentBased(sampler), nil, nil
+	case samplerRules:
+		rules := config.Rules
+		if _, err := validRatio(rules.DefaultRatio, 1, "default_ratio"); err != nil {
+			return nil, nil, err
+		}
+		for _, route := range rules.Routes {
+			if _, err := validRatio(&route.Ratio, 1, "ratio of "+route.Path); err != nil {
+				return nil, nil, err
+			}
+		}
+		// every span is recorded and propagated as sampled, so the services downstream record
+		// their part too; RuleProcessor drops the healthy traces when they end
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), &rules, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported sampler [OTEL_TRACES_SAMPLER]: %q", config.Sampler)
+}
+
+// rateLimitedSampler - token bucket refilled at limit tokens per second, holding up to one
+// second of traces, so bursts above the limit are dropped
+type rateLimitedSampler struct {
+	limit float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimitedSampler(limit float64) *rateLimitedSampler {
+	return &rateLimitedSampler{
+		limit:  limit,
+		tokens: limit,
+		last:   time.Now(),
+	}
+}
+
+func (s *rateLimitedSampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := sdktrace.SamplingResult{
+		Decision:   sdktrace.Drop,
+		Tracestate: trace.SpanContextFromContext(parameters.ParentContext).TraceState(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tokens += now.Sub(s.last).Seconds() * s.limit
+	if capacity := max(s.limit, 1); s.tokens > capacity {
+		s.tokens = capacity
+	}
+	s.last = now
+
+	if s.tokens >= 1 {
+		s.tokens--
+		result.Decision = sdktrace.RecordAndSample
+	}
+
+	return result
+}
+
+func (s *rateLimitedSampler) Description() string {
+	return fmt.Sprintf("RateLimited{%g}", s.limit)
+}
@@ -0,0 +1,198 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestRateLimitedSamplerRefill(t *testing.T) {
+	sampler := newRateLimitedSampler(2)
+	parameters := sdktrace.SamplingParameters{ParentContext: context.Background()}
+
+	for i := 0; i < 2; i++ {
+		if decision := sampler.ShouldSample(parameters).Decision; decision != sdktrace.RecordAndSample {
+			t.Fatalf("trace %d of the limit = %v, want RecordAndSample", i+1, decision)
+		}
+	}
+	if decision := sampler.ShouldSample(parameters).Decision; decision != sdktrace.Drop {
+		t.Fatalf("trace above the limit = %v, want Drop", decision)
+	}
+
+	// half a second refills one token of the two per second
+	sampler.mu.Lock()
+	sampler.last = sampler.last.Add(-500 * time.Millisecond)
+	sampler.mu.Unlock()
+	if decision := sampler.ShouldSample(parameters).Decision; decision != sdktrace.RecordAndSample {
+		t.Fatalf("trace after the refill = %v, want RecordAndSample", decision)
+	}
+	if decision := sampler.ShouldSample(parameters).Decision; decision != sdktrace.Drop {
+		t.Fatalf("second trace after half a second = %v, want Drop", decision)
+	}
+
+	// a long pause refills up to the capacity, one second of traces
+	sampler.mu.Lock()
+	sampler.last = sampler.last.Add(-time.Minute)
+	sampler.mu.Unlock()
+	kept := 0
+	for i := 0; i < 5; i++ {
+		if sampler.ShouldSample(parameters).Decision == sdktrace.RecordAndSample {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Errorf("kept %d traces after a long pause, want the capacity of 2", kept)
+	}
+}
+
+func TestLoadSamplingConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sampling.json")
+	config := `{"sampler":"rules","rules":{"slow_threshold":"250ms","default_ratio":0.5,"routes":[{"path":"/v1/weather/*","ratio":0.1}]}}`
+	if err := os.WriteFile(file, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		sampler string
+		check   func(t *testing.T, config SamplingConfig)
+		err     bool
+	}{
+		{
+			name:    "defaults",
+			sampler: "",
+			check: func(t *testing.T, config SamplingConfig) {
+				if len(config.Rules.Routes) != 1 || config.Rules.Routes[0].Path != "/cep" {
+					t.Errorf("routes = %+v, want the POST /cep default", config.Rules.Routes)
+				}
+			},
+		},
+		{
+			name:    "file",
+			env:     map[string]string{"OTEL_TRACES_SAMPLER_CONFIG": file},
+			sampler: samplerRules,
+			check: func(t *testing.T, config SamplingConfig) {
+				if time.Duration(config.Rules.SlowThreshold) != 250*time.Millisecond {
+					t.Errorf("slow_threshold = %v, want 250ms", time.Duration(config.Rules.SlowThreshold))
+				}
+				if *config.Rules.DefaultRatio != 0.5 {
+					t.Errorf("default_ratio = %v, want 0.5", *config.Rules.DefaultRatio)
+				}
+			},
+		},
+		{
+			name:    "argument overrides the file",
+			env:     map[string]string{"OTEL_TRACES_SAMPLER_CONFIG": file, "OTEL_TRACES_SAMPLER_ARG": "0.2"},
+			sampler: samplerRules,
+			check: func(t *testing.T, config SamplingConfig) {
+				if *config.Rules.DefaultRatio != 0.2 {
+					t.Errorf("default_ratio = %v, want 0.2", *config.Rules.DefaultRatio)
+				}
+			},
+		},
+		{
+			name:    "rate limit from the argument",
+			env:     map[string]string{"OTEL_TRACES_SAMPLER": "ParentBased_RateLimited", "OTEL_TRACES_SAMPLER_ARG": "10"},
+			sampler: samplerParentBasedRateLimited,
+			check: func(t *testing.T, config SamplingConfig) {
+				if config.TracesPerSecond == nil || *config.TracesPerSecond != 10 {
+					t.Errorf("traces_per_second = %v, want 10", config.TracesPerSecond)
+				}
+			},
+		},
+		{
+			name: "invalid argument",
+			env:  map[string]string{"OTEL_TRACES_SAMPLER": samplerTraceIDRatio, "OTEL_TRACES_SAMPLER_ARG": "half"},
+			err:  true,
+		},
+		{
+			name: "missing file",
+			env:  map[string]string{"OTEL_TRACES_SAMPLER_CONFIG": filepath.Join(t.TempDir(), "missing.json")},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_TRACES_SAMPLER", "OTEL_TRACES_SAMPLER_ARG", "OTEL_TRACES_SAMPLER_CONFIG"} {
+				t.Setenv(key, test.env[key])
+			}
+
+			config, err := loadSamplingConfig()
+			if test.err {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadSamplingConfig: %v", err)
+			}
+			if config.Sampler != test.sampler {
+				t.Errorf("sampler = %q, want %q", config.Sampler, test.sampler)
+			}
+			test.check(t, config)
+		})
+	}
+}
+
+func TestNewSamplerInvalidRatio(t *testing.T) {
+	configs := []SamplingConfig{
+		{Sampler: samplerTraceIDRatio, Ratio: ratio(1.5)},
+		{Sampler: samplerRules, Rules: SamplingRules{DefaultRatio: ratio(-0.1)}},
+		{Sampler: samplerRules, Rules: SamplingRules{Routes: []RouteRule{{Path: "/cep", Ratio: 2}}}},
+		{Sampler: samplerRateLimited},
+		{Sampler: "sometimes"},
+	}
+
+	for _, config := range configs {
+		if _, _, err := newSampler(config); err == nil {
+			t.Errorf("newSampler(%+v) accepted an invalid config", config)
+		}
+	}
+}
+
+func TestNewTraceProviderKeepsSamplerEnvironment(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	t.Setenv("OTEL_TRACES_SAMPLER", samplerRules)
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+
+	provider, err := newTraceProvider(context.Background(), resource.Empty(), RedactionPolicy{})
+	if err != nil {
+		t.Fatalf("newTraceProvider: %v", err)
+	}
+	defer provider.Shutdown(context.Background())
+
+	if value := os.Getenv("OTEL_TRACES_SAMPLER"); value != samplerRules {
+		t.Errorf("OTEL_TRACES_SAMPLER = %q, want %q", value, samplerRules)
+	}
+	if value := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); value != "0.25" {
+		t.Errorf("OTEL_TRACES_SAMPLER_ARG = %q, want 0.25", value)
+	}
+}
+
+func TestSDKUnsupportedSampler(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("unsupported sampler: rules"), true},
+		{errors.New("unsupported sampler: ratelimited"), true},
+		{errors.New("unsupported sampler: parentbased_ratelimited"), true},
+		{errors.New("unsupported sampler: sometimes"), false},
+		{errors.New("failed to export spans"), false},
+	}
+
+	for _, test := range tests {
+		if got := sdkUnsupportedSampler(test.err); got != test.want {
+			t.Errorf("sdkUnsupportedSampler(%q) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
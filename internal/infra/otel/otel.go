@@ -3,21 +3,24 @@ package otel
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/nagahshi/pos_go_weather_otel/internal/logging"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
-// SetupOTelSDK - configures the OpenTelemetry SDK with the given service name.
-func SetupOTelSDK(serviceName string, ctx context.Context) (shutdown func(context.Context) error, err error) {
+// SetupOTelSDK - configures the OpenTelemetry SDK with the given service name and version (empty
+// for the one recorded by go build). With OTEL_SDK_DISABLED=true nothing is set up, and the global
+// providers keep discarding the telemetry.
+func SetupOTelSDK(serviceName string, serviceVersion string, ctx context.Context) (shutdown func(context.Context) error, err error) {
 	var shutdownFuncs []func(context.Context) error
 
 	shutdown = func(ctx context.Context) error {
@@ -29,71 +32,129 @@ func SetupOTelSDK(serviceName string, ctx context.Context) (shutdown func(contex
 		return err
 	}
 
-	prop := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(handleError))
 
+	// set even with the SDK disabled, so the context received is still passed on to the services called
+	prop, err := NewPropagator()
+	if err != nil {
+		return shutdown, err
+	}
 	otel.SetTextMapPropagator(prop)
 
-	tracerProvider, err := newTraceProvider(ctx, serviceName)
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return shutdown, nil
+	}
+
+	res, err := newResource(ctx, serviceName, serviceVersion)
+	if err != nil {
+		return shutdown, err
+	}
+
+	redaction, err := loadRedactionPolicy()
+	if err != nil {
+		return shutdown, err
+	}
+
+	tracerProvider, err := newTraceProvider(ctx, res, redaction)
 	if err != nil {
 		return shutdown, errors.Join(err, shutdown(ctx))
 	}
 	shutdownFuncs = append(shutdownFuncs, tracerProvider.Shutdown)
-	otel.SetTracerProvider(tracerProvider)
+	// span events are also written as log records, correlated with the span and redacted the same way
+	otel.SetTracerProvider(logging.TracerProvider(tracerProvider, redaction.Attributes))
+
+	meterProvider, err := newMeterProvider(ctx, res)
+	if err != nil {
+		return shutdown, errors.Join(err, shutdown(ctx))
+	}
+	shutdownFuncs = append(shutdownFuncs, meterProvider.Shutdown)
+	otel.SetMeterProvider(meterProvider)
+
+	loggerProvider, err := newLoggerProvider(ctx, res)
+	if err != nil {
+		return shutdown, errors.Join(err, shutdown(ctx))
+	}
+	shutdownFuncs = append(shutdownFuncs, loggerProvider.Shutdown)
+	global.SetLoggerProvider(loggerProvider)
 
 	return
 }
 
-// newTraceProvider - creates a new trace provider configured with the given service name.
-// func newTraceProvider(ctx context.Context, serviceName string) (*trace.TracerProvider, error) {
-// 	zipkinEndpoint := os.Getenv("ZIPKIN_ENDPOINT")
-// 	if zipkinEndpoint == "" {
-// 		return nil, errors.New("zipkin [ZIPKIN_ENDPOINT] not configured yet")
-// 	}
+// handleError - errors of the SDK and of the exporters go to the standard log, as with the default
+// handler, except the unsupported sampler the SDK reports when it reads OTEL_TRACES_SAMPLER by itself
+// and finds ratelimited or rules, which newSampler builds
+func handleError(err error) {
+	if sdkUnsupportedSampler(err) {
+		return
+	}
 
-// 	traceExporter, err := zipkin.New(zipkinEndpoint)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	log.Print(err)
+}
 
-// 	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)))
-// 	if err != nil {
-// 		return nil, err
-// 	}
+// newTraceProvider - creates a trace provider that sends spans to the exporters in
+// OTEL_TRACES_EXPORTER (see newSpanProcessor), sampled as configured by loadSamplingConfig, with
+// the baggage members in OTEL_BAGGAGE_SPAN_ATTRIBUTES as attributes and with personal data removed
+// by redaction.
+func newTraceProvider(ctx context.Context, res *resource.Resource, redaction RedactionPolicy) (*trace.TracerProvider, error) {
+	samplingConfig, err := loadSamplingConfig()
+	if err != nil {
+		return nil, err
+	}
+	sampler, rules, err := newSampler(samplingConfig)
+	if err != nil {
+		return nil, err
+	}
+	processor, err := newSpanProcessor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// redacted after the rules decide, since they read the route and the path of the request
+	processor = NewRedactionProcessor(processor, redaction)
+	if rules != nil {
+		processor = NewRuleProcessor(processor, *rules)
+	}
 
-// 	bsp := trace.NewBatchSpanProcessor(traceExporter)
-// 	traceProvider := trace.NewTracerProvider(
-// 		trace.WithSampler(trace.AlwaysSample()),
-// 		trace.WithResource(res),
-// 		trace.WithSpanProcessor(bsp),
-// 	)
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(res),
+	}
+	// registered first, so the baggage attributes are set before the other processors see the span
+	if keys := baggageSpanKeys(); len(keys) > 0 {
+		options = append(options, sdktrace.WithSpanProcessor(NewBaggageProcessor(keys)))
+	}
+	options = append(options, sdktrace.WithSpanProcessor(processor))
 
-// 	return traceProvider, nil
-// }
+	return sdktrace.NewTracerProvider(options...), nil
+}
 
-func newTraceProvider(ctx context.Context, serviceName string) (*trace.TracerProvider, error) {
-	collectorEndpoint := os.Getenv("COLLECTOR_ENDPOINT")
-	if collectorEndpoint == "" {
-		return nil, errors.New("[COLLECTOR_ENDPOINT] not configured yet")
+// newMeterProvider - creates a meter provider that pushes metrics to the exporters in
+// OTEL_METRICS_EXPORTER. The export interval follows OTEL_METRIC_EXPORT_INTERVAL (60s by default).
+func newMeterProvider(ctx context.Context, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	readers, err := newMetricReaders(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(collectorEndpoint), otlptracehttp.WithInsecure())
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP trace exporter: %v", err)
+	options := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	for _, reader := range readers {
+		options = append(options, sdkmetric.WithReader(reader))
 	}
 
-	res, err := resource.New(ctx, resource.WithAttributes(
-		semconv.ServiceName(serviceName),
-	))
+	return sdkmetric.NewMeterProvider(options...), nil
+}
+
+// newLoggerProvider - creates a logger provider that pushes log records to the exporters in
+// OTEL_LOGS_EXPORTER. Records carry the trace and span IDs of the context they were emitted with.
+func newLoggerProvider(ctx context.Context, res *resource.Resource) (*sdklog.LoggerProvider, error) {
+	processors, err := newLogProcessors(ctx)
 	if err != nil {
-		log.Fatalf("failed to create resource: %v", err)
+		return nil, err
 	}
 
-	tracerProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(res),
-	)
-
-	otel.SetTracerProvider(tracerProvider)
+	options := []sdklog.LoggerProviderOption{sdklog.WithResource(res)}
+	for _, processor := range processors {
+		options = append(options, sdklog.WithProcessor(processor))
+	}
 
-	return tracerProvider, nil
+	return sdklog.NewLoggerProvider(options...), nil
 }
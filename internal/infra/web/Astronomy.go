@@ -0,0 +1,149 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultTimezone - fuso usado quando a requisição não informa ?tz=
+const defaultTimezone = "America/Sao_Paulo"
+
+// GetAstronomyByCEP - nascer e pôr do sol, duração do dia e fase da lua pelo CEP do corpo (POST /cep/astronomy)
+func (wh *Handler) GetAstronomyByCEP(w http.ResponseWriter, r *http.Request) {
+	wh.astronomyByCEP(w, r, zipcodeFromBody)
+}
+
+// GetAstronomyByCEPPath - dados astronômicos pelo CEP do path (GET /v1/weather/cep/{cep}/astronomy)
+func (wh *Handler) GetAstronomyByCEPPath(w http.ResponseWriter, r *http.Request) {
+	wh.astronomyByCEP(w, r, zipcodeFromPath)
+}
+
+// astronomyByCEP - fluxo comum dos dados astronômicos pelo CEP
+func (wh *Handler) astronomyByCEP(w http.ResponseWriter, r *http.Request, extract zipcodeExtractor) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx := r.Context()
+	ctx, spanValidate := tracer.Start(ctx, "validate_astronomy_input")
+
+	spanValidate.AddEvent("extract zipcode", trace.WithAttributes(attribute.String("http.method", r.Method)))
+	rawCEP, err := extract(r)
+	if err != nil {
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on decode body")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "corpo da requisição inválido", err), i18n.ErrDecodeZipcode)
+		spanValidate.End()
+		return
+	}
+
+	CEP, ok := sanitizeCEP(rawCEP)
+	if !ok {
+		spanValidate.RecordError(apperror.ErrInvalidCEP)
+		spanValidate.SetStatus(codes.Error, "error on check validate zipcode")
+		writeProblem(ctx, w, r, lang, apperror.ErrInvalidCEP, i18n.ErrInvalidZipcode)
+		spanValidate.End()
+		return
+	}
+
+	query := r.URL.Query()
+	timezone := query.Get("tz")
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on load timezone")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "fuso horário inválido", err), i18n.ErrInvalidTimezone)
+		spanValidate.End()
+		return
+	}
+
+	date := time.Now().In(location)
+	if query.Get("date") != "" {
+		date, err = time.ParseInLocation(time.DateOnly, query.Get("date"), location)
+		if err != nil {
+			spanValidate.RecordError(err)
+			spanValidate.SetStatus(codes.Error, "error on parse date")
+			writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "data inválida", err), i18n.ErrInvalidDate)
+			spanValidate.End()
+			return
+		}
+	}
+
+	crossCheck := false
+	switch strings.ToLower(query.Get("crosscheck")) {
+	case "yes", "true", "1":
+		crossCheck = true
+	}
+
+	spanValidate.AddEvent(
+		"astronomy input validated",
+		trace.WithAttributes(
+			attribute.String("zipcode", CEP),
+			attribute.String("date", date.Format(time.DateOnly)),
+			attribute.String("timezone", timezone),
+			attribute.Bool("crosscheck", crossCheck),
+		),
+	)
+	spanValidate.End()
+
+	ctx, spanSearch := tracer.Start(ctx, "zipcode-search")
+	outputCEP, err := wh.GetLatLonByCEP.Execute(ctx, CEP)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search location")
+		writeProblem(ctx, w, r, lang, err, i18n.ErrAstronomyNotFound)
+		spanSearch.End()
+		return
+	}
+	spanSearch.End()
+
+	ctx, spanAstronomy := tracer.Start(ctx, "astronomy-calculate")
+	outputAstronomy, err := wh.GetAstronomy.Execute(ctx, dto.AstronomyInput{
+		Latitude:   outputCEP.Latitude,
+		Longitude:  outputCEP.Longitude,
+		Date:       date,
+		CrossCheck: crossCheck,
+	})
+	if err != nil {
+		spanAstronomy.RecordError(err)
+		spanAstronomy.SetStatus(codes.Error, "error on calculate astronomy")
+		writeProblem(ctx, w, r, lang, err, i18n.ErrAstronomyCalculate)
+		spanAstronomy.End()
+		return
+	}
+	outputAstronomy.City = outputCEP.CIDADE
+	outputAstronomy.MoonPhase = i18n.T(lang, i18n.PrefixMoonPhase+strings.ReplaceAll(strings.ToLower(outputAstronomy.MoonPhase), " ", "_"))
+	spanAstronomy.End()
+
+	_, spanResponse := tracer.Start(ctx, "astronomy_response")
+	defer spanResponse.End()
+
+	w.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(outputAstronomy)
+	if err != nil {
+		spanResponse.RecordError(err)
+		spanResponse.SetStatus(codes.Error, "error on response")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInternal, "falha ao montar resposta", err), i18n.ErrEncodeResponse)
+		return
+	}
+
+	spanResponse.AddEvent(
+		"response success",
+		trace.WithAttributes(
+			attribute.String("city", outputAstronomy.City),
+			attribute.String("sunrise", outputAstronomy.Sunrise),
+			attribute.String("sunset", outputAstronomy.Sunset),
+		),
+	)
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// varyHeaders - headers que mudam o corpo das respostas de clima, para caches compartilhados (CDN)
+const varyHeaders = "Accept, Accept-Language, X-Unit-Preset, X-Units, X-Precision, X-Rounding"
+
+// cacheControl - Cache-Control com o tempo restante até expiresAt; sem expiração o cliente
+// deve revalidar sempre, o que com o ETag ainda evita reenviar o corpo
+func cacheControl(expiresAt time.Time) string {
+	if expiresAt.IsZero() {
+		return "no-cache"
+	}
+
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	return "public, max-age=" + strconv.Itoa(maxAge)
+}
+
+// etagOf - ETag forte calculado do corpo da resposta
+func etagOf(payload []byte) string {
+	sum := sha256.Sum256(payload)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified - compara o If-None-Match da requisição com o ETag (comparação fraca, RFC 9110 13.1.2)
+func notModified(r *http.Request, etag string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeCacheable - responde o corpo com ETag e Cache-Control, ou 304 sem corpo quando o cliente
+// já tem a mesma versão; devolve true no 304
+func writeCacheable(w http.ResponseWriter, r *http.Request, payload []byte, cacheControl string) bool {
+	etag := etagOf(payload)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("Vary", varyHeaders)
+
+	if notModified(r, etag) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	w.Write(payload)
+
+	return false
+}
@@ -0,0 +1,214 @@
+package web
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dataloader"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// graphQLMaxBody - tamanho máximo do corpo de uma requisição GraphQL
+	graphQLMaxBody = 64 << 10
+	// graphQLMaxDepth - profundidade máxima da consulta (Query > address > weather > airQuality > campo)
+	graphQLMaxDepth = 6
+	// graphQLMaxCost - custo máximo da consulta, conferido antes da execução (ver graphQLQueryCost)
+	graphQLMaxCost = 200
+	// graphQLBatchWait e graphQLMaxBatch - janela e tamanho máximo dos lotes dos dataloaders
+	graphQLBatchWait = 2 * time.Millisecond
+	graphQLMaxBatch  = 50
+	// graphQLMaxConcurrency - consultas simultâneas de um lote a cada provedor
+	graphQLMaxConcurrency = 8
+)
+
+// graphQLSchemaSDL - schema do gateway GraphQL
+//
+//go:embed schema.graphql
+var graphQLSchemaSDL string
+
+// GraphQLRequest - corpo de uma requisição GraphQL (POST /graphql)
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// weatherKey - chave do dataloader de clima: coordenadas e opções repassadas ao serviço B
+type weatherKey struct {
+	latitude  string
+	longitude string
+	query     string
+}
+
+// forecastKey - chave do dataloader de previsão
+type forecastKey struct {
+	latitude  string
+	longitude string
+	days      int
+}
+
+// graphQLLoaders - estado de uma requisição GraphQL: idioma negociado e dataloaders, que agrupam e
+// memorizam as consultas feitas pelos resolvers durante a requisição
+type graphQLLoaders struct {
+	lang      i18n.Lang
+	addresses *dataloader.Loader[string, dto.CEPOutput]
+	weather   *dataloader.Loader[weatherKey, dto.WeatherOutput]
+	forecasts *dataloader.Loader[forecastKey, []dto.ForecastDay]
+}
+
+type graphQLLoadersKey struct{}
+
+// newGraphQLSchema - schema com os resolvers ligados aos usecases do handler
+func newGraphQLSchema(wh *Handler) *graphqlgo.Schema {
+	return graphqlgo.MustParseSchema(
+		graphQLSchemaSDL,
+		&graphQLQuery{wh: wh},
+		graphqlgo.UseFieldResolvers(),
+		graphqlgo.MaxDepth(graphQLMaxDepth),
+	)
+}
+
+// newGraphQLLoaders - dataloaders da requisição; as fontes não têm consulta em lote, então cada lote
+// faz as consultas das chaves distintas em paralelo, até graphQLMaxConcurrency por vez
+func (wh *Handler) newGraphQLLoaders(ctx context.Context, lang i18n.Lang) *graphQLLoaders {
+	return &graphQLLoaders{
+		lang: lang,
+		addresses: dataloader.New(ctx, "address", dataloader.Concurrent(
+			func(ctx context.Context, CEP string) (dto.CEPOutput, error) {
+				return wh.GetLatLonByCEP.Execute(ctx, CEP)
+			}, graphQLMaxConcurrency,
+		), graphQLBatchWait, graphQLMaxBatch),
+		weather: dataloader.New(ctx, "weather", dataloader.Concurrent(
+			func(ctx context.Context, key weatherKey) (dto.WeatherOutput, error) {
+				query, _ := url.ParseQuery(key.query)
+				return wh.GetWeatherByLatLon.Execute(ctx, dto.WeatherByCoordinatesInput{
+					Latitude:  key.latitude,
+					Longitude: key.longitude,
+					Query:     query,
+					Lang:      string(lang),
+				})
+			}, graphQLMaxConcurrency,
+		), graphQLBatchWait, graphQLMaxBatch),
+		forecasts: dataloader.New(ctx, "forecast", dataloader.Concurrent(
+			func(ctx context.Context, key forecastKey) ([]dto.ForecastDay, error) {
+				return wh.GetForecast.Execute(ctx, dto.ForecastInput{
+					Latitude:  key.latitude,
+					Longitude: key.longitude,
+					Days:      key.days,
+					Lang:      string(lang),
+				})
+			}, graphQLMaxConcurrency,
+		), graphQLBatchWait, graphQLMaxBatch),
+	}
+}
+
+// loadersFrom - estado da requisição GraphQL guardado no contexto pelo handler
+func loadersFrom(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey{}).(*graphQLLoaders)
+}
+
+// graphQLError - erro de resolver com o código estável e o trace ID em extensions, como no problem+json
+type graphQLError struct {
+	err     error
+	message string
+	traceID string
+}
+
+// newGraphQLError - converte o erro para a resposta GraphQL, com o código no span do resolver
+// (error.type); a falha já foi marcada no span onde ocorreu
+func newGraphQLError(ctx context.Context, err error, message string) *graphQLError {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(semconv.ErrorTypeKey.String(string(apperror.CodeOf(err))))
+
+	return &graphQLError{err: err, message: message, traceID: traceIDOf(span.SpanContext())}
+}
+
+func (e *graphQLError) Error() string {
+	return e.message
+}
+
+func (e *graphQLError) Unwrap() error {
+	return e.err
+}
+
+// Extensions - campos adicionais do erro na resposta GraphQL
+func (e *graphQLError) Extensions() map[string]any {
+	extensions := map[string]any{"code": string(apperror.CodeOf(e.err))}
+	if e.traceID != "" {
+		extensions["trace_id"] = e.traceID
+	}
+
+	return extensions
+}
+
+// PostGraphQL - gateway GraphQL sobre o CEP, o clima, a previsão e a astronomia (POST /graphql).
+// Erros de resolver vão em errors com o code do problem+json; a resposta é 200 quando o corpo é válido
+// e o custo da consulta (graphQLQueryCost) não passa de graphQLMaxCost.
+func (wh *Handler) PostGraphQL(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx := r.Context()
+	ctx, spanQuery := tracer.Start(ctx, "graphql_query")
+	defer spanQuery.End()
+
+	request := GraphQLRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, graphQLMaxBody)).Decode(&request); err != nil || request.Query == "" {
+		if err == nil {
+			err = apperror.New(apperror.CodeInvalidRequest, "consulta GraphQL vazia")
+		}
+		spanQuery.RecordError(err)
+		spanQuery.SetStatus(codes.Error, "error on decode body")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "corpo da requisição inválido", err), i18n.ErrDecodeGraphQL)
+		return
+	}
+
+	spanQuery.SetAttributes(attribute.String("graphql.operation.name", request.OperationName))
+
+	// aliases multiplicam as consultas aos provedores: o custo é conferido antes da execução
+	cost, err := graphQLQueryCost(request.Query)
+	if err != nil && len(wh.graphQLSchema.Validate(request.Query)) > 0 {
+		// consulta inválida: a execução só devolve os erros da própria biblioteca, sem resolvers
+		err = nil
+	} else if err == nil && cost > graphQLMaxCost {
+		err = fmt.Errorf("consulta GraphQL com custo %d, acima de %d", cost, graphQLMaxCost)
+	}
+	if err != nil {
+		spanQuery.RecordError(err, trace.WithAttributes(attribute.Int("graphql.cost", cost)))
+		spanQuery.SetStatus(codes.Error, "error on query cost")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "consulta GraphQL recusada", err), i18n.ErrGraphQLCost)
+		return
+	}
+	spanQuery.SetAttributes(attribute.Int("graphql.cost", cost))
+	ctx = context.WithValue(ctx, graphQLLoadersKey{}, wh.newGraphQLLoaders(ctx, lang))
+
+	response := wh.graphQLSchema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+	if len(response.Errors) > 0 {
+		spanQuery.AddEvent("query errors", trace.WithAttributes(attribute.Int("errors", len(response.Errors))))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		spanQuery.RecordError(err)
+		spanQuery.SetStatus(codes.Error, "error on encode response")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInternal, "falha ao montar resposta", err), i18n.ErrEncodeResponse)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(payload)
+	spanQuery.AddEvent("response success")
+}
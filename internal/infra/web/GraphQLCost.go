@@ -0,0 +1,383 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// graphQLUpstreamFields - campos que consultam um provedor externo ou calculam a astronomia; cada
+// ocorrência na consulta, alias ou fragmento expandido, custa graphQLUpstreamCost
+var graphQLUpstreamFields = map[string]bool{
+	"address":   true,
+	"weather":   true,
+	"forecast":  true,
+	"astronomy": true,
+}
+
+// graphQLUpstreamCost - custo de um campo de graphQLUpstreamFields; os demais campos custam 1
+const graphQLUpstreamCost = 10
+
+// errGraphQLSyntax - consulta que a análise de custo não conseguiu ler
+var errGraphQLSyntax = errors.New("consulta GraphQL inválida")
+
+// graphQLSelection - campo, fragmento inline ou spread de uma seleção
+type graphQLSelection struct {
+	name     string
+	spread   string
+	children []graphQLSelection
+}
+
+// graphQLDocument - operações e fragmentos da consulta, apenas com o que importa para o custo
+type graphQLDocument struct {
+	operations [][]graphQLSelection
+	fragments  map[string][]graphQLSelection
+}
+
+// graphQLQueryCost - maior custo entre as operações do documento: a soma dos campos, com os
+// fragmentos expandidos em cada spread. Argumentos, variáveis e diretivas não contam.
+func graphQLQueryCost(query string) (int, error) {
+	tokens, err := graphQLTokens(query)
+	if err != nil {
+		return 0, err
+	}
+
+	parser := &graphQLParser{tokens: tokens}
+	document, err := parser.document()
+	if err != nil {
+		return 0, err
+	}
+
+	cost := 0
+	for _, operation := range document.operations {
+		operationCost, err := document.cost(operation, map[string]bool{})
+		if err != nil {
+			return 0, err
+		}
+		cost = max(cost, operationCost)
+	}
+
+	return cost, nil
+}
+
+// cost - custo da seleção; visiting guarda os fragmentos em expansão, para recusar ciclos
+func (d graphQLDocument) cost(selections []graphQLSelection, visiting map[string]bool) (int, error) {
+	total := 0
+	for _, selection := range selections {
+		if selection.spread != "" {
+			fragment, ok := d.fragments[selection.spread]
+			if !ok || visiting[selection.spread] {
+				return 0, fmt.Errorf("%w: fragmento %s ausente ou recursivo", errGraphQLSyntax, selection.spread)
+			}
+			visiting[selection.spread] = true
+			fragmentCost, err := d.cost(fragment, visiting)
+			delete(visiting, selection.spread)
+			if err != nil {
+				return 0, err
+			}
+			total += fragmentCost
+			continue
+		}
+
+		if graphQLUpstreamFields[selection.name] {
+			total += graphQLUpstreamCost
+		} else if selection.name != "" {
+			total++
+		}
+		childrenCost, err := d.cost(selection.children, visiting)
+		if err != nil {
+			return 0, err
+		}
+		total += childrenCost
+
+		// interrompe cedo documentos com fragmentos aninhados que crescem exponencialmente
+		if total > graphQLMaxCost*graphQLUpstreamCost {
+			return total, nil
+		}
+	}
+
+	return total, nil
+}
+
+// graphQLTokens - tokens da consulta, sem espaços, vírgulas e comentários; strings viram um único
+// token, para que chaves e parênteses dentro delas não contem
+func graphQLTokens(query string) ([]string, error) {
+	tokens := []string{}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case strings.HasPrefix(query[i:], "\uFEFF"):
+			i += len("\uFEFF")
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], `"""`):
+			end := i + 3
+			for {
+				next := strings.Index(query[end:], `"""`)
+				if next < 0 {
+					return nil, fmt.Errorf("%w: string sem fim", errGraphQLSyntax)
+				}
+				end += next
+				if query[end-1] != '\\' {
+					break
+				}
+				end += 3
+			}
+			tokens = append(tokens, `""`)
+			i = end + 3
+		case c == '"':
+			end := i + 1
+			for end < len(query) && query[end] != '"' {
+				if query[end] == '\\' {
+					end++
+				} else if query[end] == '\n' {
+					return nil, fmt.Errorf("%w: string sem fim", errGraphQLSyntax)
+				}
+				end++
+			}
+			if end >= len(query) {
+				return nil, fmt.Errorf("%w: string sem fim", errGraphQLSyntax)
+			}
+			tokens = append(tokens, `""`)
+			i = end + 1
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case strings.ContainsRune("{}()[]:=@$!|&", rune(c)):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '_' || c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := i + 1
+			for end < len(query) {
+				d := query[end]
+				if d != '_' && d != '.' && d != '+' && d != '-' && !(d >= '0' && d <= '9') && !(d >= 'a' && d <= 'z') && !(d >= 'A' && d <= 'Z') {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, query[i:end])
+			i = end
+		default:
+			r, _ := utf8.DecodeRuneInString(query[i:])
+			return nil, fmt.Errorf("%w: caractere %q", errGraphQLSyntax, r)
+		}
+	}
+
+	return tokens, nil
+}
+
+// graphQLParser - leitura recursiva dos tokens de graphQLTokens
+type graphQLParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *graphQLParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *graphQLParser) next() string {
+	token := p.peek()
+	p.pos++
+
+	return token
+}
+
+func (p *graphQLParser) expect(token string) error {
+	if got := p.next(); got != token {
+		return fmt.Errorf("%w: esperado %q, encontrado %q", errGraphQLSyntax, token, got)
+	}
+
+	return nil
+}
+
+// name - nome de campo, tipo ou fragmento
+func (p *graphQLParser) name() (string, error) {
+	token := p.next()
+	if token == "" || !(token[0] == '_' || token[0] >= 'a' && token[0] <= 'z' || token[0] >= 'A' && token[0] <= 'Z') {
+		return "", fmt.Errorf("%w: nome esperado, encontrado %q", errGraphQLSyntax, token)
+	}
+
+	return token, nil
+}
+
+// document - definições de operação e de fragmento
+func (p *graphQLParser) document() (graphQLDocument, error) {
+	document := graphQLDocument{fragments: map[string][]graphQLSelection{}}
+
+	for p.pos < len(p.tokens) {
+		switch p.peek() {
+		case "{":
+			selections, err := p.selectionSet()
+			if err != nil {
+				return document, err
+			}
+			document.operations = append(document.operations, selections)
+		case "query", "mutation", "subscription":
+			p.next()
+			if p.peek() != "{" && p.peek() != "(" && p.peek() != "@" {
+				if _, err := p.name(); err != nil {
+					return document, err
+				}
+			}
+			if err := p.skipHeader(); err != nil {
+				return document, err
+			}
+			selections, err := p.selectionSet()
+			if err != nil {
+				return document, err
+			}
+			document.operations = append(document.operations, selections)
+		case "fragment":
+			p.next()
+			name, err := p.name()
+			if err != nil {
+				return document, err
+			}
+			if err := p.expect("on"); err != nil {
+				return document, err
+			}
+			if _, err := p.name(); err != nil {
+				return document, err
+			}
+			if err := p.skipHeader(); err != nil {
+				return document, err
+			}
+			selections, err := p.selectionSet()
+			if err != nil {
+				return document, err
+			}
+			document.fragments[name] = selections
+		default:
+			return document, fmt.Errorf("%w: definição inesperada %q", errGraphQLSyntax, p.peek())
+		}
+	}
+
+	return document, nil
+}
+
+// skipHeader - variáveis e diretivas antes da seleção
+func (p *graphQLParser) skipHeader() error {
+	if p.peek() == "(" {
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return err
+		}
+	}
+
+	return p.skipDirectives()
+}
+
+// skipDirectives - @diretiva(argumentos) repetidas
+func (p *graphQLParser) skipDirectives() error {
+	for p.peek() == "@" {
+		p.next()
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if p.peek() == "(" {
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipBalanced - pula do open atual até o close correspondente
+func (p *graphQLParser) skipBalanced(open string, close string) error {
+	depth := 0
+	for {
+		switch p.next() {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case "":
+			return fmt.Errorf("%w: %q sem %q", errGraphQLSyntax, open, close)
+		}
+	}
+}
+
+// selectionSet - { seleções }
+func (p *graphQLParser) selectionSet() ([]graphQLSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	selections := []graphQLSelection{}
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return nil, fmt.Errorf("%w: seleção sem fim", errGraphQLSyntax)
+		}
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	p.next()
+
+	return selections, nil
+}
+
+// selection - campo com alias, argumentos e subseleção, spread ou fragmento inline
+func (p *graphQLParser) selection() (graphQLSelection, error) {
+	if p.peek() == "..." {
+		p.next()
+		if p.peek() != "on" && p.peek() != "@" && p.peek() != "{" {
+			name, err := p.name()
+			if err != nil {
+				return graphQLSelection{}, err
+			}
+			return graphQLSelection{spread: name}, p.skipDirectives()
+		}
+		if p.peek() == "on" {
+			p.next()
+			if _, err := p.name(); err != nil {
+				return graphQLSelection{}, err
+			}
+		}
+		if err := p.skipDirectives(); err != nil {
+			return graphQLSelection{}, err
+		}
+		children, err := p.selectionSet()
+		return graphQLSelection{children: children}, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return graphQLSelection{}, err
+	}
+	if p.peek() == ":" {
+		p.next()
+		if name, err = p.name(); err != nil {
+			return graphQLSelection{}, err
+		}
+	}
+	if err := p.skipHeader(); err != nil {
+		return graphQLSelection{}, err
+	}
+
+	selection := graphQLSelection{name: name}
+	if p.peek() == "{" {
+		if selection.children, err = p.selectionSet(); err != nil {
+			return graphQLSelection{}, err
+		}
+	}
+
+	return selection, nil
+}
@@ -0,0 +1,404 @@
+package web
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"github.com/nagahshi/pos_go_weather_otel/internal/units"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// graphQLMaxForecastDays - maior previsão aceita pela WeatherAPI
+const graphQLMaxForecastDays = 14
+
+// graphQLQuery - resolvers da raiz do schema
+type graphQLQuery struct {
+	wh *Handler
+}
+
+// graphQLUnitsInput - UnitsInput do schema
+type graphQLUnitsInput struct {
+	Preset      *string
+	Temperature *string
+	Precision   *int32
+	Rounding    *string
+}
+
+// argumentos com valor padrão no schema chegam sempre preenchidos
+type graphQLWeatherArgs struct {
+	Units *graphQLUnitsInput
+	Aqi   bool
+}
+
+type graphQLForecastArgs struct {
+	Days int32
+}
+
+type graphQLAstronomyArgs struct {
+	Date *string
+	Tz   string
+}
+
+// graphQLAddress - Address do schema; source guarda o retorno da BrasilAPI para os campos aninhados
+type graphQLAddress struct {
+	CEP          string
+	Street       string
+	Neighborhood string
+	City         string
+	State        string
+	Latitude     *float64
+	Longitude    *float64
+
+	wh     *Handler
+	source dto.CEPOutput
+}
+
+// graphQLWeather - Weather do schema, com os nomes e tipos escalares do GraphQL
+type graphQLWeather struct {
+	City                string
+	TempC               float64
+	TempF               float64
+	TempK               float64
+	TempR               *float64
+	Humidity            float64
+	WindSpeed           float64
+	Pressure            float64
+	Condition           *string
+	ObservedAt          *string
+	FeelsLike           float64
+	HeatIndex           float64
+	WindChill           float64
+	DewPoint            *float64
+	Humidex             *float64
+	Beaufort            int32
+	BeaufortDescription string
+	AirQuality          *graphQLAirQuality
+	Units               *dto.Units
+	Alerts              []dto.Alert
+}
+
+type graphQLAirQuality struct {
+	PM25         float64
+	PM10         float64
+	O3           float64
+	NO2          float64
+	USEPAIndex   int32
+	GBDefraIndex int32
+	Category     string
+	Provider     string
+}
+
+type graphQLAstronomy struct {
+	Date             string
+	Timezone         string
+	Sunrise          *string
+	Sunset           *string
+	SolarNoon        string
+	DayLength        string
+	DayLengthSeconds int32
+	MoonPhase        string
+	MoonIllumination float64
+	MoonAgeDays      float64
+}
+
+// optional - nil para o valor vazio, que o schema expõe como null
+func optional[T comparable](value T) *T {
+	var zero T
+	if value == zero {
+		return nil
+	}
+
+	return &value
+}
+
+// problemTitle - título do código do erro no idioma negociado, usado como mensagem dos erros de resolver
+func problemTitle(lang i18n.Lang, err error) string {
+	return i18n.T(lang, i18n.PrefixProblem+string(apperror.CodeOf(err)))
+}
+
+// Address - endereço do CEP; CEPs repetidos na consulta são buscados uma única vez
+func (q *graphQLQuery) Address(ctx context.Context, args struct{ CEP string }) (*graphQLAddress, error) {
+	loaders := loadersFrom(ctx)
+
+	ctx, spanResolve := tracer.Start(ctx, "resolve_address", trace.WithAttributes(attribute.String("zipcode", args.CEP)))
+	defer spanResolve.End()
+
+	CEP, ok := sanitizeCEP(args.CEP)
+	if !ok {
+		spanResolve.RecordError(apperror.ErrInvalidCEP)
+		spanResolve.SetStatus(codes.Error, "error on check validate zipcode")
+		return nil, newGraphQLError(ctx, apperror.ErrInvalidCEP, i18n.T(loaders.lang, i18n.ErrInvalidZipcode))
+	}
+
+	output, err := loaders.addresses.Load(ctx, CEP)
+	if err != nil {
+		spanResolve.RecordError(err)
+		spanResolve.SetStatus(codes.Error, "error on search zipcode")
+		return nil, newGraphQLError(ctx, err, problemTitle(loaders.lang, err))
+	}
+
+	address := &graphQLAddress{
+		CEP:          CEP,
+		Street:       output.Logradouro,
+		Neighborhood: output.Bairro,
+		City:         output.CIDADE,
+		State:        output.UF,
+		wh:           q.wh,
+		source:       output,
+	}
+	if latitude, err := strconv.ParseFloat(output.Latitude, 64); err == nil {
+		address.Latitude = &latitude
+	}
+	if longitude, err := strconv.ParseFloat(output.Longitude, 64); err == nil {
+		address.Longitude = &longitude
+	}
+
+	spanResolve.AddEvent("resolve success", trace.WithAttributes(attribute.String("city", address.City)))
+
+	return address, nil
+}
+
+// Weather - clima atual nas coordenadas
+func (q *graphQLQuery) Weather(ctx context.Context, args struct {
+	Lat   string
+	Lon   string
+	Units *graphQLUnitsInput
+	Aqi   bool
+}) (*graphQLWeather, error) {
+	ctx, spanResolve := tracer.Start(ctx, "resolve_weather")
+	defer spanResolve.End()
+
+	return resolveWeather(ctx, strings.TrimSpace(args.Lat), strings.TrimSpace(args.Lon), "", graphQLWeatherArgs{Units: args.Units, Aqi: args.Aqi})
+}
+
+// Forecast - previsão diária nas coordenadas
+func (q *graphQLQuery) Forecast(ctx context.Context, args struct {
+	Lat  string
+	Lon  string
+	Days int32
+}) (*[]dto.ForecastDay, error) {
+	ctx, spanResolve := tracer.Start(ctx, "resolve_forecast")
+	defer spanResolve.End()
+
+	return resolveForecast(ctx, strings.TrimSpace(args.Lat), strings.TrimSpace(args.Lon), graphQLForecastArgs{Days: args.Days})
+}
+
+// Weather - clima atual nas coordenadas do CEP, com a cidade do CEP
+func (a *graphQLAddress) Weather(ctx context.Context, args graphQLWeatherArgs) (*graphQLWeather, error) {
+	ctx, spanResolve := tracer.Start(ctx, "resolve_address_weather", trace.WithAttributes(attribute.String("zipcode", a.CEP)))
+	defer spanResolve.End()
+
+	if a.Latitude == nil || a.Longitude == nil {
+		spanResolve.AddEvent("zipcode without coordinates")
+		return nil, newGraphQLError(ctx, apperror.ErrLocationNotFound, i18n.T(loadersFrom(ctx).lang, i18n.ErrLocationNotFound))
+	}
+
+	return resolveWeather(ctx, a.source.Latitude, a.source.Longitude, a.City, args)
+}
+
+// Forecast - previsão diária nas coordenadas do CEP
+func (a *graphQLAddress) Forecast(ctx context.Context, args graphQLForecastArgs) (*[]dto.ForecastDay, error) {
+	ctx, spanResolve := tracer.Start(ctx, "resolve_address_forecast", trace.WithAttributes(attribute.String("zipcode", a.CEP)))
+	defer spanResolve.End()
+
+	if a.Latitude == nil || a.Longitude == nil {
+		spanResolve.AddEvent("zipcode without coordinates")
+		return nil, newGraphQLError(ctx, apperror.ErrLocationNotFound, i18n.T(loadersFrom(ctx).lang, i18n.ErrLocationNotFound))
+	}
+
+	return resolveForecast(ctx, a.source.Latitude, a.source.Longitude, args)
+}
+
+// Astronomy - dados astronômicos calculados localmente pelas coordenadas do CEP
+func (a *graphQLAddress) Astronomy(ctx context.Context, args graphQLAstronomyArgs) (*graphQLAstronomy, error) {
+	lang := loadersFrom(ctx).lang
+
+	ctx, spanResolve := tracer.Start(ctx, "resolve_address_astronomy", trace.WithAttributes(attribute.String("zipcode", a.CEP)))
+	defer spanResolve.End()
+
+	timezone := args.Tz
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		spanResolve.RecordError(err)
+		spanResolve.SetStatus(codes.Error, "error on load timezone")
+		return nil, newGraphQLError(ctx, apperror.Wrap(apperror.CodeInvalidRequest, "fuso horário inválido", err), i18n.T(lang, i18n.ErrInvalidTimezone))
+	}
+
+	date := time.Now().In(location)
+	if args.Date != nil && *args.Date != "" {
+		date, err = time.ParseInLocation(time.DateOnly, *args.Date, location)
+		if err != nil {
+			spanResolve.RecordError(err)
+			spanResolve.SetStatus(codes.Error, "error on parse date")
+			return nil, newGraphQLError(ctx, apperror.Wrap(apperror.CodeInvalidRequest, "data inválida", err), i18n.T(lang, i18n.ErrInvalidDate))
+		}
+	}
+
+	output, err := a.wh.GetAstronomy.Execute(ctx, dto.AstronomyInput{
+		Latitude:  a.source.Latitude,
+		Longitude: a.source.Longitude,
+		Date:      date,
+	})
+	if err != nil {
+		spanResolve.RecordError(err)
+		spanResolve.SetStatus(codes.Error, "error on calculate astronomy")
+		return nil, newGraphQLError(ctx, err, i18n.T(lang, i18n.ErrAstronomyCalculate))
+	}
+
+	spanResolve.AddEvent("resolve success", trace.WithAttributes(attribute.String("moon_phase", output.MoonPhase)))
+
+	return &graphQLAstronomy{
+		Date:             output.Date,
+		Timezone:         output.Timezone,
+		Sunrise:          optional(output.Sunrise),
+		Sunset:           optional(output.Sunset),
+		SolarNoon:        output.SolarNoon,
+		DayLength:        output.DayLength,
+		DayLengthSeconds: int32(output.DayLengthSeconds),
+		MoonPhase:        i18n.T(lang, i18n.PrefixMoonPhase+strings.ReplaceAll(strings.ToLower(output.MoonPhase), " ", "_")),
+		MoonIllumination: output.MoonIllumination,
+		MoonAgeDays:      output.MoonAgeDays,
+	}, nil
+}
+
+// resolveWeather - valida as opções e carrega o clima pelo dataloader; city substitui a cidade do provedor
+func resolveWeather(ctx context.Context, latitude string, longitude string, city string, args graphQLWeatherArgs) (*graphQLWeather, error) {
+	loaders := loadersFrom(ctx)
+	span := trace.SpanFromContext(ctx)
+
+	if err := validateCoordinates(latitude, longitude); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error on validate coordinates")
+		return nil, newGraphQLError(ctx, apperror.Wrap(apperror.CodeInvalidRequest, "localização inválida", err), i18n.T(loaders.lang, i18n.ErrInvalidCoordinates))
+	}
+
+	var preset, temperature, precision, rounding string
+	if args.Units != nil {
+		if args.Units.Preset != nil {
+			preset = *args.Units.Preset
+		}
+		if args.Units.Temperature != nil {
+			temperature = *args.Units.Temperature
+		}
+		if args.Units.Precision != nil {
+			precision = strconv.Itoa(int(*args.Units.Precision))
+		}
+		if args.Units.Rounding != nil {
+			rounding = *args.Units.Rounding
+		}
+	}
+	unitOptions, err := units.Parse(preset, temperature, precision, rounding)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error on parse units")
+		return nil, newGraphQLError(ctx, apperror.Wrap(apperror.CodeInvalidRequest, "opções de unidade inválidas", err), i18n.T(loaders.lang, i18n.ErrInvalidUnits))
+	}
+	query := unitOptions.Query()
+	if args.Aqi {
+		query.Set("aqi", "yes")
+	}
+
+	span.AddEvent(
+		"load weather",
+		trace.WithAttributes(attribute.String("latitude", latitude), attribute.String("longitude", longitude), attribute.String("query", query.Encode())),
+	)
+	output, err := loaders.weather.Load(ctx, weatherKey{latitude: latitude, longitude: longitude, query: query.Encode()})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error on load weather")
+		return nil, newGraphQLError(ctx, err, weatherByCEPDetail(loaders.lang, err))
+	}
+	if city != "" {
+		output.City = city
+	}
+
+	span.AddEvent("resolve success", trace.WithAttributes(attribute.Float64("temp_C", output.C)))
+
+	return newGraphQLWeather(loaders.lang, output), nil
+}
+
+// resolveForecast - valida os dias e carrega a previsão pelo dataloader
+func resolveForecast(ctx context.Context, latitude string, longitude string, args graphQLForecastArgs) (*[]dto.ForecastDay, error) {
+	loaders := loadersFrom(ctx)
+	span := trace.SpanFromContext(ctx)
+
+	if err := validateCoordinates(latitude, longitude); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error on validate coordinates")
+		return nil, newGraphQLError(ctx, apperror.Wrap(apperror.CodeInvalidRequest, "localização inválida", err), i18n.T(loaders.lang, i18n.ErrInvalidCoordinates))
+	}
+
+	days := int(args.Days)
+	if days < 1 || days > graphQLMaxForecastDays {
+		span.AddEvent("invalid forecast days", trace.WithAttributes(attribute.Int("days", days)))
+		return nil, newGraphQLError(ctx, apperror.New(apperror.CodeInvalidRequest, "quantidade de dias inválida"), i18n.T(loaders.lang, i18n.ErrInvalidForecastDays))
+	}
+
+	span.AddEvent(
+		"load forecast",
+		trace.WithAttributes(attribute.String("latitude", latitude), attribute.String("longitude", longitude), attribute.Int("days", days)),
+	)
+	output, err := loaders.forecasts.Load(ctx, forecastKey{latitude: latitude, longitude: longitude, days: days})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error on load forecast")
+		return nil, newGraphQLError(ctx, err, problemTitle(loaders.lang, err))
+	}
+
+	span.AddEvent("resolve success", trace.WithAttributes(attribute.Int("days", len(output))))
+
+	return &output, nil
+}
+
+// newGraphQLWeather - converte a leitura para o tipo Weather do schema, com os alertas no idioma negociado
+func newGraphQLWeather(lang i18n.Lang, output dto.WeatherOutput) *graphQLWeather {
+	weather := &graphQLWeather{
+		City:                output.City,
+		TempC:               output.C,
+		TempF:               output.F,
+		TempK:               output.K,
+		TempR:               optional(output.R),
+		Humidity:            output.Humidity,
+		WindSpeed:           output.WindSpeed,
+		Pressure:            output.Pressure,
+		Condition:           optional(output.Condition),
+		ObservedAt:          optional(output.ObservedAt),
+		FeelsLike:           output.FeelsLike,
+		HeatIndex:           output.HeatIndex,
+		WindChill:           output.WindChill,
+		DewPoint:            output.DewPoint,
+		Humidex:             output.Humidex,
+		Beaufort:            int32(output.Beaufort),
+		BeaufortDescription: output.BeaufortDescription,
+		Units:               output.Units,
+		Alerts:              localizedAlerts(lang, output),
+	}
+	if weather.Alerts == nil {
+		weather.Alerts = []dto.Alert{}
+	}
+
+	if output.AirQuality != nil {
+		weather.AirQuality = &graphQLAirQuality{
+			PM25:         output.AirQuality.PM25,
+			PM10:         output.AirQuality.PM10,
+			O3:           output.AirQuality.O3,
+			NO2:          output.AirQuality.NO2,
+			USEPAIndex:   int32(output.AirQuality.USEPAIndex),
+			GBDefraIndex: int32(output.AirQuality.GBDefraIndex),
+			Category:     output.AirQuality.Category,
+			Provider:     output.AirQuality.Provider,
+		}
+	}
+
+	return weather
+}
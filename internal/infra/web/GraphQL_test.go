@@ -0,0 +1,254 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/usecase"
+)
+
+func TestGraphQLQueryCost(t *testing.T) {
+	aliases := &strings.Builder{}
+	aliases.WriteString("{")
+	for i := range 21 {
+		fmt.Fprintf(aliases, ` a%d: address(cep: "87033080") { city }`, i)
+	}
+	aliases.WriteString(" }")
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		err   bool
+	}{
+		{name: "campo simples", query: `{ address(cep: "87033080") { city state } }`, want: 12},
+		{name: "operação nomeada com variáveis", query: `query Q($cep: String!) { address(cep: $cep) { city } }`, want: 11},
+		{
+			name:  "campos aninhados que consultam provedores",
+			query: `{ address(cep: "87033080") { weather(units: { preset: "imperial" }) { tempF } forecast(days: 3) { date } astronomy { sunrise } } }`,
+			want:  43,
+		},
+		{
+			name:  "aliases contam cada ocorrência",
+			query: `{ a: weather(lat: "-23.4", lon: "-51.9") { tempC } b: weather(lat: "-23.4", lon: "-51.9") { tempC } }`,
+			want:  22,
+		},
+		{name: "21 aliases de address", query: aliases.String(), want: 231},
+		{
+			name:  "fragmentos expandidos em cada spread",
+			query: `query { a: address(cep: "1") { ...F } b: address(cep: "2") { ...F } } fragment F on Address { city weather { tempC } }`,
+			want:  44,
+		},
+		{name: "fragmento inline", query: `{ address(cep: "1") { ... on Address { city } ... @include(if: true) { state } } }`, want: 12},
+		{
+			name:  "strings, comentários e vírgulas não contam",
+			query: "# { weather }\n{ address(cep: \"{ weather }\"), { city, } }",
+			want:  11,
+		},
+		{name: "string em bloco", query: `{ address(cep: """ { forecast } """) { city } }`, want: 11},
+		{name: "maior custo entre as operações", query: `query A { address(cep: "1") { city } } query B { address(cep: "1") { city weather { tempC } } }`, want: 22},
+		{name: "chave sem fim", query: `{ address(cep: "1") { city }`, err: true},
+		{name: "string sem fim", query: `{ address(cep: "1) { city } }`, err: true},
+		{name: "fragmento ausente", query: `{ address(cep: "1") { ...F } }`, err: true},
+		{name: "fragmento recursivo", query: `{ address(cep: "1") { ...F } } fragment F on Address { ...F }`, err: true},
+		{name: "definição inesperada", query: `type Query { a: Int }`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := graphQLQueryCost(test.query)
+			if test.err {
+				if !errors.Is(err, errGraphQLSyntax) {
+					t.Errorf("err = %v, esperado errGraphQLSyntax", err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("custo = %d, %v, esperado %d", got, err, test.want)
+			}
+		})
+	}
+}
+
+// newCountingBrasilAPIStub - BrasilAPI que conta as consultas de cada CEP; 00000000 não existe
+func newCountingBrasilAPIStub(t *testing.T) (*httptest.Server, func(CEP string) int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		CEP := strings.TrimPrefix(r.URL.Path, "/api/cep/v2/")
+		mu.Lock()
+		calls[CEP]++
+		mu.Unlock()
+
+		if CEP == "00000000" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"cep":"` + CEP + `","city":"Maringá","state":"PR","location":{"coordinates":{"latitude":"-23.4","longitude":"-51.9"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(CEP string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[CEP]
+	}
+}
+
+// graphQLResult - resposta do gateway com os erros de resolver
+type graphQLResult struct {
+	Data   map[string]map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Path       []any          `json:"path"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// postGraphQL - POST /graphql no handler, com o status e o corpo da resposta
+func postGraphQL(t *testing.T, handler *Handler, query string) (int, []byte) {
+	t.Helper()
+
+	body, _ := json.Marshal(GraphQLRequest{Query: query})
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	recorder := httptest.NewRecorder()
+	handler.PostGraphQL(recorder, request)
+
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+func newGraphQLTestHandler() *Handler {
+	handler := &Handler{GetLatLonByCEP: *usecase.NewGetLatLonByCEPUseCase()}
+	handler.graphQLSchema = newGraphQLSchema(handler)
+
+	return handler
+}
+
+func TestPostGraphQLDeduplicatesAddresses(t *testing.T) {
+	brasilAPI, calls := newCountingBrasilAPIStub(t)
+	t.Setenv("BRASILAPI_URL", brasilAPI.URL)
+
+	status, body := postGraphQL(t, newGraphQLTestHandler(), `{
+		a: address(cep: "87033-080") { city }
+		b: address(cep: "87033080") { state }
+		c: address(cep: "01001000") { city }
+	}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200: %s", status, body)
+	}
+
+	result := graphQLResult{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("erros inesperados: %s", body)
+	}
+	if result.Data["a"]["city"] != "Maringá" || result.Data["b"]["state"] != "PR" || result.Data["c"]["city"] != "Maringá" {
+		t.Errorf("data = %v", result.Data)
+	}
+	if got := calls("87033080"); got != 1 {
+		t.Errorf("87033080 consultado %d vezes, esperado 1", got)
+	}
+	if got := calls("01001000"); got != 1 {
+		t.Errorf("01001000 consultado %d vezes, esperado 1", got)
+	}
+}
+
+func TestPostGraphQLErrorFanOut(t *testing.T) {
+	brasilAPI, calls := newCountingBrasilAPIStub(t)
+	t.Setenv("BRASILAPI_URL", brasilAPI.URL)
+
+	status, body := postGraphQL(t, newGraphQLTestHandler(), `{
+		a: address(cep: "00000000") { city }
+		b: address(cep: "00000000") { city }
+		c: address(cep: "87033080") { city }
+		d: address(cep: "123") { city }
+	}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200: %s", status, body)
+	}
+
+	result := graphQLResult{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	codes := map[string]any{}
+	for _, graphQLErr := range result.Errors {
+		if len(graphQLErr.Path) == 0 {
+			t.Fatalf("erro sem path: %s", body)
+		}
+		codes[graphQLErr.Path[0].(string)] = graphQLErr.Extensions["code"]
+	}
+	want := map[string]any{"a": "CEP_NOT_FOUND", "b": "CEP_NOT_FOUND", "d": "INVALID_CEP"}
+	if len(codes) != len(want) {
+		t.Errorf("erros = %v, esperado %v", codes, want)
+	}
+	for alias, code := range want {
+		if codes[alias] != code {
+			t.Errorf("erro de %s = %v, esperado %v", alias, codes[alias], code)
+		}
+	}
+	if result.Data["c"]["city"] != "Maringá" {
+		t.Errorf("c = %v, esperado o endereço apesar dos erros dos demais", result.Data["c"])
+	}
+	if got := calls("00000000"); got != 1 {
+		t.Errorf("00000000 consultado %d vezes, esperado 1", got)
+	}
+	if got := calls("123"); got != 0 {
+		t.Errorf("CEP inválido consultado %d vezes, esperado 0", got)
+	}
+}
+
+func TestPostGraphQLRejectsCost(t *testing.T) {
+	brasilAPI, calls := newCountingBrasilAPIStub(t)
+	t.Setenv("BRASILAPI_URL", brasilAPI.URL)
+
+	query := &strings.Builder{}
+	query.WriteString("{")
+	for i := range 50 {
+		fmt.Fprintf(query, ` a%d: address(cep: "87033080") { city }`, i)
+	}
+	query.WriteString(" }")
+
+	status, body := postGraphQL(t, newGraphQLTestHandler(), query.String())
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, esperado 422: %s", status, body)
+	}
+
+	problem := map[string]any{}
+	if err := json.Unmarshal(body, &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem["code"] != "INVALID_REQUEST" {
+		t.Errorf("code = %v, esperado INVALID_REQUEST", problem["code"])
+	}
+	if got := calls("87033080"); got != 0 {
+		t.Errorf("BrasilAPI consultada %d vezes, esperado nenhuma", got)
+	}
+}
+
+func TestPostGraphQLInvalidQuery(t *testing.T) {
+	status, body := postGraphQL(t, newGraphQLTestHandler(), `{ address(cep: "1") { city }`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200 com os erros da biblioteca: %s", status, body)
+	}
+
+	result := graphQLResult{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) == 0 {
+		t.Errorf("consulta inválida sem erros: %s", body)
+	}
+}
@@ -2,35 +2,83 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/traceid"
-	"github.com/go-chi/transport"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/nagahshi/pos_go_weather_otel/internal/alert"
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
 	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/encoder"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"github.com/nagahshi/pos_go_weather_otel/internal/service"
+	"github.com/nagahshi/pos_go_weather_otel/internal/stream"
+	"github.com/nagahshi/pos_go_weather_otel/internal/units"
 	"github.com/nagahshi/pos_go_weather_otel/internal/usecase"
-	"github.com/valyala/fastjson"
+	"github.com/nagahshi/pos_go_weather_otel/internal/webhook"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer - escopo de instrumentação dos spans dos handlers HTTP, GraphQL e WebSocket
+var tracer = otel.Tracer("github.com/nagahshi/pos_go_weather_otel/internal/infra/web")
+
 type Handler struct {
 	GetLatLonByCEP       usecase.GetLatLonByCEP
+	GetWeatherByZipcode  usecase.GetWeatherByCEPUseCase
 	GetWeatherByLocation usecase.GetWeatherUseCase
+	GetAirQuality        usecase.GetAirQualityUseCase
+	GetAstronomy         usecase.GetAstronomyUseCase
+	WeatherStream        *stream.Hub[dto.WeatherOutput]
+	GetWeatherByLatLon   usecase.GetWeatherByCoordinatesUseCase
+	GetForecast          usecase.GetForecastUseCase
+	Webhooks             *webhook.Scheduler
+	GetHistory           usecase.GetHistoryByCEPUseCase
+
+	// WebSocketMaxSubscriptions - assinaturas simultâneas por conexão WebSocket
+	WebSocketMaxSubscriptions int
+
+	graphQLSchema *graphqlgo.Schema
 }
 
 // NewHandler - cria um novo handler com os usecases
-func NewHandler(GetLatLonByCEP usecase.GetLatLonByCEP, GetWeatherByLocation usecase.GetWeatherUseCase) *Handler {
-	return &Handler{
+func NewHandler(
+	GetLatLonByCEP usecase.GetLatLonByCEP,
+	GetWeatherByZipcode usecase.GetWeatherByCEPUseCase,
+	GetWeatherByLocation usecase.GetWeatherUseCase,
+	GetAirQuality usecase.GetAirQualityUseCase,
+	GetAstronomy usecase.GetAstronomyUseCase,
+	WeatherStream *stream.Hub[dto.WeatherOutput],
+	GetWeatherByLatLon usecase.GetWeatherByCoordinatesUseCase,
+	GetForecast usecase.GetForecastUseCase,
+	Webhooks *webhook.Scheduler,
+	GetHistory usecase.GetHistoryByCEPUseCase,
+) *Handler {
+	handler := &Handler{
 		GetLatLonByCEP:       GetLatLonByCEP,
+		GetWeatherByZipcode:  GetWeatherByZipcode,
 		GetWeatherByLocation: GetWeatherByLocation,
+		GetAirQuality:        GetAirQuality,
+		GetAstronomy:         GetAstronomy,
+		WeatherStream:        WeatherStream,
+		GetWeatherByLatLon:   GetWeatherByLatLon,
+		GetForecast:          GetForecast,
+		Webhooks:             Webhooks,
+		GetHistory:           GetHistory,
+
+		WebSocketMaxSubscriptions: DefaultWebSocketMaxSubscriptions,
 	}
+	handler.graphQLSchema = newGraphQLSchema(handler)
+
+	return handler
 }
 
 // GetLocationByCEPRequest - estrutura de entrada para busca de clima pelo CEP
@@ -44,197 +92,358 @@ type GetWeatherByLocalRequest struct {
 	Longitude string `json:"longitude"`
 }
 
-// GetLocationByCEP - busca de clima pelo CEP
-func (wh *Handler) GetLocationByCEP(w http.ResponseWriter, r *http.Request) {
+// sanitizeCEP - mantém apenas os dígitos do CEP e valida o tamanho
+func sanitizeCEP(CEP string) (string, bool) {
 	var re *regexp.Regexp = regexp.MustCompile("[0-9]+")
 
-	carrier := propagation.HeaderCarrier(r.Header)
-	ctx := r.Context()
-	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	CEP = strings.Join(re.FindAllString(CEP, -1), "")
 
-	tracer := otel.Tracer("handler-GetLocationByCEP")
-	ctx, spanValidate := tracer.Start(ctx, "validate_zipcode")
+	return CEP, len(CEP) == 8
+}
+
+// wantsAirQuality - qualidade do ar é opcional e habilitada via query string (?aqi=yes)
+func wantsAirQuality(r *http.Request) bool {
+	switch strings.ToLower(r.URL.Query().Get("aqi")) {
+	case "yes", "true", "1":
+		return true
+	}
+
+	return false
+}
+
+// unitsFromRequest - opções de unidade pela query string, com os headers X-Units, X-Unit-Preset,
+// X-Precision e X-Rounding como alternativa; a query string tem prioridade
+func unitsFromRequest(r *http.Request) (units.Options, error) {
+	value := func(query string, header string) string {
+		if v := r.URL.Query().Get(query); v != "" {
+			return v
+		}
+
+		return r.Header.Get(header)
+	}
+
+	return units.Parse(
+		value("preset", "X-Unit-Preset"),
+		value("units", "X-Units"),
+		value("precision", "X-Precision"),
+		value("rounding", "X-Rounding"),
+	)
+}
 
-	spanValidate.AddEvent("extract POST body")
+// zipcodeExtractor - obtém o CEP, ainda não sanitizado, da requisição
+type zipcodeExtractor func(r *http.Request) (string, error)
+
+// zipcodeFromBody - CEP no corpo JSON das rotas legadas (POST /cep)
+func zipcodeFromBody(r *http.Request) (string, error) {
 	data := GetLocationByCEPRequest{}
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&data)
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	return data.CEP, err
+}
+
+// zipcodeFromPath - CEP no path das rotas versionadas (GET /v1/weather/cep/{cep})
+func zipcodeFromPath(r *http.Request) (string, error) {
+	return r.PathValue("cep"), nil
+}
+
+// locationExtractor - obtém latitude e longitude da requisição
+type locationExtractor func(r *http.Request) (GetWeatherByLocalRequest, error)
+
+// locationFromBody - coordenadas no corpo JSON da rota legada (POST /weather)
+func locationFromBody(r *http.Request) (GetWeatherByLocalRequest, error) {
+	data := GetWeatherByLocalRequest{}
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	return data, err
+}
+
+// locationFromQuery - coordenadas na query string da rota versionada (GET /v1/weather/coordinates?lat=&lon=)
+func locationFromQuery(r *http.Request) (GetWeatherByLocalRequest, error) {
+	data := GetWeatherByLocalRequest{
+		Latitude:  strings.TrimSpace(r.URL.Query().Get("lat")),
+		Longitude: strings.TrimSpace(r.URL.Query().Get("lon")),
+	}
+
+	return data, validateCoordinates(data.Latitude, data.Longitude)
+}
+
+// validateCoordinates - latitude e longitude numéricas e dentro da faixa
+func validateCoordinates(latitude string, longitude string) error {
+	lat, err := strconv.ParseFloat(latitude, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude inválida: %q", latitude)
+	}
+
+	lon, err := strconv.ParseFloat(longitude, 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return fmt.Errorf("longitude inválida: %q", longitude)
+	}
+
+	return nil
+}
+
+// GetLocationByCEP - busca de clima pelo CEP informado no corpo (POST /cep)
+func (wh *Handler) GetLocationByCEP(w http.ResponseWriter, r *http.Request) {
+	wh.weatherByCEP(w, r, zipcodeFromBody)
+}
+
+// GetWeatherByCEP - busca de clima pelo CEP informado no path (GET /v1/weather/cep/{cep})
+func (wh *Handler) GetWeatherByCEP(w http.ResponseWriter, r *http.Request) {
+	wh.weatherByCEP(w, r, zipcodeFromPath)
+}
+
+// weatherByCEP - fluxo comum de busca de clima pelo CEP, independente de onde o CEP é lido
+func (wh *Handler) weatherByCEP(w http.ResponseWriter, r *http.Request, extract zipcodeExtractor) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx := r.Context()
+	ctx, spanValidate := tracer.Start(ctx, "validate_zipcode")
+
+	spanValidate.AddEvent("extract zipcode", trace.WithAttributes(attribute.String("http.method", r.Method)))
+	rawCEP, err := extract(r)
 	if err != nil {
-		spanValidate.AddEvent("error on decode body", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on decode body")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "corpo da requisição inválido", err), i18n.ErrDecodeZipcode)
 		spanValidate.End()
-		http.Error(w, "cant decode zipcode", http.StatusUnprocessableEntity)
 		return
 	}
 
-	spanValidate.AddEvent("sanitize zipcode", trace.WithAttributes(attribute.String("zipcode", data.CEP)))
-	CEP := strings.Join(re.FindAllString(data.CEP, -1), "")
-	if len(CEP) != 8 {
-		spanValidate.AddEvent("error on check validate zipcode")
+	spanValidate.AddEvent("sanitize zipcode", trace.WithAttributes(attribute.String("zipcode", rawCEP)))
+	CEP, ok := sanitizeCEP(rawCEP)
+	if !ok {
+		spanValidate.RecordError(apperror.ErrInvalidCEP)
+		spanValidate.SetStatus(codes.Error, "error on check validate zipcode")
+		writeProblem(ctx, w, r, lang, apperror.ErrInvalidCEP, i18n.ErrInvalidZipcode)
 		spanValidate.End()
-		http.Error(w, "invalid zipcode", http.StatusUnprocessableEntity)
 		return
 	}
 	spanValidate.AddEvent("sanitized zipcode", trace.WithAttributes(attribute.String("zipcode", CEP)))
-	spanValidate.End()
 
-	ctx, spanSearch := tracer.Start(ctx, "zipcode-search")
-	spanSearch.AddEvent("search location by zipcode")
-	outputCEP, err := wh.GetLatLonByCEP.Execute(ctx, CEP)
+	unitOptions, err := unitsFromRequest(r)
 	if err != nil {
-		spanSearch.AddEvent("error on search location", trace.WithAttributes(attribute.String("error", err.Error())))
-		spanSearch.End()
-		http.Error(w, "can not find location to weather", http.StatusNotFound)
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on units options")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "opções de unidade inválidas", err), i18n.ErrInvalidUnits)
+		spanValidate.End()
 		return
 	}
 
-	spanSearch.AddEvent("prepare request to service B")
-	locationJson, err := json.Marshal(outputCEP)
+	responseEncoder, err := encoder.Negotiate(r.Header.Get("Accept"))
 	if err != nil {
-		spanSearch.AddEvent("error on prepare request", trace.WithAttributes(attribute.String("error", err.Error())))
-		spanSearch.End()
-		http.Error(w, "can not find location to weather", http.StatusNotFound)
+		spanValidate.RecordError(err, trace.WithAttributes(attribute.String("accept", r.Header.Get("Accept"))))
+		spanValidate.SetStatus(codes.Error, "error on negotiate format")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeNotAcceptable, "formato de resposta não suportado", err), i18n.ErrNotAcceptable)
+		spanValidate.End()
 		return
 	}
+	spanValidate.AddEvent("response format", trace.WithAttributes(attribute.String("content_type", responseEncoder.ContentType())))
+	spanValidate.End()
 
-	spanSearch.End()
-
-	ctx, spanRequestServiceB := tracer.Start(ctx, "CEP-request-service-B")
-
-	spanRequestServiceB.AddEvent("prepare transport")
-	http.DefaultTransport = transport.Chain(
-		http.DefaultTransport,
-		transport.SetHeader("User-Agent", "my-app/v1.0.0"),
-		traceid.Transport,
-	)
-
-	spanRequestServiceB.AddEvent("prepare request")
-	req, err := http.NewRequest("POST", os.Getenv("HOST_SERVICE_B")+"/weather", strings.NewReader(string(locationJson)))
+	ctx, spanSearch := tracer.Start(ctx, "zipcode-search")
+	spanSearch.AddEvent("search weather by zipcode")
+	outputWeather, err := wh.GetWeatherByZipcode.Execute(ctx, dto.WeatherByCEPInput{
+		CEP:   CEP,
+		Query: serviceBQuery(r, unitOptions),
+		Lang:  string(lang),
+	})
 	if err != nil {
-		spanRequestServiceB.AddEvent("request error service B", trace.WithAttributes(attribute.String("error", err.Error())))
-		spanRequestServiceB.End()
-		http.Error(w, "cant create request", http.StatusUnprocessableEntity)
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search weather")
+		writeProblemDetail(ctx, w, r, lang, err, weatherByCEPDetail(lang, err))
+		spanSearch.End()
 		return
 	}
+	spanSearch.End()
 
-	spanRequestServiceB.AddEvent("try request service B")
-	resp, err := http.DefaultClient.Do(req)
+	_, spanResponse := tracer.Start(ctx, "CEP-response")
+	spanResponse.AddEvent("prepare to response", trace.WithAttributes(attribute.String("content_type", responseEncoder.ContentType())))
+	payload, err := responseEncoder.Encode(outputWeather)
 	if err != nil {
-		spanRequestServiceB.AddEvent("request error service B", trace.WithAttributes(attribute.String("error", err.Error())))
-		spanRequestServiceB.End()
-		http.Error(w, "cant get data", http.StatusUnprocessableEntity)
+		spanResponse.RecordError(err)
+		spanResponse.SetStatus(codes.Error, "error on response")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInternal, "falha ao montar resposta", err), i18n.ErrEncodeResponse)
+		spanResponse.End()
 		return
 	}
 
-	spanRequestServiceB.AddEvent("read data response service B")
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		spanRequestServiceB.AddEvent("error read body service B", trace.WithAttributes(attribute.String("error", err.Error())))
-		spanRequestServiceB.End()
-		http.Error(w, "cant read data service B", http.StatusUnprocessableEntity)
+	w.Header().Set("Content-Type", responseEncoder.ContentType())
+	if writeCacheable(w, r, payload, cacheControl(outputWeather.ExpiresAt)) {
+		spanResponse.AddEvent("not modified", trace.WithAttributes(attribute.String("etag", w.Header().Get("ETag"))))
+		spanResponse.End()
 		return
 	}
-	spanRequestServiceB.End()
 
-	_, spanResponse := tracer.Start(ctx, "CEP-response")
-	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-		spanResponse.AddEvent("response service B success")
-		var p fastjson.Parser
-		v, err := p.Parse(string(respBody))
-		if err != nil {
-			spanResponse.AddEvent("error parse body data service B", trace.WithAttributes(attribute.String("error", err.Error())))
-			http.Error(w, "cant parse data service B", http.StatusUnprocessableEntity)
-			return
-		}
-
-		w.Header().Add("Content-Type", "application/json")
-
-		spanResponse.AddEvent("prepare to response")
+	spanResponse.AddEvent(
+		"response success",
+		trace.WithAttributes(
+			attribute.String("city", outputWeather.City),
+			attribute.Float64("temp_C", outputWeather.C),
+			attribute.Float64("temp_F", outputWeather.F),
+			attribute.Float64("temp_K", outputWeather.K),
+		),
+	)
+	spanResponse.End()
+}
 
-		response := make(map[string]interface{})
-		response["city"] = outputCEP.CIDADE
-		response["temp_C"] = v.GetFloat64("temp_C")
-		response["temp_F"] = v.GetFloat64("temp_F")
-		response["temp_K"] = v.GetFloat64("temp_K")
+// serviceBQuery - opções de unidade e qualidade do ar recebidas, repassadas ao serviço B
+func serviceBQuery(r *http.Request, unitOptions units.Options) url.Values {
+	query := unitOptions.Query()
+	if wantsAirQuality(r) {
+		query.Set("aqi", "yes")
+	}
 
-		err = json.NewEncoder(w).Encode(response)
-		if err != nil {
-			spanResponse.AddEvent("error response service B", trace.WithAttributes(attribute.String("error", err.Error())))
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-			return
-		}
+	return query
+}
 
-		spanResponse.AddEvent(
-			"response success",
-			trace.WithAttributes(
-				attribute.String("city", outputCEP.CIDADE),
-				attribute.Float64("temp_C", v.GetFloat64("temp_C")),
-				attribute.Float64("temp_F", v.GetFloat64("temp_F")),
-				attribute.Float64("temp_K", v.GetFloat64("temp_K")),
-			),
-		)
-		spanResponse.End()
+// weatherByCEPDetail - detail do problem para os erros da busca de clima pelo CEP; o problem do
+// serviço B mantém o detail recebido, que já vem no idioma pedido
+func weatherByCEPDetail(lang i18n.Lang, err error) string {
+	var appErr *apperror.Error
+	if errors.Is(err, service.ErrServiceBProblem) && errors.As(err, &appErr) {
+		return appErr.Message
+	}
 
-		return
+	switch {
+	case errors.Is(err, service.ErrServiceBResponse):
+		return i18n.T(lang, i18n.ErrUnexpectedServiceB)
+	case errors.Is(err, apperror.ErrCEPNotFound), errors.Is(err, apperror.ErrLocationNotFound):
+		return i18n.T(lang, i18n.ErrLocationNotFound)
+	case errors.Is(err, apperror.ErrInternal):
+		return i18n.T(lang, i18n.ErrCreateRequest)
 	}
 
-	spanResponse.AddEvent(fmt.Sprintf("response service B error: %d", resp.StatusCode))
-	spanResponse.End()
-	http.Error(w, string(respBody), resp.StatusCode)
+	return i18n.T(lang, i18n.ErrRequestServiceB)
 }
 
-// GetWeatherByLocal - busca de clima pelo local
+// GetWeatherByLocal - busca de clima pelas coordenadas informadas no corpo (POST /weather)
 func (wh *Handler) GetWeatherByLocal(w http.ResponseWriter, r *http.Request) {
-	carrier := propagation.HeaderCarrier(r.Header)
-	ctx := traceid.NewContext(r.Context())
-	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	wh.weatherByLocation(w, r, locationFromBody, i18n.ErrDecodeLocation)
+}
+
+// GetWeatherByCoordinates - busca de clima pelas coordenadas da query string (GET /v1/weather/coordinates)
+func (wh *Handler) GetWeatherByCoordinates(w http.ResponseWriter, r *http.Request) {
+	wh.weatherByLocation(w, r, locationFromQuery, i18n.ErrInvalidCoordinates)
+}
 
-	tracer := otel.Tracer("handler-GetWeatherByLocal")
+// weatherByLocation - fluxo comum de busca de clima pelas coordenadas
+func (wh *Handler) weatherByLocation(w http.ResponseWriter, r *http.Request, extract locationExtractor, extractErrorKey string) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx := traceid.NewContext(r.Context())
 	ctx, spanValidate := tracer.Start(ctx, "validate_location")
 
-	spanValidate.AddEvent("extract POST body")
-	data := GetWeatherByLocalRequest{}
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&data)
+	spanValidate.AddEvent("extract location", trace.WithAttributes(attribute.String("http.method", r.Method)))
+	data, err := extract(r)
 	if err != nil {
-		spanValidate.AddEvent("error on decode body", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on extract location")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "localização inválida", err), extractErrorKey)
 		spanValidate.End()
-		http.Error(w, "cant decode location", http.StatusUnprocessableEntity)
 		return
 	}
+
+	unitOptions, err := unitsFromRequest(r)
+	if err != nil {
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on units options")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "opções de unidade inválidas", err), i18n.ErrInvalidUnits)
+		spanValidate.End()
+		return
+	}
+
+	responseEncoder, err := encoder.Negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		spanValidate.RecordError(err, trace.WithAttributes(attribute.String("accept", r.Header.Get("Accept"))))
+		spanValidate.SetStatus(codes.Error, "error on negotiate format")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeNotAcceptable, "formato de resposta não suportado", err), i18n.ErrNotAcceptable)
+		spanValidate.End()
+		return
+	}
+	spanValidate.AddEvent("response format", trace.WithAttributes(attribute.String("content_type", responseEncoder.ContentType())))
 	spanValidate.End()
 
 	ctx, spanInput := tracer.Start(ctx, "weather_input")
+	withAirQuality := wantsAirQuality(r)
 	input := dto.WeatherInput{
 		Latitude:  data.Latitude,
 		Longitude: data.Longitude,
+		// com a WeatherAPI a qualidade do ar vem na mesma consulta do clima
+		AirQuality: withAirQuality && wh.GetAirQuality.InWeatherResponse(),
+		Lang:       i18n.WeatherAPILang(lang),
 	}
 
-	spanInput.AddEvent("location data", trace.WithAttributes(attribute.String("latitude", data.Latitude), attribute.String("longitude", data.Longitude)))
+	spanInput.AddEvent(
+		"location data",
+		trace.WithAttributes(
+			attribute.String("latitude", data.Latitude),
+			attribute.String("longitude", data.Longitude),
+			attribute.Bool("air_quality", withAirQuality),
+		),
+	)
 
 	spanInput.End()
 
 	ctx, spanSearch := tracer.Start(ctx, "weather_search")
 	outputWeather, err := wh.GetWeatherByLocation.Execute(ctx, input)
 	if err != nil {
-		spanSearch.AddEvent("error on search location", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search location")
+		writeProblem(ctx, w, r, lang, err, i18n.ErrLocationNotFound)
 		spanSearch.End()
-		http.Error(w, "can not find location to weather", http.StatusNotFound)
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-
 	spanSearch.AddEvent("locations and weather found")
 	spanSearch.End()
 
+	if withAirQuality && !input.AirQuality {
+		var spanAirQuality trace.Span
+		ctx, spanAirQuality = tracer.Start(ctx, "air_quality_search")
+		airQuality, err := wh.GetAirQuality.Execute(ctx, dto.AirQualityInput{
+			Latitude:  input.Latitude,
+			Longitude: input.Longitude,
+		})
+		if err != nil {
+			// qualidade do ar é opcional, a temperatura segue sendo respondida
+			spanAirQuality.RecordError(err)
+			spanAirQuality.SetStatus(codes.Error, "error on search air quality")
+		} else {
+			spanAirQuality.AddEvent("air quality found", trace.WithAttributes(attribute.String("category", airQuality.Category)))
+			outputWeather.AirQuality = &airQuality
+		}
+		spanAirQuality.End()
+	}
+
 	_, spanResponse := tracer.Start(ctx, "weather_response")
-	spanResponse.AddEvent("prepare response")
-	// hidratando com cidade
-	err = json.NewEncoder(w).Encode(outputWeather)
+	spanResponse.AddEvent(
+		"apply units",
+		trace.WithAttributes(
+			attribute.String("temperature_unit", string(unitOptions.Temperature)),
+			attribute.Int("precision", unitOptions.Precision),
+			attribute.String("rounding", string(unitOptions.Rounding)),
+		),
+	)
+	units.Apply(unitOptions, &outputWeather)
+	localizeWeather(lang, &outputWeather)
+
+	spanResponse.AddEvent("prepare response", trace.WithAttributes(attribute.String("content_type", responseEncoder.ContentType())))
+	payload, err := responseEncoder.Encode(outputWeather)
 	if err != nil {
-		spanResponse.AddEvent("error on response", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanResponse.RecordError(err)
+		spanResponse.SetStatus(codes.Error, "error on response")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInternal, "falha ao montar resposta", err), i18n.ErrEncodeResponse)
+		spanResponse.End()
+		return
+	}
+
+	w.Header().Set("Content-Type", responseEncoder.ContentType())
+
+	if writeCacheable(w, r, payload, cacheControl(outputWeather.ExpiresAt)) {
+		spanResponse.AddEvent("not modified", trace.WithAttributes(attribute.String("etag", w.Header().Get("ETag"))))
 		spanResponse.End()
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
@@ -248,3 +457,36 @@ func (wh *Handler) GetWeatherByLocal(w http.ResponseWriter, r *http.Request) {
 	)
 	spanResponse.End()
 }
+
+// localizeWeather - traduz as descrições derivadas localmente; a condição já vem traduzida pelo provedor.
+// A qualidade do ar é copiada antes da tradução, já que o ponteiro pode ser o da entrada do cache.
+func localizeWeather(lang i18n.Lang, output *dto.WeatherOutput) {
+	output.BeaufortDescription = i18n.T(lang, i18n.PrefixBeaufort+strconv.Itoa(output.Beaufort))
+	if output.AirQuality != nil {
+		airQuality := *output.AirQuality
+		airQuality.Category = i18n.AirQualityCategory(lang, airQuality.USEPAIndex)
+		output.AirQuality = &airQuality
+	}
+}
+
+// localizedAlerts - alertas da leitura com a mensagem no idioma negociado
+func localizedAlerts(lang i18n.Lang, output dto.WeatherOutput) []dto.Alert {
+	alerts := alert.Evaluate(output)
+	for i := range alerts {
+		alerts[i].Message = i18n.T(lang, i18n.PrefixAlert+alerts[i].Type+"."+alerts[i].Severity)
+	}
+
+	return alerts
+}
+
+// GetLabels - rótulos dos campos de resposta no idioma negociado pelo Accept-Language
+func (wh *Handler) GetLabels(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+	w.Header().Add("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(i18n.Labels(lang))
+	if err != nil {
+		writeProblem(r.Context(), w, r, lang, apperror.Wrap(apperror.CodeInternal, "falha ao montar resposta", err), i18n.ErrEncodeResponse)
+	}
+}
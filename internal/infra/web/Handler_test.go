@@ -0,0 +1,63 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+)
+
+func TestLocalizeWeatherKeepsSharedAirQuality(t *testing.T) {
+	// a leitura do cache, com a categoria no idioma padrão
+	cached := dto.WeatherOutput{Beaufort: 2, AirQuality: &dto.AirQualityOutput{USEPAIndex: 2, Category: i18n.AirQualityCategory(i18n.Default, 2)}}
+	want := cached.AirQuality.Category
+
+	var wg sync.WaitGroup
+	categories := make([]string, 3)
+	for i, lang := range []i18n.Lang{i18n.PT, i18n.ES, i18n.EN} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output := cached
+			localizeWeather(lang, &output)
+			categories[i] = output.AirQuality.Category
+		}()
+	}
+	wg.Wait()
+
+	if cached.AirQuality.Category != want {
+		t.Errorf("categoria no cache = %q, esperado %q", cached.AirQuality.Category, want)
+	}
+	for i, lang := range []i18n.Lang{i18n.PT, i18n.ES, i18n.EN} {
+		if expected := i18n.AirQualityCategory(lang, 2); categories[i] != expected {
+			t.Errorf("categoria em %s = %q, esperado %q", lang, categories[i], expected)
+		}
+	}
+}
+
+func TestWeatherNotAcceptable(t *testing.T) {
+	handler := &Handler{}
+
+	for _, accept := range []string{"text/html", "application/json;q=0, */*;q=0"} {
+		request := httptest.NewRequest(http.MethodGet, "/v1/weather/coordinates?lat=-23.42&lon=-51.93", nil)
+		request.Header.Set("Accept", accept)
+		recorder := httptest.NewRecorder()
+		// a negociação acontece antes das consultas, então o handler não precisa dos usecases
+		handler.GetWeatherByCoordinates(recorder, request)
+
+		if recorder.Code != http.StatusNotAcceptable {
+			t.Fatalf("Accept %q: status = %d, esperado 406: %s", accept, recorder.Code, recorder.Body)
+		}
+		problem := map[string]any{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+			t.Fatal(err)
+		}
+		if problem["code"] != "NOT_ACCEPTABLE" {
+			t.Errorf("Accept %q: code = %v, esperado NOT_ACCEPTABLE", accept, problem["code"])
+		}
+	}
+}
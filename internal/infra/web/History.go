@@ -0,0 +1,148 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// historyDefaultRange - período consultado quando a requisição não informa ?from=
+	historyDefaultRange = 24 * time.Hour
+	// historyMaxRange - maior período aceito em uma consulta
+	historyMaxRange = 366 * 24 * time.Hour
+	// historyMinStep - menor intervalo de agregação
+	historyMinStep = time.Minute
+)
+
+// parseHistoryTime - momento em RFC 3339 ou data YYYY-MM-DD (meia-noite UTC)
+func parseHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
+
+// parseHistoryStep - intervalo de agregação no formato de time.Duration, aceitando também dias
+// (ex.: 1d); vazio ou raw devolve as leituras sem agregação
+func parseHistoryStep(value string) (time.Duration, error) {
+	if value == "" || value == "raw" {
+		return 0, nil
+	}
+
+	var step time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("intervalo inválido: %q", value)
+		}
+		step = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+		step = parsed
+	}
+
+	if step < historyMinStep {
+		return 0, fmt.Errorf("intervalo menor que %s: %q", historyMinStep, value)
+	}
+
+	return step, nil
+}
+
+// historyInputFromRequest - período e agregação pela query string (?from=&to=&step=); sem from, as
+// últimas 24 horas até to, que por padrão é agora
+func historyInputFromRequest(r *http.Request) (dto.HistoryInput, string, error) {
+	query := r.URL.Query()
+	input := dto.HistoryInput{To: time.Now().UTC()}
+
+	var err error
+	if value := query.Get("to"); value != "" {
+		if input.To, err = parseHistoryTime(value); err != nil {
+			return input, i18n.ErrInvalidHistoryRange, err
+		}
+	}
+	input.From = input.To.Add(-historyDefaultRange)
+	if value := query.Get("from"); value != "" {
+		if input.From, err = parseHistoryTime(value); err != nil {
+			return input, i18n.ErrInvalidHistoryRange, err
+		}
+	}
+	if !input.From.Before(input.To) || input.To.Sub(input.From) > historyMaxRange {
+		return input, i18n.ErrInvalidHistoryRange, fmt.Errorf("período inválido: de %s a %s", input.From, input.To)
+	}
+
+	if input.Step, err = parseHistoryStep(query.Get("step")); err != nil {
+		return input, i18n.ErrInvalidHistoryStep, err
+	}
+
+	return input, "", nil
+}
+
+// GetHistoryByCEP - leituras guardadas de um CEP da watchlist, com agregação opcional (GET /history/cep/{cep})
+func (wh *Handler) GetHistoryByCEP(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx := r.Context()
+	ctx, spanValidate := tracer.Start(ctx, "validate_history_input")
+
+	CEP, ok := sanitizeCEP(r.PathValue("cep"))
+	if !ok {
+		spanValidate.RecordError(apperror.ErrInvalidCEP)
+		spanValidate.SetStatus(codes.Error, "error on check validate zipcode")
+		writeProblem(ctx, w, r, lang, apperror.ErrInvalidCEP, i18n.ErrInvalidZipcode)
+		spanValidate.End()
+		return
+	}
+
+	input, detailKey, err := historyInputFromRequest(r)
+	if err != nil {
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on parse history query")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "consulta ao histórico inválida", err), detailKey)
+		spanValidate.End()
+		return
+	}
+	input.CEP = CEP
+	spanValidate.End()
+
+	ctx, spanSearch := tracer.Start(ctx, "history-search")
+	defer spanSearch.End()
+
+	output, err := wh.GetHistory.Execute(ctx, input)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search history")
+		detailKey := i18n.ErrReadHistory
+		if apperror.CodeOf(err) == apperror.CodeNotFound {
+			detailKey = i18n.ErrHistoryNotFound
+		}
+		writeProblem(ctx, w, r, lang, err, detailKey)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(output)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on response")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInternal, "falha ao montar resposta", err), i18n.ErrEncodeResponse)
+		return
+	}
+
+	spanSearch.AddEvent("response success", trace.WithAttributes(attribute.Int("points", len(output.Points))))
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Metrics - registra rate, erros e duração (RED) de cada requisição pela rota do mux que a atende,
+// e nomeia o span do servidor pela rota (ex.: GET /v1/weather/cep/{cep}), com o atributo http.route.
+// httpsnoop preserva Flusher e Hijacker do ResponseWriter, então SSE e WebSocket seguem funcionando;
+// nesses, a duração é a da conexão.
+func Metrics(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// o padrão registrado (ex.: GET /v1/weather/cep/{cep}), não o path, para não abrir uma série por CEP
+		_, pattern := mux.Handler(r)
+		route := pattern
+		if _, path, ok := strings.Cut(pattern, " "); ok {
+			route = path
+		}
+		if route != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		captured := httpsnoop.CaptureMetrics(mux, w, r)
+		metrics.RecordRequest(r.Context(), r.Method, route, captured.Code, captured.Duration)
+	})
+}
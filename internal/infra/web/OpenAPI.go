@@ -0,0 +1,88 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// openAPISpec - documento OpenAPI 3 das rotas dos serviços A e B; o teste de drift garante que os
+// schemas acompanham os tipos de requisição e resposta dos handlers
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIAssets - CSS e JS do Swagger UI embutidos no binário, servidos em /docs/{asset}; o go
+// generate traz os arquivos do swagger-ui-dist na versão fixada abaixo, do registry do npm (uma versão
+// publicada no npm não muda)
+//
+//go:generate sh -c "set -e; tmp=$(mktemp -d); curl -fsSL https://registry.npmjs.org/swagger-ui-dist/-/swagger-ui-dist-5.17.14.tgz | tar -xz -C $tmp; cp $tmp/package/swagger-ui.css $tmp/package/swagger-ui-bundle.js swaggerui/; rm -rf $tmp"
+//go:embed swaggerui
+var swaggerUIAssets embed.FS
+
+// swaggerUIFiles - assets servidos em /docs/{asset}
+var swaggerUIFiles = map[string]bool{
+	"swagger-ui.css":       true,
+	"swagger-ui-bundle.js": true,
+}
+
+// swaggerUIBundled - se o go generate trouxe os assets para o binário
+var swaggerUIBundled = func() bool {
+	for name := range swaggerUIFiles {
+		if _, err := fs.Stat(swaggerUIAssets, "swaggerui/"+name); err != nil {
+			return false
+		}
+	}
+	return true
+}()
+
+// swaggerUI - página do Swagger UI apontando para /openapi.json, com os assets servidos pelo próprio serviço
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>pos_go_weather_otel - API</title>
+	<link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="/docs/swagger-ui-bundle.js"></script>
+	<script>
+		window.onload = () => {
+			window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+		};
+	</script>
+</body>
+</html>
+`
+
+// GetOpenAPI - documento OpenAPI das rotas
+func (wh *Handler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// GetDocs - Swagger UI com a documentação das rotas; 503 se o binário foi compilado sem os assets
+func (wh *Handler) GetDocs(w http.ResponseWriter, r *http.Request) {
+	if !swaggerUIBundled {
+		http.Error(w, "Swagger UI não embutido: rode go generate ./internal/infra/web antes de compilar", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUI))
+}
+
+// GetDocsAsset - CSS e JS do Swagger UI embutidos
+func (wh *Handler) GetDocsAsset(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("asset")
+	if !swaggerUIFiles[name] {
+		http.NotFound(w, r)
+		return
+	}
+
+	// o nome não carrega a versão, então o cache é curto
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.ServeFileFS(w, r, swaggerUIAssets, "swaggerui/"+name)
+}
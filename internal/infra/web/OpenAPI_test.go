@@ -0,0 +1,225 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/webhook"
+)
+
+type specSchema struct {
+	Type       string                `json:"type"`
+	Ref        string                `json:"$ref"`
+	Required   []string              `json:"required"`
+	Properties map[string]specSchema `json:"properties"`
+}
+
+type spec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]specSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// specTypes - schema do documento para cada tipo de requisição e resposta dos handlers
+var specTypes = map[string]reflect.Type{
+	"CEPRequest":                 reflect.TypeOf(GetLocationByCEPRequest{}),
+	"LocationRequest":            reflect.TypeOf(GetWeatherByLocalRequest{}),
+	"Weather":                    reflect.TypeOf(dto.WeatherOutput{}),
+	"AirQuality":                 reflect.TypeOf(dto.AirQualityOutput{}),
+	"Units":                      reflect.TypeOf(dto.Units{}),
+	"Astronomy":                  reflect.TypeOf(dto.AstronomyOutput{}),
+	"AstronomyCrossCheck":        reflect.TypeOf(dto.AstronomyCrossCheck{}),
+	"Problem":                    reflect.TypeOf(Problem{}),
+	"Alert":                      reflect.TypeOf(dto.Alert{}),
+	"WebSocketClientMessage":     reflect.TypeOf(wsClientMessage{}),
+	"WebSocketServerMessage":     reflect.TypeOf(wsServerMessage{}),
+	"GraphQLRequest":             reflect.TypeOf(GraphQLRequest{}),
+	"WebhookRequest":             reflect.TypeOf(WebhookRequest{}),
+	"WebhookSubscription":        reflect.TypeOf(webhook.Subscription{}),
+	"WebhookSubscriptionCreated": reflect.TypeOf(WebhookSubscriptionCreated{}),
+	"WebhookEvent":               reflect.TypeOf(webhook.Event{}),
+	"WebhookDeadLetter":          reflect.TypeOf(webhook.DeadLetter{}),
+	"HistoryOutput":              reflect.TypeOf(dto.HistoryOutput{}),
+	"HistoryPoint":               reflect.TypeOf(dto.HistoryPoint{}),
+}
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+
+	var document spec
+	if err := json.Unmarshal(openAPISpec, &document); err != nil {
+		t.Fatalf("openapi.json inválido: %v", err)
+	}
+
+	return document
+}
+
+// schemaNameOf - nome do schema documentado para o tipo, ou vazio quando o tipo não é mapeado
+func schemaNameOf(typ reflect.Type) string {
+	for name, mapped := range specTypes {
+		if mapped == typ {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// specTypeOf - tipo JSON esperado no documento para o tipo Go
+func specTypeOf(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+
+	return ""
+}
+
+func TestOpenAPISchemasMatchTypes(t *testing.T) {
+	document := loadSpec(t)
+
+	for name, typ := range specTypes {
+		schema, ok := document.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s (%s) ausente do openapi.json", name, typ)
+			continue
+		}
+
+		fields := map[string]bool{}
+		var required []string
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" || !field.IsExported() {
+				continue
+			}
+
+			jsonName, options, _ := strings.Cut(tag, ",")
+			if jsonName == "" {
+				jsonName = field.Name
+			}
+			fields[jsonName] = true
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, jsonName)
+			}
+
+			property, ok := schema.Properties[jsonName]
+			if !ok {
+				t.Errorf("%s.%s: campo %s não documentado em %s", typ, field.Name, jsonName, name)
+				continue
+			}
+
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+
+			if refName := schemaNameOf(fieldType); refName != "" {
+				if property.Ref != "#/components/schemas/"+refName {
+					t.Errorf("%s.%s: esperado $ref para %s, documentado %q", name, jsonName, refName, property.Ref)
+				}
+				continue
+			}
+
+			if want := specTypeOf(fieldType); property.Type != want {
+				t.Errorf("%s.%s: tipo documentado %q, tipo Go %s espera %q", name, jsonName, property.Type, field.Type, want)
+			}
+		}
+
+		for property := range schema.Properties {
+			if !fields[property] {
+				t.Errorf("%s.%s documentado mas ausente de %s", name, property, typ)
+			}
+		}
+
+		documented := append([]string(nil), schema.Required...)
+		sort.Strings(documented)
+		sort.Strings(required)
+		if !reflect.DeepEqual(documented, required) {
+			t.Errorf("%s: required documentado %v, campos sem omitempty em %s %v", name, documented, typ, required)
+		}
+	}
+}
+
+// undocumentedRoutes - rotas da documentação, fora do próprio documento
+var undocumentedRoutes = map[string]bool{
+	"GET /openapi.json": true,
+	"GET /docs":         true,
+	"GET /docs/{asset}": true,
+}
+
+func TestOpenAPIDocumentsRoutes(t *testing.T) {
+	document := loadSpec(t)
+
+	registered := map[string]bool{}
+	for _, route := range (&Handler{}).Routes() {
+		registered[route.Pattern] = true
+		if undocumentedRoutes[route.Pattern] {
+			continue
+		}
+
+		method, path, _ := strings.Cut(route.Pattern, " ")
+		if _, ok := document.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("rota %s não documentada no openapi.json", route.Pattern)
+		}
+	}
+
+	for path, operations := range document.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			if route := strings.ToUpper(method) + " " + path; !registered[route] {
+				t.Errorf("rota %s documentada no openapi.json mas não registrada", route)
+			}
+		}
+	}
+}
+
+func TestGetDocsAsset(t *testing.T) {
+	mux := http.NewServeMux()
+	for _, route := range (&Handler{}).Routes() {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
+
+	for _, test := range []struct {
+		path   string
+		status int
+	}{
+		{"/docs/README.md", http.StatusNotFound},
+		{"/docs/..%2FOpenAPI.go", http.StatusNotFound},
+	} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if recorder.Code != test.status {
+			t.Errorf("GET %s = %d, esperado %d", test.path, recorder.Code, test.status)
+		}
+	}
+
+	// sem os assets a página não aponta para arquivos inexistentes
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if want := map[bool]int{true: http.StatusOK, false: http.StatusServiceUnavailable}[swaggerUIBundled]; recorder.Code != want {
+		t.Errorf("GET /docs = %d com assets embutidos = %v, esperado %d", recorder.Code, swaggerUIBundled, want)
+	}
+	if strings.Contains(recorder.Body.String(), "unpkg.com") {
+		t.Error("a página do Swagger UI carrega assets de CDN")
+	}
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem - corpo de erro no formato RFC 7807 (application/problem+json), estendido com o código
+// estável do erro e o trace ID para correlação com o Zipkin
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	TraceID  string `json:"trace_id,omitempty"`
+}
+
+// statusOf - status HTTP para cada erro tipado; erros sem tipo respondem 500
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, apperror.ErrInvalidRequest), errors.Is(err, apperror.ErrInvalidCEP):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperror.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperror.ErrCEPNotFound), errors.Is(err, apperror.ErrLocationNotFound), errors.Is(err, apperror.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperror.ErrNotAcceptable):
+		return http.StatusNotAcceptable
+	case errors.Is(err, apperror.ErrUpstreamTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, apperror.ErrUpstreamRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, apperror.ErrUpstreamUnavailable):
+		return http.StatusBadGateway
+	case errors.Is(err, apperror.ErrQuotaExceeded):
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+// writeProblem - responde o erro como problem+json; title vem do código e detail da chave do catálogo
+func writeProblem(ctx context.Context, w http.ResponseWriter, r *http.Request, lang i18n.Lang, err error, detailKey string) {
+	writeProblemDetail(ctx, w, r, lang, err, i18n.T(lang, detailKey))
+}
+
+// writeProblemDetail - como writeProblem, mas com o detail já pronto (ex.: repassado pelo serviço B).
+// O código do erro vai para o span corrente e para o span do servidor HTTP (error.type). Seguindo as
+// convenções semânticas, só as respostas 5xx marcam o span do servidor com a exceção e o status
+// Error; nas 4xx a falha é do cliente, e o status fica sem definir.
+func writeProblemDetail(ctx context.Context, w http.ResponseWriter, r *http.Request, lang i18n.Lang, err error, detail string) {
+	code := apperror.CodeOf(err)
+	status := statusOf(err)
+
+	trace.SpanFromContext(ctx).SetAttributes(semconv.ErrorTypeKey.String(string(code)))
+	serverSpan := trace.SpanFromContext(r.Context())
+	serverSpan.SetAttributes(semconv.ErrorTypeKey.String(string(code)))
+	if status >= http.StatusInternalServerError {
+		serverSpan.RecordError(err)
+		serverSpan.SetStatus(codes.Error, string(code))
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(newProblem(ctx, r, lang, err, detail))
+}
+
+// newProblem - monta o problem do erro, com o trace ID do contexto
+func newProblem(ctx context.Context, r *http.Request, lang i18n.Lang, err error, detail string) Problem {
+	code := apperror.CodeOf(err)
+
+	problem := Problem{
+		Type:     "urn:pos-go-weather-otel:problem:" + strings.ToLower(strings.ReplaceAll(string(code), "_", "-")),
+		Title:    i18n.T(lang, i18n.PrefixProblem+string(code)),
+		Status:   statusOf(err),
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     string(code),
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		problem.TraceID = spanContext.TraceID().String()
+	}
+
+	return problem
+}
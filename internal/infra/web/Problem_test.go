@@ -0,0 +1,66 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWriteProblemServerSpanStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		code      codes.Code
+		exception bool
+	}{
+		{name: "4xx do cliente", err: apperror.ErrInvalidCEP, status: http.StatusUnprocessableEntity, code: codes.Unset},
+		{name: "5xx do serviço", err: apperror.ErrUpstreamUnavailable, status: http.StatusBadGateway, code: codes.Error, exception: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			ctx, span := provider.Tracer("test").Start(context.Background(), "POST /cep", trace.WithSpanKind(trace.SpanKindServer))
+			r := httptest.NewRequest(http.MethodPost, "/cep", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			writeProblem(ctx, w, r, i18n.EN, test.err, i18n.ErrInvalidZipcode)
+			span.End()
+
+			if w.Code != test.status {
+				t.Fatalf("status = %d, esperado %d", w.Code, test.status)
+			}
+
+			ended := recorder.Ended()[0]
+			if ended.Status().Code != test.code {
+				t.Errorf("status do span = %v, esperado %v", ended.Status().Code, test.code)
+			}
+			exception := false
+			for _, event := range ended.Events() {
+				exception = exception || event.Name == "exception"
+			}
+			if exception != test.exception {
+				t.Errorf("exceção registrada = %v, esperado %v", exception, test.exception)
+			}
+			errorType := ""
+			for _, kv := range ended.Attributes() {
+				if kv.Key == "error.type" {
+					errorType = kv.Value.AsString()
+				}
+			}
+			if errorType != string(apperror.CodeOf(test.err)) {
+				t.Errorf("error.type = %q, esperado %q", errorType, apperror.CodeOf(test.err))
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	infraotel "github.com/nagahshi/pos_go_weather_otel/internal/infra/otel"
+	"github.com/nagahshi/pos_go_weather_otel/internal/usecase"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Contexto de trace recebido pelo serviço A, no formato de cada propagador
+const (
+	incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	incomingSpanID  = "00f067aa0ba902b7"
+)
+
+// switchedPropagator - propagador global trocado a cada caso: os transports do pacote service
+// guardam o propagador global na inicialização, que só repassa ao primeiro configurado
+type switchedPropagator struct {
+	current propagation.TextMapPropagator
+}
+
+func (p *switchedPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	p.current.Inject(ctx, carrier)
+}
+
+func (p *switchedPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return p.current.Extract(ctx, carrier)
+}
+
+func (p *switchedPropagator) Fields() []string {
+	return p.current.Fields()
+}
+
+// serviceBCall - o que o serviço B recebeu do serviço A: os headers, o span pai extraído deles e
+// o span do servidor
+type serviceBCall struct {
+	header      http.Header
+	parent      trace.SpanContext
+	spanContext trace.SpanContext
+}
+
+// newBrasilAPIStub - BrasilAPI com as coordenadas de qualquer CEP
+func newBrasilAPIStub(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"city":"Maringá","state":"PR","location":{"coordinates":{"latitude":"-23.4","longitude":"-51.9"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newServiceBStub - serviço B instrumentado como o real (otelhttp), que guarda os headers e o
+// contexto do span do servidor de cada chamada
+func newServiceBStub(t *testing.T, calls chan<- serviceBCall) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(r.Header))
+		calls <- serviceBCall{
+			header:      r.Header.Clone(),
+			parent:      trace.SpanContextFromContext(parent),
+			spanContext: trace.SpanContextFromContext(r.Context()),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"temp_C":21.5,"temp_F":70.7,"temp_K":294.65}`))
+	}), "/"))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestPropagationFromServiceAToServiceB(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	propagator := &switchedPropagator{current: propagation.TraceContext{}}
+	otel.SetTextMapPropagator(propagator)
+
+	brasilAPI := newBrasilAPIStub(t)
+	t.Setenv("BRASILAPI_URL", brasilAPI.URL)
+
+	calls := make(chan serviceBCall, 1)
+	serviceB := newServiceBStub(t, calls)
+
+	handler := &Handler{GetWeatherByZipcode: *usecase.NewGetWeatherByCEPUseCase(serviceB.URL)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /cep", handler.GetLocationByCEP)
+	serviceA := otelhttp.NewHandler(Metrics(mux), "/")
+
+	b3Single := http.Header{"B3": {incomingTraceID + "-" + incomingSpanID + "-1"}}
+	b3Multi := http.Header{
+		"X-B3-Traceid": {incomingTraceID},
+		"X-B3-Spanid":  {incomingSpanID},
+		"X-B3-Sampled": {"1"},
+	}
+
+	tests := []struct {
+		name        string
+		propagators string
+		incoming    http.Header
+		// continued - o trace recebido segue até o serviço B
+		continued bool
+		// outgoing - header que o serviço B deve receber com o trace ID
+		outgoing []string
+	}{
+		{
+			name:        "b3 single header",
+			propagators: "b3",
+			incoming:    b3Single,
+			continued:   true,
+			outgoing:    []string{"B3"},
+		},
+		{
+			name:        "b3 multiple headers",
+			propagators: "b3multi",
+			incoming:    b3Multi,
+			continued:   true,
+			outgoing:    []string{"X-B3-Traceid"},
+		},
+		{
+			name:        "b3 received, all formats sent",
+			propagators: "tracecontext,baggage,b3,b3multi,jaeger",
+			incoming:    b3Single,
+			continued:   true,
+			outgoing:    []string{"Traceparent", "B3", "X-B3-Traceid", "Uber-Trace-Id"},
+		},
+		{
+			name:        "jaeger",
+			propagators: "jaeger",
+			incoming:    http.Header{"Uber-Trace-Id": {incomingTraceID + ":" + incomingSpanID + ":0:1"}},
+			continued:   true,
+			outgoing:    []string{"Uber-Trace-Id"},
+		},
+		{
+			name:        "b3 ignored by the default propagators",
+			propagators: "",
+			incoming:    b3Single,
+			continued:   false,
+			outgoing:    []string{"Traceparent"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("OTEL_PROPAGATORS", test.propagators)
+			current, err := infraotel.NewPropagator()
+			if err != nil {
+				t.Fatalf("NewPropagator: %v", err)
+			}
+			propagator.current = current
+
+			request := httptest.NewRequest(http.MethodPost, "/cep", strings.NewReader(`{"cep":"87033080"}`))
+			for key, values := range test.incoming {
+				request.Header[key] = values
+			}
+			response := httptest.NewRecorder()
+			serviceA.ServeHTTP(response, request)
+
+			if response.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", response.Code, response.Body.String())
+			}
+
+			var call serviceBCall
+			select {
+			case call = <-calls:
+			default:
+				t.Fatal("serviço B não foi chamado")
+			}
+
+			traceID := call.spanContext.TraceID().String()
+			if continued := traceID == incomingTraceID; continued != test.continued {
+				t.Errorf("trace ID no serviço B = %s, continua o recebido = %v, esperado %v", traceID, continued, test.continued)
+			}
+
+			for _, key := range test.outgoing {
+				if value := call.header.Get(key); !strings.Contains(value, traceID) {
+					t.Errorf("header %s enviado ao serviço B = %q, sem o trace ID %s", key, value, traceID)
+				}
+			}
+
+			server, client := propagationSpans(recorder.Ended(), call.spanContext.TraceID())
+			if server == nil || client == nil {
+				t.Fatalf("spans do servidor e do cliente do serviço A não encontrados no trace %s", call.spanContext.TraceID())
+			}
+			if test.continued && (server.Parent().SpanID().String() != incomingSpanID || !server.Parent().IsRemote()) {
+				t.Errorf("pai do span do servidor = %s, esperado o span remoto %s", server.Parent().SpanID(), incomingSpanID)
+			}
+			if client.SpanContext().TraceID() != server.SpanContext().TraceID() {
+				t.Errorf("span do cliente em outro trace: %s, servidor %s", client.SpanContext().TraceID(), server.SpanContext().TraceID())
+			}
+			if call.parent.SpanID() != client.SpanContext().SpanID() {
+				t.Errorf("pai do span do serviço B = %s, esperado o span do cliente %s", call.parent.SpanID(), client.SpanContext().SpanID())
+			}
+		})
+	}
+}
+
+// propagationSpans - span do servidor de /cep e span do cliente da chamada ao serviço B, entre os
+// spans terminados do serviço A no trace que chegou ao serviço B
+func propagationSpans(spans []sdktrace.ReadOnlySpan, traceID trace.TraceID) (server sdktrace.ReadOnlySpan, client sdktrace.ReadOnlySpan) {
+	for _, span := range spans {
+		switch {
+		case span.SpanContext().TraceID() != traceID:
+		case span.SpanKind() == trace.SpanKindServer && span.Name() == "POST /cep":
+			server = span
+		case span.SpanKind() == trace.SpanKindClient && span.Parent().IsValid() && spanNamed(spans, span.Parent().SpanID(), "service_B_request"):
+			client = span
+		}
+	}
+
+	return server, client
+}
+
+// spanNamed - indica se o span com o ID tem o nome
+func spanNamed(spans []sdktrace.ReadOnlySpan, spanID trace.SpanID, name string) bool {
+	for _, span := range spans {
+		if span.SpanContext().SpanID() == spanID {
+			return span.Name() == name
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package web
+
+import "net/http"
+
+// Route - padrão do ServeMux ("MÉTODO /caminho") e o handler da rota
+type Route struct {
+	Pattern string
+	Handler http.HandlerFunc
+}
+
+// Routes - rotas dos serviços, registradas por cmd/main.go; o teste do OpenAPI confere o documento
+// contra esta mesma tabela
+func (wh *Handler) Routes() []Route {
+	return []Route{
+		// rotas legadas, corpo JSON
+		{"POST /cep", wh.GetLocationByCEP},
+		{"POST /weather", wh.GetWeatherByLocal},
+		{"POST /cep/astronomy", wh.GetAstronomyByCEP},
+		// rotas versionadas, cacheáveis por CDN e navegadores
+		{"GET /v1/weather/cep/{cep}", wh.GetWeatherByCEP},
+		{"GET /v1/weather/cep/{cep}/astronomy", wh.GetAstronomyByCEPPath},
+		{"GET /v1/weather/cep/{cep}/stream", wh.GetWeatherStream},
+		{"GET /v1/weather/coordinates", wh.GetWeatherByCoordinates},
+		{"GET /v1/weather/ws", wh.GetWeatherWebSocket},
+		{"GET /labels", wh.GetLabels},
+		// histórico local dos CEPs da watchlist
+		{"GET /history/cep/{cep}", wh.GetHistoryByCEP},
+		// assinaturas de webhook por condição de clima
+		{"POST /v1/webhooks", wh.PostWebhook},
+		{"GET /v1/webhooks", wh.GetWebhooks},
+		{"GET /v1/webhooks/{id}", wh.GetWebhook},
+		{"DELETE /v1/webhooks/{id}", wh.DeleteWebhook},
+		{"GET /v1/webhooks/dead-letters", wh.GetWebhookDeadLetters},
+		{"POST /v1/webhooks/dead-letters/{id}/retry", wh.PostWebhookDeadLetterRetry},
+		// gateway GraphQL
+		{"POST /graphql", wh.PostGraphQL},
+		// documentação, fora do próprio documento
+		{"GET /openapi.json", wh.GetOpenAPI},
+		{"GET /docs", wh.GetDocs},
+		{"GET /docs/{asset}", wh.GetDocsAsset},
+	}
+}
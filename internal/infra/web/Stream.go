@@ -0,0 +1,190 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// streamHeartbeat - comentário enviado sem leitura nova, para proxies não fecharem a conexão ociosa
+	streamHeartbeat = 15 * time.Second
+	// streamWriteTimeout - prazo de cada escrita no stream, no lugar do WriteTimeout do servidor
+	streamWriteTimeout = 10 * time.Second
+)
+
+// transientError - erros em que vale seguir consultando; os demais encerram o stream
+func transientError(err error) bool {
+	return errors.Is(err, apperror.ErrUpstreamUnavailable) ||
+		errors.Is(err, apperror.ErrUpstreamTimeout) ||
+		errors.Is(err, apperror.ErrUpstreamRateLimited) ||
+		errors.Is(err, apperror.ErrQuotaExceeded)
+}
+
+// streamKey - chave do hub para um local (CEP ou "lat,lon"), idioma e opções; SSE e WebSocket
+// usam a mesma chave e compartilham a consulta
+func streamKey(location string, lang i18n.Lang, query url.Values) string {
+	return location + "|" + string(lang) + "|" + query.Encode()
+}
+
+// writeEvent - escreve um evento SSE e envia de imediato; o prazo de escrita é renovado a cada
+// evento, já que a conexão fica aberta além do WriteTimeout do servidor
+func writeEvent(rc *http.ResponseController, w http.ResponseWriter, event string, id string, data []byte) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		return err
+	}
+
+	var err error
+	if event == "" {
+		// apenas comentário (heartbeat)
+		_, err = fmt.Fprintf(w, ": %s\n\n", data)
+	} else {
+		_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	return rc.Flush()
+}
+
+// GetWeatherStream - leituras de clima do CEP via Server-Sent Events (GET /v1/weather/cep/{cep}/stream).
+// Os assinantes de um mesmo CEP, idioma e unidades compartilham uma única consulta por intervalo, e
+// só leituras diferentes da anterior são enviadas.
+func (wh *Handler) GetWeatherStream(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx := r.Context()
+	ctx, spanValidate := tracer.Start(ctx, "validate_stream")
+
+	spanValidate.AddEvent("sanitize zipcode", trace.WithAttributes(attribute.String("zipcode", r.PathValue("cep"))))
+	CEP, ok := sanitizeCEP(r.PathValue("cep"))
+	if !ok {
+		spanValidate.RecordError(apperror.ErrInvalidCEP)
+		spanValidate.SetStatus(codes.Error, "error on check validate zipcode")
+		writeProblem(ctx, w, r, lang, apperror.ErrInvalidCEP, i18n.ErrInvalidZipcode)
+		spanValidate.End()
+		return
+	}
+
+	unitOptions, err := unitsFromRequest(r)
+	if err != nil {
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on units options")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "opções de unidade inválidas", err), i18n.ErrInvalidUnits)
+		spanValidate.End()
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// zera o prazo herdado do WriteTimeout; cada evento define o próprio em writeEvent
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		spanValidate.RecordError(err)
+		spanValidate.SetStatus(codes.Error, "error on streaming support")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInternal, "streaming não suportado", err), i18n.ErrEncodeResponse)
+		spanValidate.End()
+		return
+	}
+	spanValidate.End()
+
+	input := dto.WeatherByCEPInput{
+		CEP:   CEP,
+		Query: serviceBQuery(r, unitOptions),
+		Lang:  string(lang),
+	}
+	key := streamKey(CEP, lang, input.Query)
+	fetch := func(pollCtx context.Context) (dto.WeatherOutput, error) {
+		return wh.GetWeatherByZipcode.Execute(pollCtx, input)
+	}
+
+	ctx, spanStream := tracer.Start(ctx, "weather_stream", trace.WithAttributes(attribute.String("stream.key", key)))
+	defer spanStream.End()
+
+	updates, unsubscribe := wh.WeatherStream.Subscribe(ctx, key, fetch)
+	defer unsubscribe()
+	spanStream.AddEvent("subscribed", trace.WithAttributes(attribute.Int("subscribers", wh.WeatherStream.Subscribers(key))))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	retry := []byte(fmt.Sprintf("retry: %d\n\n", wh.WeatherStream.Interval().Milliseconds()))
+	if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err == nil {
+		w.Write(retry)
+		rc.Flush()
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	lastID := ""
+	for {
+		select {
+		case <-r.Context().Done():
+			spanStream.AddEvent("client disconnected")
+			return
+
+		case <-heartbeat.C:
+			if err := writeEvent(rc, w, "", "", []byte("ping")); err != nil {
+				spanStream.RecordError(err)
+				spanStream.SetStatus(codes.Error, "error on write heartbeat")
+				return
+			}
+
+		case update := <-updates:
+			event := "weather"
+			data, err := json.Marshal(update.Value)
+			if update.Err != nil {
+				event = "error"
+				data, err = json.Marshal(newProblem(ctx, r, lang, update.Err, weatherByCEPDetail(lang, update.Err)))
+			}
+			if err != nil {
+				spanStream.RecordError(err)
+				spanStream.SetStatus(codes.Error, "error on encode event")
+				return
+			}
+
+			id := strings.Trim(etagOf(data), `"`)
+			if id == lastID {
+				continue
+			}
+			lastID = id
+
+			if err := writeEvent(rc, w, event, id, data); err != nil {
+				spanStream.RecordError(err)
+				spanStream.SetStatus(codes.Error, "error on write event")
+				return
+			}
+			spanStream.AddEvent(
+				"event sent",
+				trace.WithAttributes(
+					attribute.String("event", event),
+					attribute.String("id", id),
+					attribute.String("poll.trace_id", update.SpanContext.TraceID().String()),
+				),
+			)
+
+			if update.Err != nil && !transientError(update.Err) {
+				spanStream.RecordError(update.Err)
+				spanStream.SetAttributes(semconv.ErrorTypeKey.String(string(apperror.CodeOf(update.Err))))
+				spanStream.SetStatus(codes.Error, "stream closed")
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers que identificam o cliente da requisição
+const (
+	headerAPIKey   = "X-API-Key"
+	headerTenantID = "X-Tenant-ID"
+	headerClientID = "X-Client-ID"
+)
+
+// Tenant - coloca no baggage o tenant da chave de API (X-API-Key, mapeada em apiKeys) e o cliente
+// do header X-Client-ID. O tenant do header X-Tenant-ID e o do baggage recebido só valem quando a
+// requisição vem de um dos trustedPeers, que é como o serviço B recebe o tenant do serviço A; de
+// outros endereços, qualquer um poderia se passar por outro tenant. O span do servidor já começou,
+// então recebe os atributos aqui; os demais os copiam do baggage.
+func Tenant(next http.Handler, apiKeys map[string]string, trustedPeers []netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+
+		trusted := trustedPeer(r.RemoteAddr, trustedPeers)
+		if trusted {
+			ctx = tenant.Sanitize(ctx)
+		} else {
+			ctx = tenant.Discard(ctx)
+		}
+
+		tenantID, known := apiKeys[r.Header.Get(headerAPIKey)]
+		if !known {
+			if r.Header.Get(headerAPIKey) != "" {
+				span.AddEvent("unknown api key")
+			}
+			if header := r.Header.Get(headerTenantID); header != "" {
+				if trusted {
+					tenantID = header
+				} else {
+					span.AddEvent("untrusted tenant header")
+				}
+			}
+		}
+		clientID := r.Header.Get(headerClientID)
+
+		if !tenant.Valid(tenantID) {
+			tenantID = ""
+		}
+		if !tenant.Valid(clientID) {
+			clientID = ""
+		}
+
+		ctx, err := tenant.NewContext(ctx, tenantID, clientID)
+		if err != nil {
+			span.RecordError(err)
+		}
+
+		var attributes []attribute.KeyValue
+		if value := tenant.FromContext(ctx); value != "" {
+			attributes = append(attributes, attribute.String(tenant.KeyTenant, value))
+		}
+		if value := tenant.ClientFromContext(ctx); value != "" {
+			attributes = append(attributes, attribute.String(tenant.KeyClient, value))
+		}
+		span.SetAttributes(attributes...)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// trustedPeer - indica se o endereço remoto da requisição está em uma das faixas
+func trustedPeer(remoteAddr string, trustedPeers []netip.Prefix) bool {
+	if len(trustedPeers) == 0 {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trustedPeers {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
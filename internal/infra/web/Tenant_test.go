@@ -0,0 +1,91 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/tenant"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestTenantTrustedPeers(t *testing.T) {
+	apiKeys := map[string]string{"abc123": "acme"}
+	trustedPeers := []netip.Prefix{netip.MustParsePrefix("172.28.0.10/32")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		tenant     string
+		client     string
+	}{
+		{
+			name:       "chave de API de qualquer endereço",
+			remoteAddr: "203.0.113.7:41000",
+			header:     http.Header{"X-Api-Key": {"abc123"}, "X-Tenant-Id": {"globex"}},
+			tenant:     "acme",
+		},
+		{
+			name:       "X-Tenant-ID de par confiável",
+			remoteAddr: "172.28.0.10:41000",
+			header:     http.Header{"X-Tenant-Id": {"globex"}},
+			tenant:     "globex",
+		},
+		{
+			name:       "X-Tenant-ID de outro endereço",
+			remoteAddr: "203.0.113.7:41000",
+			header:     http.Header{"X-Tenant-Id": {"globex"}},
+		},
+		{
+			name:       "baggage de par confiável",
+			remoteAddr: "172.28.0.10:41000",
+			header:     http.Header{"Baggage": {"tenant.id=globex,client.id=app-mobile"}},
+			tenant:     "globex",
+			client:     "app-mobile",
+		},
+		{
+			name:       "baggage de outro endereço",
+			remoteAddr: "[2001:db8::1]:41000",
+			header:     http.Header{"Baggage": {"tenant.id=globex,client.id=app-mobile"}},
+		},
+		{
+			name:       "X-Client-ID de qualquer endereço",
+			remoteAddr: "203.0.113.7:41000",
+			header:     http.Header{"X-Client-Id": {"app-mobile"}},
+			client:     "app-mobile",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tenantID, clientID string
+			handler := Tenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenantID, clientID = tenant.FromContext(r.Context()), tenant.ClientFromContext(r.Context())
+			}), apiKeys, trustedPeers)
+
+			r := httptest.NewRequest(http.MethodPost, "/cep", nil)
+			r.RemoteAddr = test.remoteAddr
+			for key, values := range test.header {
+				r.Header[key] = values
+			}
+			// o baggage recebido, como o propagador do otelhttp o extrai
+			if value := r.Header.Get("Baggage"); value != "" {
+				bag, err := baggage.Parse(value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = r.WithContext(baggage.ContextWithBaggage(r.Context(), bag))
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tenantID != test.tenant {
+				t.Errorf("tenant = %q, esperado %q", tenantID, test.tenant)
+			}
+			if clientID != test.client {
+				t.Errorf("cliente = %q, esperado %q", clientID, test.client)
+			}
+		})
+	}
+}
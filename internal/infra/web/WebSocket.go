@@ -0,0 +1,416 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"github.com/nagahshi/pos_go_weather_otel/internal/stream"
+	"github.com/nagahshi/pos_go_weather_otel/internal/units"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultWebSocketMaxSubscriptions - assinaturas simultâneas por conexão, salvo WS_MAX_SUBSCRIPTIONS
+	DefaultWebSocketMaxSubscriptions = 20
+
+	// wsMaxMessageSize - tamanho máximo de uma mensagem do cliente
+	wsMaxMessageSize = 4096
+	// wsSendBuffer - mensagens aguardando envio por conexão; um cliente que não acompanha é desconectado
+	wsSendBuffer = 32
+	// wsWriteWait - prazo de cada escrita
+	wsWriteWait = 10 * time.Second
+	// wsPongWait e wsPingPeriod - o servidor envia ping e espera o pong para detectar conexões mortas
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+// tipos das mensagens do protocolo
+const (
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsWeather      = "weather"
+	wsAlert        = "alert"
+	wsError        = "error"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsClientMessage - mensagem do cliente: assina um CEP ou coordenadas, ou cancela uma assinatura pelo id
+type wsClientMessage struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	CEP       string `json:"cep,omitempty"`
+	Latitude  string `json:"latitude,omitempty"`
+	Longitude string `json:"longitude,omitempty"`
+
+	// mesmas opções da query string das rotas REST
+	Preset     string `json:"preset,omitempty"`
+	Units      string `json:"units,omitempty"`
+	Precision  string `json:"precision,omitempty"`
+	Rounding   string `json:"rounding,omitempty"`
+	AirQuality bool   `json:"aqi,omitempty"`
+
+	// contexto W3C do cliente; quando informado, a assinatura é filha do span do cliente
+	Traceparent string `json:"traceparent,omitempty"`
+	Tracestate  string `json:"tracestate,omitempty"`
+}
+
+// wsServerMessage - mensagem do servidor; TraceID é o trace da consulta que produziu a leitura
+// (weather e alert) ou o trace da própria assinatura (subscribed, unsubscribed e error)
+type wsServerMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Data    any    `json:"data,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+type wsSubscription struct {
+	cancel context.CancelFunc
+	span   trace.Span
+}
+
+// wsConnection - estado de uma conexão: fila de envio e assinaturas ativas
+type wsConnection struct {
+	conn *websocket.Conn
+	send chan wsServerMessage
+	done chan struct{}
+
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+
+	mu            sync.Mutex
+	subscriptions map[string]*wsSubscription
+}
+
+// close - encerra a conexão uma única vez, com o código informado ao cliente
+func (c *wsConnection) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// enqueue - coloca a mensagem na fila sem bloquear; com a fila cheia o cliente não está acompanhando
+// e a conexão é encerrada, para não acumular memória nem atrasar as demais assinaturas
+func (c *wsConnection) enqueue(message wsServerMessage) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+		c.close(websocket.ClosePolicyViolation, "slow consumer")
+		return false
+	}
+}
+
+// writeLoop - única rotina que escreve na conexão: mensagens da fila, pings e o fechamento
+func (c *wsConnection) writeLoop() {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+	defer c.conn.Close()
+
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(message); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+
+		case <-c.done:
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				c.conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(c.closeCode, c.closeReason),
+					time.Now().Add(wsWriteWait),
+				)
+			}
+			return
+		}
+	}
+}
+
+// GetWeatherWebSocket - assinaturas de clima por CEP ou coordenadas via WebSocket (GET /v1/weather/ws).
+// Cada assinatura recebe leituras (weather) e mudanças nos alertas (alert) como JSON; assinaturas do
+// mesmo local, idioma e unidades compartilham a consulta com as demais conexões e com o SSE.
+func (wh *Handler) GetWeatherWebSocket(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+
+	ctx := r.Context()
+
+	conn, err := wsUpgrader.Upgrade(w, r, http.Header{"Content-Language": []string{string(lang)}})
+	if err != nil {
+		// o upgrader já respondeu o erro ao cliente
+		trace.SpanFromContext(ctx).RecordError(err)
+		trace.SpanFromContext(ctx).SetStatus(codes.Error, "error on upgrade")
+		return
+	}
+
+	ctx, spanConnection := tracer.Start(ctx, "weather_websocket")
+	defer spanConnection.End()
+
+	c := &wsConnection{
+		conn:          conn,
+		send:          make(chan wsServerMessage, wsSendBuffer),
+		done:          make(chan struct{}),
+		subscriptions: map[string]*wsSubscription{},
+	}
+	go c.writeLoop()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// a leitura bloqueia; o fechamento iniciado pelo servidor (fila cheia) desbloqueia pelo Close do writeLoop
+	for {
+		message := wsClientMessage{}
+		if err := conn.ReadJSON(&message); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				wh.wsSendError(ctx, r, c, lang, "", apperror.Wrap(apperror.CodeInvalidRequest, "mensagem inválida", err), i18n.ErrInvalidMessage)
+				continue
+			}
+
+			spanConnection.AddEvent("connection closed", trace.WithAttributes(attribute.String("reason", err.Error())))
+			break
+		}
+
+		switch message.Type {
+		case wsSubscribe:
+			wh.wsSubscribe(ctx, r, c, lang, message)
+		case wsUnsubscribe:
+			wh.wsUnsubscribe(ctx, r, c, lang, message.ID)
+		default:
+			wh.wsSendError(ctx, r, c, lang, message.ID, apperror.New(apperror.CodeInvalidRequest, "tipo de mensagem desconhecido: "+message.Type), i18n.ErrInvalidMessage)
+		}
+	}
+
+	c.mu.Lock()
+	spanConnection.SetAttributes(attribute.Int("websocket.subscriptions", len(c.subscriptions)))
+	for id, subscription := range c.subscriptions {
+		subscription.cancel()
+		subscription.span.End()
+		delete(c.subscriptions, id)
+	}
+	c.mu.Unlock()
+
+	c.close(websocket.CloseNormalClosure, "")
+}
+
+// traceIDOf - trace ID do span, vazio quando não há trace (ex.: sem SDK configurado)
+func traceIDOf(spanContext trace.SpanContext) string {
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
+
+// wsSendError - envia um problem como mensagem de erro da assinatura id
+func (wh *Handler) wsSendError(ctx context.Context, r *http.Request, c *wsConnection, lang i18n.Lang, id string, err error, detailKey string) {
+	// a mensagem inválida é do cliente e a conexão segue aberta: o erro fica no span, sem marcá-lo como falha
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(attribute.String("id", id)))
+	span.AddEvent("invalid message", trace.WithAttributes(attribute.String("id", id), semconv.ErrorTypeKey.String(string(apperror.CodeOf(err)))))
+	problem := newProblem(ctx, r, lang, err, i18n.T(lang, detailKey))
+	c.enqueue(wsServerMessage{Type: wsError, ID: id, Data: problem, TraceID: problem.TraceID})
+}
+
+// wsSubscribe - valida a assinatura, abre o span dela e passa a repassar as leituras do hub
+func (wh *Handler) wsSubscribe(ctx context.Context, r *http.Request, c *wsConnection, lang i18n.Lang, message wsClientMessage) {
+	if strings.TrimSpace(message.ID) == "" {
+		wh.wsSendError(ctx, r, c, lang, "", apperror.New(apperror.CodeInvalidRequest, "assinatura sem id"), i18n.ErrInvalidMessage)
+		return
+	}
+
+	unitOptions, err := units.Parse(message.Preset, message.Units, message.Precision, message.Rounding)
+	if err != nil {
+		wh.wsSendError(ctx, r, c, lang, message.ID, apperror.Wrap(apperror.CodeInvalidRequest, "opções de unidade inválidas", err), i18n.ErrInvalidUnits)
+		return
+	}
+	query := unitOptions.Query()
+	if message.AirQuality {
+		query.Set("aqi", "yes")
+	}
+
+	var location string
+	var fetch stream.FetchFunc[dto.WeatherOutput]
+	switch {
+	case message.CEP != "":
+		CEP, ok := sanitizeCEP(message.CEP)
+		if !ok {
+			wh.wsSendError(ctx, r, c, lang, message.ID, apperror.ErrInvalidCEP, i18n.ErrInvalidZipcode)
+			return
+		}
+		location = CEP
+		input := dto.WeatherByCEPInput{CEP: CEP, Query: query, Lang: string(lang)}
+		fetch = func(pollCtx context.Context) (dto.WeatherOutput, error) {
+			return wh.GetWeatherByZipcode.Execute(pollCtx, input)
+		}
+
+	default:
+		latitude, longitude := strings.TrimSpace(message.Latitude), strings.TrimSpace(message.Longitude)
+		if err := validateCoordinates(latitude, longitude); err != nil {
+			wh.wsSendError(ctx, r, c, lang, message.ID, apperror.Wrap(apperror.CodeInvalidRequest, "localização inválida", err), i18n.ErrInvalidCoordinates)
+			return
+		}
+		location = latitude + "," + longitude
+		input := dto.WeatherByCoordinatesInput{Latitude: latitude, Longitude: longitude, Query: query, Lang: string(lang)}
+		fetch = func(pollCtx context.Context) (dto.WeatherOutput, error) {
+			return wh.GetWeatherByLatLon.Execute(pollCtx, input)
+		}
+	}
+
+	// o span da assinatura é filho do contexto enviado pelo cliente, quando houver, e sempre
+	// referencia a conexão
+	parent := ctx
+	if message.Traceparent != "" {
+		carrier := propagation.MapCarrier{"traceparent": message.Traceparent, "tracestate": message.Tracestate}
+		parent = propagation.TraceContext{}.Extract(context.Background(), carrier)
+	}
+
+	c.mu.Lock()
+	if _, exists := c.subscriptions[message.ID]; exists {
+		c.mu.Unlock()
+		wh.wsSendError(ctx, r, c, lang, message.ID, apperror.New(apperror.CodeInvalidRequest, "id de assinatura em uso"), i18n.ErrSubscriptionExists)
+		return
+	}
+	if len(c.subscriptions) >= wh.WebSocketMaxSubscriptions {
+		c.mu.Unlock()
+		wh.wsSendError(ctx, r, c, lang, message.ID, apperror.New(apperror.CodeInvalidRequest, "limite de assinaturas da conexão atingido"), i18n.ErrSubscriptionLimit)
+		return
+	}
+
+	key := streamKey(location, lang, query)
+	subscriptionCtx, spanSubscription := tracer.Start(
+		parent,
+		"weather_subscription",
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(attribute.String("subscription.id", message.ID), attribute.String("stream.key", key)),
+	)
+	subscriptionCtx, cancel := context.WithCancel(subscriptionCtx)
+	c.subscriptions[message.ID] = &wsSubscription{cancel: cancel, span: spanSubscription}
+	c.mu.Unlock()
+
+	updates, unsubscribe := wh.WeatherStream.Subscribe(subscriptionCtx, key, fetch)
+	subscriptionTraceID := traceIDOf(spanSubscription.SpanContext())
+	c.enqueue(wsServerMessage{Type: wsSubscribed, ID: message.ID, TraceID: subscriptionTraceID})
+
+	go wh.wsForward(subscriptionCtx, r, c, lang, message.ID, updates, unsubscribe)
+}
+
+// wsForward - repassa as leituras do hub para a conexão até a assinatura ser cancelada; cada envio é
+// um span filho da assinatura com link para a consulta que produziu a leitura
+func (wh *Handler) wsForward(ctx context.Context, r *http.Request, c *wsConnection, lang i18n.Lang, id string, updates <-chan stream.Update[dto.WeatherOutput], unsubscribe func()) {
+	defer unsubscribe()
+
+	lastWeather, lastAlerts := "", ""
+
+	for {
+		var update stream.Update[dto.WeatherOutput]
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case update = <-updates:
+		}
+
+		pollTraceID := traceIDOf(update.SpanContext)
+		_, spanUpdate := tracer.Start(
+			ctx,
+			"weather_subscription_update",
+			trace.WithLinks(trace.Link{SpanContext: update.SpanContext}),
+			trace.WithAttributes(attribute.String("subscription.id", id), attribute.String("poll.trace_id", pollTraceID)),
+		)
+
+		if update.Err != nil {
+			spanUpdate.RecordError(update.Err)
+			spanUpdate.SetStatus(codes.Error, "error on fetch")
+			problem := newProblem(ctx, r, lang, update.Err, weatherByCEPDetail(lang, update.Err))
+			c.enqueue(wsServerMessage{Type: wsError, ID: id, Data: problem, TraceID: pollTraceID})
+			spanUpdate.End()
+
+			if !transientError(update.Err) {
+				// erro definitivo (ex.: CEP inexistente): a assinatura é encerrada
+				wh.wsUnsubscribe(ctx, r, c, lang, id)
+				return
+			}
+			continue
+		}
+
+		payload, _ := json.Marshal(update.Value)
+		if weather := etagOf(payload); weather != lastWeather {
+			lastWeather = weather
+			c.enqueue(wsServerMessage{Type: wsWeather, ID: id, Data: update.Value, TraceID: pollTraceID})
+			spanUpdate.AddEvent("weather sent")
+		}
+
+		alerts := localizedAlerts(lang, update.Value)
+		signature, _ := json.Marshal(alerts)
+		// a primeira leitura só gera mensagem se houver alerta; depois, qualquer mudança é enviada,
+		// inclusive a lista vazia quando os alertas cessam
+		if string(signature) != lastAlerts && (lastAlerts != "" || len(alerts) > 0) {
+			if alerts == nil {
+				alerts = []dto.Alert{}
+			}
+			c.enqueue(wsServerMessage{Type: wsAlert, ID: id, Data: alerts, TraceID: pollTraceID})
+			spanUpdate.AddEvent("alerts sent", trace.WithAttributes(attribute.Int("alerts", len(alerts))))
+		}
+		lastAlerts = string(signature)
+
+		spanUpdate.End()
+	}
+}
+
+// wsUnsubscribe - cancela a assinatura id e confirma ao cliente
+func (wh *Handler) wsUnsubscribe(ctx context.Context, r *http.Request, c *wsConnection, lang i18n.Lang, id string) {
+	c.mu.Lock()
+	subscription, ok := c.subscriptions[id]
+	delete(c.subscriptions, id)
+	c.mu.Unlock()
+
+	if !ok {
+		wh.wsSendError(ctx, r, c, lang, id, apperror.New(apperror.CodeInvalidRequest, "assinatura não encontrada"), i18n.ErrSubscriptionNotFound)
+		return
+	}
+
+	subscription.cancel()
+	subscription.span.End()
+	c.enqueue(wsServerMessage{Type: wsUnsubscribed, ID: id, TraceID: traceIDOf(subscription.span.SpanContext())})
+}
@@ -0,0 +1,252 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"github.com/nagahshi/pos_go_weather_otel/internal/tenant"
+	"github.com/nagahshi/pos_go_weather_otel/internal/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// webhookMaxBody - tamanho máximo do corpo de uma assinatura de webhook
+const webhookMaxBody = 16 << 10
+
+// WebhookRequest - corpo da assinatura de webhook (POST /v1/webhooks); sem secret, um é gerado
+type WebhookRequest struct {
+	CEP         string `json:"cep"`
+	Condition   string `json:"condition"`
+	CallbackURL string `json:"callback_url"`
+	Secret      string `json:"secret,omitempty"`
+}
+
+// WebhookSubscriptionCreated - assinatura recém-criada; é a única resposta que devolve o secret
+type WebhookSubscriptionCreated struct {
+	ID          string `json:"id"`
+	CEP         string `json:"cep"`
+	Condition   string `json:"condition"`
+	CallbackURL string `json:"callback_url"`
+	CreatedAt   string `json:"created_at"`
+	Secret      string `json:"secret"`
+}
+
+// webhookProblemKey - mensagem do catálogo para os erros do scheduler
+func webhookProblemKey(err error) string {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidCondition):
+		return i18n.ErrInvalidCondition
+	case errors.Is(err, webhook.ErrInvalidCallbackURL):
+		return i18n.ErrInvalidCallbackURL
+	case errors.Is(err, webhook.ErrInvalidSecret):
+		return i18n.ErrInvalidWebhookSecret
+	case errors.Is(err, webhook.ErrSubscriptionLimit):
+		return i18n.ErrWebhookLimit
+	case errors.Is(err, webhook.ErrSubscriptionNotFound):
+		return i18n.ErrWebhookNotFound
+	case errors.Is(err, webhook.ErrDeadLetterNotFound):
+		return i18n.ErrDeadLetterNotFound
+	}
+
+	return i18n.ErrEncodeResponse
+}
+
+// webhookOwner - tenant dono das assinaturas da requisição (ver Tenant). Sem tenant a requisição é
+// recusada com 401: clientes anônimos enxergariam e cancelariam as assinaturas uns dos outros.
+func webhookOwner(ctx context.Context, w http.ResponseWriter, r *http.Request, lang i18n.Lang) (string, bool) {
+	owner := tenant.FromContext(ctx)
+	if owner == "" {
+		trace.SpanFromContext(ctx).AddEvent("webhook without tenant")
+		w.Header().Set("WWW-Authenticate", `APIKey header="`+headerAPIKey+`"`)
+		writeProblem(ctx, w, r, lang, apperror.New(apperror.CodeUnauthorized, "webhook sem tenant"), i18n.ErrWebhookTenant)
+		return "", false
+	}
+
+	return owner, true
+}
+
+// writeWebhookJSON - responde o corpo em JSON com o status informado; os operadores das condições
+// (>, <) saem sem escape HTML
+func writeWebhookJSON(w http.ResponseWriter, r *http.Request, lang i18n.Lang, status int, body any) {
+	payload := bytes.Buffer{}
+	encoder := json.NewEncoder(&payload)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		writeProblem(r.Context(), w, r, lang, apperror.Wrap(apperror.CodeInternal, "falha ao montar resposta", err), i18n.ErrEncodeResponse)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(payload.Bytes())
+}
+
+// PostWebhook - assina notificações de uma condição de clima do CEP (POST /v1/webhooks). A assinatura
+// e as dead letters dela ficam com o tenant da requisição (ver Tenant), o único que as enxerga nas
+// demais rotas; as requisições sem tenant recebem 401 em todas as rotas de webhook.
+func (wh *Handler) PostWebhook(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx := r.Context()
+	ctx, spanSubscribe := tracer.Start(ctx, "webhook_subscribe")
+	defer spanSubscribe.End()
+
+	owner, ok := webhookOwner(ctx, w, r, lang)
+	if !ok {
+		return
+	}
+
+	request := WebhookRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBody)).Decode(&request); err != nil {
+		spanSubscribe.RecordError(err)
+		spanSubscribe.SetStatus(codes.Error, "error on decode body")
+		writeProblem(ctx, w, r, lang, apperror.Wrap(apperror.CodeInvalidRequest, "corpo da requisição inválido", err), i18n.ErrDecodeWebhook)
+		return
+	}
+
+	CEP, ok := sanitizeCEP(request.CEP)
+	if !ok {
+		spanSubscribe.AddEvent("invalid zipcode", trace.WithAttributes(attribute.String("zipcode", request.CEP)))
+		writeProblem(ctx, w, r, lang, apperror.New(apperror.CodeInvalidCEP, "CEP inválido: "+request.CEP), i18n.ErrInvalidZipcode)
+		return
+	}
+
+	subscription, err := wh.Webhooks.Subscribe(ctx, owner, CEP, request.Condition, request.CallbackURL, request.Secret)
+	if err != nil {
+		spanSubscribe.RecordError(err)
+		spanSubscribe.SetStatus(codes.Error, "error on subscribe")
+		writeProblem(ctx, w, r, lang, err, webhookProblemKey(err))
+		return
+	}
+
+	spanSubscribe.AddEvent(
+		"subscribe success",
+		trace.WithAttributes(
+			attribute.String("webhook.subscription_id", subscription.ID),
+			attribute.String("webhook.condition", subscription.Condition),
+		),
+	)
+	w.Header().Set("Location", "/v1/webhooks/"+subscription.ID)
+	writeWebhookJSON(w, r, lang, http.StatusCreated, WebhookSubscriptionCreated{
+		ID:          subscription.ID,
+		CEP:         subscription.CEP,
+		Condition:   subscription.Condition,
+		CallbackURL: subscription.CallbackURL,
+		CreatedAt:   subscription.CreatedAt,
+		Secret:      subscription.Secret,
+	})
+}
+
+// GetWebhooks - assinaturas de webhook em ordem de criação, sem o secret (GET /v1/webhooks)
+func (wh *Handler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	owner, ok := webhookOwner(r.Context(), w, r, lang)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := wh.Webhooks.Subscriptions(owner)
+	if err != nil {
+		writeProblem(r.Context(), w, r, lang, err, webhookProblemKey(err))
+		return
+	}
+
+	writeWebhookJSON(w, r, lang, http.StatusOK, subscriptions)
+}
+
+// GetWebhook - assinatura de webhook pelo id, sem o secret (GET /v1/webhooks/{id})
+func (wh *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	owner, ok := webhookOwner(r.Context(), w, r, lang)
+	if !ok {
+		return
+	}
+
+	subscription, err := wh.Webhooks.Subscription(owner, r.PathValue("id"))
+	if err != nil {
+		writeProblem(r.Context(), w, r, lang, err, webhookProblemKey(err))
+		return
+	}
+
+	writeWebhookJSON(w, r, lang, http.StatusOK, subscription)
+}
+
+// DeleteWebhook - cancela a assinatura de webhook (DELETE /v1/webhooks/{id})
+func (wh *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx, spanUnsubscribe := tracer.Start(r.Context(), "webhook_unsubscribe")
+	defer spanUnsubscribe.End()
+
+	owner, ok := webhookOwner(ctx, w, r, lang)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	spanUnsubscribe.SetAttributes(attribute.String("webhook.subscription_id", id))
+	if err := wh.Webhooks.Unsubscribe(owner, id); err != nil {
+		spanUnsubscribe.RecordError(err)
+		spanUnsubscribe.SetStatus(codes.Error, "error on unsubscribe")
+		writeProblem(ctx, w, r, lang, err, webhookProblemKey(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeadLetters - entregas que esgotaram as tentativas (GET /v1/webhooks/dead-letters)
+func (wh *Handler) GetWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	owner, ok := webhookOwner(r.Context(), w, r, lang)
+	if !ok {
+		return
+	}
+
+	deadLetters, err := wh.Webhooks.DeadLetters(owner)
+	if err != nil {
+		writeProblem(r.Context(), w, r, lang, err, webhookProblemKey(err))
+		return
+	}
+
+	writeWebhookJSON(w, r, lang, http.StatusOK, deadLetters)
+}
+
+// PostWebhookDeadLetterRetry - reenvia uma entrega da dead letter (POST /v1/webhooks/dead-letters/{id}/retry)
+func (wh *Handler) PostWebhookDeadLetterRetry(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+
+	ctx, spanRetry := tracer.Start(r.Context(), "webhook_redeliver")
+	defer spanRetry.End()
+
+	owner, ok := webhookOwner(ctx, w, r, lang)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	spanRetry.SetAttributes(attribute.String("webhook.delivery_id", id))
+	if err := wh.Webhooks.Redeliver(ctx, owner, id); err != nil {
+		spanRetry.RecordError(err)
+		spanRetry.SetStatus(codes.Error, "error on redeliver")
+		writeProblem(ctx, w, r, lang, err, webhookProblemKey(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
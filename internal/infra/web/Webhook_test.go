@@ -0,0 +1,122 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/webhook"
+)
+
+// newWebhookTestServer - rotas de webhook atrás do middleware Tenant, com as chaves de acme e globex
+func newWebhookTestServer(t *testing.T) http.Handler {
+	t.Helper()
+
+	store, err := webhook.Open(filepath.Join(t.TempDir(), "webhooks.db"), 2)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	handler := &Handler{
+		Webhooks: webhook.NewScheduler(store, nil, webhook.NewDispatcher(store, 1, time.Millisecond, false), time.Minute),
+	}
+	mux := http.NewServeMux()
+	for _, route := range handler.Routes() {
+		if strings.Contains(route.Pattern, "/v1/webhooks") {
+			mux.HandleFunc(route.Pattern, route.Handler)
+		}
+	}
+
+	return Tenant(mux, map[string]string{"key-acme": "acme", "key-globex": "globex"}, nil)
+}
+
+// serveWebhook - requisição às rotas de webhook com a chave de API informada
+func serveWebhook(server http.Handler, method string, path string, apiKey string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.RemoteAddr = "203.0.113.7:41000"
+	if apiKey != "" {
+		request.Header.Set(headerAPIKey, apiKey)
+	}
+	// o X-Tenant-ID de um par não confiável é descartado, então não identifica o cliente
+	request.Header.Set(headerTenantID, "acme")
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestWebhookRequiresTenant(t *testing.T) {
+	server := newWebhookTestServer(t)
+	body := `{"cep":"87033080","condition":"temp_C > 35","callback_url":"https://93.184.215.14/hooks"}`
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/v1/webhooks", body},
+		{http.MethodGet, "/v1/webhooks", ""},
+		{http.MethodGet, "/v1/webhooks/abc", ""},
+		{http.MethodDelete, "/v1/webhooks/abc", ""},
+		{http.MethodGet, "/v1/webhooks/dead-letters", ""},
+		{http.MethodPost, "/v1/webhooks/dead-letters/abc/retry", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			for _, apiKey := range []string{"", "unknown"} {
+				response := serveWebhook(server, test.method, test.path, apiKey, test.body)
+
+				if response.Code != http.StatusUnauthorized {
+					t.Fatalf("chave %q: status = %d, esperado 401: %s", apiKey, response.Code, response.Body)
+				}
+				if response.Header().Get("WWW-Authenticate") == "" {
+					t.Errorf("chave %q: 401 sem WWW-Authenticate", apiKey)
+				}
+				problem := map[string]any{}
+				if err := json.Unmarshal(response.Body.Bytes(), &problem); err != nil {
+					t.Fatal(err)
+				}
+				if problem["code"] != "UNAUTHORIZED" {
+					t.Errorf("chave %q: code = %v, esperado UNAUTHORIZED", apiKey, problem["code"])
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookTenantIsolation(t *testing.T) {
+	server := newWebhookTestServer(t)
+
+	created := serveWebhook(server, http.MethodPost, "/v1/webhooks", "key-acme",
+		`{"cep":"87033080","condition":"temp_C > 35","callback_url":"https://93.184.215.14/hooks"}`)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("assinatura de acme: status = %d: %s", created.Code, created.Body)
+	}
+	subscription := WebhookSubscriptionCreated{}
+	if err := json.Unmarshal(created.Body.Bytes(), &subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	if response := serveWebhook(server, http.MethodGet, "/v1/webhooks/"+subscription.ID, "key-acme", ""); response.Code != http.StatusOK {
+		t.Errorf("acme consultando a própria assinatura: status = %d", response.Code)
+	}
+	if response := serveWebhook(server, http.MethodGet, "/v1/webhooks/"+subscription.ID, "key-globex", ""); response.Code != http.StatusNotFound {
+		t.Errorf("globex consultando a assinatura de acme: status = %d, esperado 404", response.Code)
+	}
+	if response := serveWebhook(server, http.MethodGet, "/v1/webhooks", "key-globex", ""); strings.TrimSpace(response.Body.String()) != "[]" {
+		t.Errorf("assinaturas de globex = %s, esperado nenhuma", response.Body)
+	}
+	if response := serveWebhook(server, http.MethodDelete, "/v1/webhooks/"+subscription.ID, "key-globex", ""); response.Code != http.StatusNotFound {
+		t.Errorf("globex cancelando a assinatura de acme: status = %d, esperado 404", response.Code)
+	}
+	if response := serveWebhook(server, http.MethodDelete, "/v1/webhooks/"+subscription.ID, "key-acme", ""); response.Code != http.StatusNoContent {
+		t.Errorf("acme cancelando a própria assinatura: status = %d, esperado 204", response.Code)
+	}
+}
@@ -0,0 +1,218 @@
+// Package logging liga o log/slog aos logs do OpenTelemetry: cada registro vai para a saída padrão,
+// com trace_id e span_id do span em andamento, e para o LoggerProvider global, que o envia ao
+// collector correlacionado com o trace.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler - slog.Handler que repassa o registro ao handler next, acrescido de trace_id e span_id, e
+// o emite como log do OpenTelemetry. Os grupos de WithGroup ficam com o Handler, e não com next, para
+// trace_id e span_id continuarem na raiz do registro.
+type Handler struct {
+	next   slog.Handler
+	logger otellog.Logger
+	// attrs - atributos de WithAttrs fora de grupos, já convertidos; em next eles já estão aplicados
+	attrs []otellog.KeyValue
+	// groups - grupos abertos por WithGroup, com os atributos recebidos dentro de cada um
+	groups []group
+}
+
+type group struct {
+	name  string
+	attrs []slog.Attr
+}
+
+// NewHandler - handler sobre next que também emite pelo logger do OpenTelemetry
+func NewHandler(next slog.Handler, logger otellog.Logger) *Handler {
+	return &Handler{
+		next:   next,
+		logger: logger,
+	}
+}
+
+// New - logger em JSON na saída w, a partir de level, que também emite pelo LoggerProvider global;
+// antes de otel.SetupOTelSDK configurar o provider, só a saída w recebe os registros
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(NewHandler(
+		slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}),
+		global.Logger("logging"),
+	))
+}
+
+// ParseLevel - nível pelo nome (debug, info, warn ou error); vazio é info
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if value == "" {
+		return slog.LevelInfo, nil
+	}
+
+	err := level.UnmarshalText([]byte(value))
+
+	return level, err
+}
+
+// Enabled - o nível mínimo é o do handler next
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle - emite o registro nos dois destinos; o SDK do OpenTelemetry lê o trace do ctx
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := h.nest(record)
+
+	otelRecord := otellog.Record{}
+	otelRecord.SetTimestamp(record.Time)
+	otelRecord.SetObservedTimestamp(time.Now())
+	otelRecord.SetSeverity(severity(record.Level))
+	otelRecord.SetSeverityText(record.Level.String())
+	otelRecord.SetBody(otellog.StringValue(record.Message))
+	otelRecord.AddAttributes(h.attrs...)
+	for _, attr := range attrs {
+		otelRecord.AddAttributes(convertAttr("", attr)...)
+	}
+	h.logger.Emit(ctx, otelRecord)
+
+	output := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		output.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	output.AddAttrs(attrs...)
+
+	return h.next.Handle(ctx, output)
+}
+
+// nest - atributos do registro dentro dos grupos abertos, do mais interno para o mais externo
+func (h *Handler) nest(record slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		members := make([]any, 0, len(h.groups[i].attrs)+len(attrs))
+		for _, attr := range h.groups[i].attrs {
+			members = append(members, attr)
+		}
+		for _, attr := range attrs {
+			members = append(members, attr)
+		}
+		attrs = []slog.Attr{slog.Group(h.groups[i].name, members...)}
+	}
+
+	return attrs
+}
+
+// WithAttrs - handler com atributos fixos; dentro de um grupo, eles ficam com o grupo
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	if len(h.groups) == 0 {
+		clone.next = h.next.WithAttrs(attrs)
+		clone.attrs = append([]otellog.KeyValue{}, h.attrs...)
+		for _, attr := range attrs {
+			clone.attrs = append(clone.attrs, convertAttr("", attr)...)
+		}
+
+		return &clone
+	}
+
+	clone.groups = append([]group{}, h.groups...)
+	last := &clone.groups[len(clone.groups)-1]
+	last.attrs = append(append([]slog.Attr{}, last.attrs...), attrs...)
+
+	return &clone
+}
+
+// WithGroup - handler em que os próximos atributos ficam sob o grupo name
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.groups = append(append([]group{}, h.groups...), group{name: name})
+
+	return &clone
+}
+
+// severity - níveis do slog na escala do OpenTelemetry: Debug(-4) em DEBUG, Info(0) em INFO, Warn(4)
+// em WARN e Error(8) em ERROR, com os intermediários entre eles
+func severity(level slog.Level) otellog.Severity {
+	value := int(level) + int(otellog.SeverityInfo)
+	if value < int(otellog.SeverityTrace1) {
+		return otellog.SeverityTrace1
+	}
+	if value > int(otellog.SeverityFatal4) {
+		return otellog.SeverityFatal4
+	}
+
+	return otellog.Severity(value)
+}
+
+// convertAttr - atributo do slog em atributos do OpenTelemetry; grupos viram chaves com prefixo
+func convertAttr(prefix string, attr slog.Attr) []otellog.KeyValue {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return nil
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix += attr.Key + "."
+		}
+
+		var kvs []otellog.KeyValue
+		for _, member := range attr.Value.Group() {
+			kvs = append(kvs, convertAttr(groupPrefix, member)...)
+		}
+
+		return kvs
+	}
+
+	return []otellog.KeyValue{{Key: prefix + attr.Key, Value: convertValue(attr.Value)}}
+}
+
+// convertValue - valor escalar do slog; durações, momentos e demais tipos viram texto
+func convertValue(value slog.Value) otellog.Value {
+	switch value.Kind() {
+	case slog.KindString:
+		return otellog.StringValue(value.String())
+	case slog.KindInt64:
+		return otellog.Int64Value(value.Int64())
+	case slog.KindUint64:
+		if value.Uint64() <= math.MaxInt64 {
+			return otellog.Int64Value(int64(value.Uint64()))
+		}
+		return otellog.StringValue(value.String())
+	case slog.KindFloat64:
+		return otellog.Float64Value(value.Float64())
+	case slog.KindBool:
+		return otellog.BoolValue(value.Bool())
+	case slog.KindTime:
+		return otellog.StringValue(value.Time().Format(time.RFC3339Nano))
+	case slog.KindDuration:
+		return otellog.StringValue(value.Duration().String())
+	}
+
+	if err, ok := value.Any().(error); ok {
+		return otellog.StringValue(err.Error())
+	}
+
+	return otellog.StringValue(strings.TrimSpace(fmt.Sprint(value.Any())))
+}
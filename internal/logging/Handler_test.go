@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/logtest"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextLogger - logger do OpenTelemetry que guarda o span de cada Emit, que o logtest descarta
+type contextLogger struct {
+	otellog.Logger
+	spans *[]trace.SpanContext
+}
+
+func (l contextLogger) Emit(ctx context.Context, record otellog.Record) {
+	*l.spans = append(*l.spans, trace.SpanContextFromContext(ctx))
+	l.Logger.Emit(ctx, record)
+}
+
+// testOutput - destinos do Handler nos testes: a saída JSON e os registros do OpenTelemetry
+type testOutput struct {
+	json     *bytes.Buffer
+	recorder *logtest.Recorder
+	spans    []trace.SpanContext
+}
+
+// newTestLogger - logger sobre o Handler, a partir de debug, com os dois destinos em memória
+func newTestLogger() (*slog.Logger, *testOutput) {
+	output := &testOutput{json: &bytes.Buffer{}, recorder: logtest.NewRecorder()}
+	handler := NewHandler(
+		slog.NewJSONHandler(output.json, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		contextLogger{Logger: output.recorder.Logger("test"), spans: &output.spans},
+	)
+
+	return slog.New(handler), output
+}
+
+// records - registros emitidos pelo OpenTelemetry
+func (o *testOutput) records() []otellog.Record {
+	var records []otellog.Record
+	for _, scope := range o.recorder.Result() {
+		records = append(records, scope.Records...)
+	}
+
+	return records
+}
+
+// lines - registros da saída JSON, um por linha
+func (o *testOutput) lines(t *testing.T) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(o.json.Bytes()))
+	for decoder.More() {
+		line := map[string]any{}
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("saída JSON inválida: %v: %s", err, o.json)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// attributes - atributos do registro pela chave
+func attributes(record otellog.Record) map[string]otellog.Value {
+	attrs := map[string]otellog.Value{}
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+
+	return attrs
+}
+
+func TestHandlerTraceContext(t *testing.T) {
+	logger, output := newTestLogger()
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	logger.InfoContext(ctx, "dentro do span")
+	span.End()
+	logger.InfoContext(context.Background(), "fora do span")
+
+	lines := output.lines(t)
+	if len(lines) != 2 {
+		t.Fatalf("%d linhas na saída, esperado 2: %s", len(lines), output.json)
+	}
+	spanContext := span.SpanContext()
+	if lines[0]["trace_id"] != spanContext.TraceID().String() || lines[0]["span_id"] != spanContext.SpanID().String() {
+		t.Errorf("trace_id = %v, span_id = %v, esperado %s e %s", lines[0]["trace_id"], lines[0]["span_id"], spanContext.TraceID(), spanContext.SpanID())
+	}
+	if _, ok := lines[1]["trace_id"]; ok {
+		t.Errorf("registro fora do span com trace_id: %v", lines[1])
+	}
+
+	// o SDK do OpenTelemetry lê o trace do ctx recebido pelo Emit
+	if len(output.spans) != 2 {
+		t.Fatalf("%d registros emitidos, esperado 2", len(output.spans))
+	}
+	if !output.spans[0].Equal(spanContext) {
+		t.Errorf("Emit com o span %v, esperado %v", output.spans[0], spanContext)
+	}
+	if output.spans[1].IsValid() {
+		t.Errorf("Emit fora do span com o span %v", output.spans[1])
+	}
+}
+
+func TestHandlerGroups(t *testing.T) {
+	logger, output := newTestLogger()
+
+	logger.With("service", "weather").
+		WithGroup("request").With("id", "abc").
+		WithGroup("location").Info("consulta", "latitude", -23.42, slog.Group("source", "name", "brasilapi"))
+
+	records := output.records()
+	if len(records) != 1 {
+		t.Fatalf("%d registros, esperado 1", len(records))
+	}
+	got := attributes(records[0])
+	want := map[string]otellog.Value{
+		"service":                      otellog.StringValue("weather"),
+		"request.id":                   otellog.StringValue("abc"),
+		"request.location.latitude":    otellog.Float64Value(-23.42),
+		"request.location.source.name": otellog.StringValue("brasilapi"),
+	}
+	if len(got) != len(want) {
+		t.Errorf("atributos = %v, esperado %v", got, want)
+	}
+	for key, value := range want {
+		if !got[key].Equal(value) {
+			t.Errorf("%s = %v, esperado %v", key, got[key], value)
+		}
+	}
+
+	// na saída JSON os grupos continuam aninhados, com trace_id e span_id na raiz
+	line := output.lines(t)[0]
+	request, _ := line["request"].(map[string]any)
+	location, _ := request["location"].(map[string]any)
+	if line["service"] != "weather" || request["id"] != "abc" || location["latitude"] != -23.42 {
+		t.Errorf("saída JSON = %s", output.json)
+	}
+}
+
+func TestHandlerSeverity(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  otellog.Severity
+	}{
+		{slog.LevelDebug, otellog.SeverityDebug},
+		{slog.LevelInfo, otellog.SeverityInfo},
+		{slog.LevelInfo + 2, otellog.SeverityInfo3},
+		{slog.LevelWarn, otellog.SeverityWarn},
+		{slog.LevelError, otellog.SeverityError},
+		{slog.LevelError + 4, otellog.SeverityFatal},
+		{slog.Level(-100), otellog.SeverityTrace1},
+		{slog.Level(100), otellog.SeverityFatal4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.level.String(), func(t *testing.T) {
+			logger, output := newTestLogger()
+			logger.Log(context.Background(), test.level, "registro")
+
+			records := output.records()
+			if test.level < slog.LevelDebug {
+				// abaixo do nível do handler, nenhum destino recebe o registro
+				if len(records) != 0 {
+					t.Errorf("%d registros abaixo de debug, esperado nenhum", len(records))
+				}
+				if got := severity(test.level); got != test.want {
+					t.Errorf("severity(%s) = %s, esperado %s", test.level, got, test.want)
+				}
+				return
+			}
+
+			if len(records) != 1 {
+				t.Fatalf("%d registros, esperado 1", len(records))
+			}
+			if got := records[0].Severity(); got != test.want {
+				t.Errorf("severidade = %s, esperado %s", got, test.want)
+			}
+			if got := records[0].SeverityText(); got != test.level.String() {
+				t.Errorf("texto da severidade = %q, esperado %q", got, test.level.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerProvider - envolve provider para que cada evento de span também seja um registro do slog
+// padrão, no momento do evento e com o trace_id e o span_id do span. Assim handlers, usecases e
+// services registram seus marcos uma vez, com span.AddEvent, e eles aparecem nos dois lugares; as
+// falhas, com span.RecordError e span.SetStatus, viram registros de erro. Os atributos do evento passam por redact, quando informado, antes de chegar ao log.
+func TracerProvider(provider trace.TracerProvider, redact func([]attribute.KeyValue) []attribute.KeyValue) trace.TracerProvider {
+	return tracerProvider{TracerProvider: provider, redact: redact}
+}
+
+type tracerProvider struct {
+	trace.TracerProvider
+	redact func([]attribute.KeyValue) []attribute.KeyValue
+}
+
+func (p tracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return tracer{Tracer: p.TracerProvider.Tracer(name, options...), name: name, redact: p.redact}
+}
+
+type tracer struct {
+	trace.Tracer
+	name   string
+	redact func([]attribute.KeyValue) []attribute.KeyValue
+}
+
+func (t tracer) Start(ctx context.Context, spanName string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx, span := t.Tracer.Start(ctx, spanName, options...)
+	logged := loggedSpan{Span: span, scope: t.name, name: spanName, redact: t.redact, recorded: &recordedError{}}
+
+	return trace.ContextWithSpan(ctx, logged), logged
+}
+
+type loggedSpan struct {
+	trace.Span
+	scope    string
+	name     string
+	redact   func([]attribute.KeyValue) []attribute.KeyValue
+	recorded *recordedError
+}
+
+// recordedError - último erro de RecordError no span, que acompanha o registro do SetStatus
+type recordedError struct {
+	mu  sync.Mutex
+	err error
+}
+
+// AddEvent - registra o evento no span e no slog
+func (s loggedSpan) AddEvent(name string, options ...trace.EventOption) {
+	s.Span.AddEvent(name, options...)
+
+	config := trace.NewEventConfig(options...)
+	s.log(eventLevel(name), name, config.Attributes())
+}
+
+// RecordError - registra a exceção no span; o slog a recebe com o SetStatus que marca a falha
+func (s loggedSpan) RecordError(err error, options ...trace.EventOption) {
+	s.Span.RecordError(err, options...)
+
+	s.recorded.mu.Lock()
+	s.recorded.err = err
+	s.recorded.mu.Unlock()
+}
+
+// SetStatus - o status Error também é um registro de erro no slog, com a descrição como mensagem e
+// o último erro de RecordError; sem descrição (ex.: o status dado pelo otelhttp às respostas 5xx),
+// fica só no span
+func (s loggedSpan) SetStatus(code codes.Code, description string) {
+	s.Span.SetStatus(code, description)
+	if code != codes.Error || description == "" {
+		return
+	}
+
+	s.recorded.mu.Lock()
+	err := s.recorded.err
+	s.recorded.mu.Unlock()
+
+	var attrs []attribute.KeyValue
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	}
+	s.log(slog.LevelError, description, attrs)
+}
+
+// log - registro no slog com o trace_id e o span_id do span e os atributos, sem os dados pessoais
+func (s loggedSpan) log(level slog.Level, message string, kvs []attribute.KeyValue) {
+	ctx := trace.ContextWithSpanContext(context.Background(), s.SpanContext())
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	if s.redact != nil {
+		kvs = s.redact(kvs)
+	}
+	attrs := []slog.Attr{slog.String("scope", s.scope), slog.String("span", s.name)}
+	for _, kv := range kvs {
+		attrs = append(attrs, slog.Any(string(kv.Key), attributeValue(kv.Value)))
+	}
+
+	slog.Default().LogAttrs(ctx, level, message, attrs...)
+}
+
+// TracerProvider - o provider do span, também envolvido, para quem cria tracers a partir dele
+func (s loggedSpan) TracerProvider() trace.TracerProvider {
+	return TracerProvider(s.Span.TracerProvider(), s.redact)
+}
+
+// eventLevel - nível pelo nome do evento, que segue a convenção do projeto: falhas começam com
+// "error" (error on search) ou terminam com ele (response error); ausências esperadas (not found,
+// not available, without ...) e entradas inválidas são avisos; o restante são marcos do fluxo
+func eventLevel(name string) slog.Level {
+	switch {
+	case strings.HasPrefix(name, "error") || strings.Contains(name, " error"):
+		return slog.LevelError
+	case strings.Contains(name, "not found") || strings.Contains(name, "not available") ||
+		strings.Contains(name, "without") || strings.HasPrefix(name, "invalid"):
+		return slog.LevelWarn
+	}
+
+	return slog.LevelInfo
+}
+
+// attributeValue - valor do atributo do span no tipo Go equivalente
+func attributeValue(value attribute.Value) any {
+	switch value.Type() {
+	case attribute.BOOL:
+		return value.AsBool()
+	case attribute.INT64:
+		return value.AsInt64()
+	case attribute.FLOAT64:
+		return value.AsFloat64()
+	case attribute.STRING:
+		return value.AsString()
+	}
+
+	return value.Emit()
+}
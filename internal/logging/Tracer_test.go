@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otellog "go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redactZipcode - troca o CEP dos atributos, como a política de redação faz
+func redactZipcode(kvs []attribute.KeyValue) []attribute.KeyValue {
+	redacted := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		if kv.Key == "zipcode" {
+			kv = attribute.String("zipcode", "[REDACTED]")
+		}
+		redacted = append(redacted, kv)
+	}
+
+	return redacted
+}
+
+// newTestTracer - tracer envolvido por TracerProvider, com o slog padrão sobre o Handler em memória
+// durante o teste
+func newTestTracer(t *testing.T) (trace.Tracer, *tracetest.SpanRecorder, *testOutput) {
+	t.Helper()
+
+	logger, output := newTestLogger()
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	return TracerProvider(provider, redactZipcode).Tracer("usecase"), recorder, output
+}
+
+func TestTracerProviderLogsEvents(t *testing.T) {
+	tracer, recorder, output := newTestTracer(t)
+
+	_, span := tracer.Start(context.Background(), "search_address")
+	span.AddEvent("address found", trace.WithAttributes(
+		attribute.String("zipcode", "87033080"),
+		attribute.String("city", "Maringá"),
+	))
+	span.End()
+
+	records := output.records()
+	if len(records) != 1 {
+		t.Fatalf("%d registros para um evento, esperado 1", len(records))
+	}
+	record := records[0]
+	if record.Body().AsString() != "address found" || record.Severity() != otellog.SeverityInfo {
+		t.Errorf("registro = %q %s, esperado \"address found\" INFO", record.Body().AsString(), record.Severity())
+	}
+	got := attributes(record)
+	want := map[string]string{"scope": "usecase", "span": "search_address", "zipcode": "[REDACTED]", "city": "Maringá"}
+	if len(got) != len(want) {
+		t.Errorf("atributos = %v, esperado %v", got, want)
+	}
+	for key, value := range want {
+		if got[key].AsString() != value {
+			t.Errorf("%s = %v, esperado %q", key, got[key], value)
+		}
+	}
+
+	// o registro leva o trace do span; o evento do span fica como foi registrado
+	spanContext := span.SpanContext()
+	if len(output.spans) != 1 || !output.spans[0].Equal(spanContext) {
+		t.Errorf("Emit com os spans %v, esperado %v", output.spans, spanContext)
+	}
+	if line := output.lines(t)[0]; line["trace_id"] != spanContext.TraceID().String() || line["span_id"] != spanContext.SpanID().String() {
+		t.Errorf("saída JSON sem o trace do span: %v", line)
+	}
+	events := recorder.Ended()[0].Events()
+	if len(events) != 1 || len(events[0].Attributes) != 2 || events[0].Attributes[0].Value.AsString() != "87033080" {
+		t.Errorf("eventos do span = %v", events)
+	}
+}
+
+func TestTracerProviderErrors(t *testing.T) {
+	tracer, _, output := newTestTracer(t)
+
+	// RecordError sozinho fica só no span
+	_, span := tracer.Start(context.Background(), "service_brasilAPI_request")
+	span.RecordError(errors.New("connection refused"))
+	if records := output.records(); len(records) != 0 {
+		t.Fatalf("%d registros após RecordError, esperado nenhum", len(records))
+	}
+
+	// o SetStatus com descrição é um registro de erro, com o último erro de RecordError
+	span.RecordError(errors.New("context deadline exceeded"))
+	span.SetStatus(codes.Error, "error on search")
+	// status sem descrição (ex.: respostas 5xx no otelhttp) e status Ok ficam só no span
+	span.SetStatus(codes.Error, "")
+	span.SetStatus(codes.Ok, "done")
+	span.End()
+
+	records := output.records()
+	if len(records) != 1 {
+		t.Fatalf("%d registros, esperado 1", len(records))
+	}
+	record := records[0]
+	if record.Body().AsString() != "error on search" || record.Severity() != otellog.SeverityError {
+		t.Errorf("registro = %q %s, esperado \"error on search\" ERROR", record.Body().AsString(), record.Severity())
+	}
+	if got := attributes(record)["error"].AsString(); got != "context deadline exceeded" {
+		t.Errorf("error = %q, esperado o último erro de RecordError", got)
+	}
+
+	// SetStatus sem RecordError também é registrado, sem o atributo error
+	_, other := tracer.Start(context.Background(), "service_weatherAPI_request")
+	other.SetStatus(codes.Error, "response error")
+	other.End()
+	if records := output.records(); len(records) != 2 {
+		t.Fatalf("%d registros, esperado 2", len(records))
+	} else if _, ok := attributes(records[1])["error"]; ok {
+		t.Errorf("registro com error sem RecordError no span: %v", attributes(records[1]))
+	}
+}
+
+func TestEventLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		want slog.Level
+	}{
+		{"error on search", slog.LevelError},
+		{"response error", slog.LevelError},
+		{"cep not found", slog.LevelWarn},
+		{"air quality not available", slog.LevelWarn},
+		{"location without coordinates", slog.LevelWarn},
+		{"invalid zipcode", slog.LevelWarn},
+		{"response success", slog.LevelInfo},
+		{"cache hit", slog.LevelInfo},
+	}
+
+	for _, test := range tests {
+		if got := eventLevel(test.name); got != test.want {
+			t.Errorf("eventLevel(%q) = %s, esperado %s", test.name, got, test.want)
+		}
+	}
+}
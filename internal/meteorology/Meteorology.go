@@ -0,0 +1,124 @@
+// Package meteorology calcula índices meteorológicos derivados a partir dos campos
+// brutos entregues pelos provedores (temperatura em °C, umidade relativa em %, vento em km/h).
+package meteorology
+
+import "math"
+
+// CelsiusToFahrenheit - converte °C para °F
+func CelsiusToFahrenheit(tempC float64) float64 {
+	return tempC*9/5 + 32
+}
+
+// FahrenheitToCelsius - converte °F para °C
+func FahrenheitToCelsius(tempF float64) float64 {
+	return (tempF - 32) * 5 / 9
+}
+
+// CelsiusToKelvin - converte °C para K
+func CelsiusToKelvin(tempC float64) float64 {
+	return tempC + 273.15
+}
+
+// HeatIndex - índice de calor da NOAA (regressão de Rothfusz com os ajustes oficiais), em °C.
+// Abaixo de 80°F a própria NOAA usa a fórmula simplificada de Steadman.
+func HeatIndex(tempC float64, humidity float64) float64 {
+	t := CelsiusToFahrenheit(tempC)
+	rh := humidity
+
+	simple := 0.5 * (t + 61.0 + (t-68.0)*1.2 + rh*0.094)
+	if (simple+t)/2 < 80 {
+		return FahrenheitToCelsius(simple)
+	}
+
+	hi := -42.379 + 2.04901523*t + 10.14333127*rh -
+		0.22475541*t*rh - 0.00683783*t*t -
+		0.05481717*rh*rh + 0.00122874*t*t*rh +
+		0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+	switch {
+	case rh < 13 && t >= 80 && t <= 112:
+		hi -= ((13 - rh) / 4) * math.Sqrt((17-math.Abs(t-95))/17)
+	case rh > 85 && t >= 80 && t <= 87:
+		hi += ((rh - 85) / 10) * ((87 - t) / 5)
+	}
+
+	return FahrenheitToCelsius(hi)
+}
+
+// WindChill - sensação térmica pelo vento (fórmula conjunta EUA/Canadá), em °C.
+// Fora da faixa de validade (acima de 10°C ou vento até 4,8 km/h) retorna a própria temperatura.
+func WindChill(tempC float64, windKph float64) float64 {
+	if tempC > 10 || windKph <= 4.8 {
+		return tempC
+	}
+
+	v := math.Pow(windKph, 0.16)
+
+	return 13.12 + 0.6215*tempC - 11.37*v + 0.3965*tempC*v
+}
+
+// DewPoint - ponto de orvalho pela fórmula de Magnus, em °C
+func DewPoint(tempC float64, humidity float64) float64 {
+	if humidity <= 0 {
+		return math.NaN()
+	}
+
+	const a, b = 17.625, 243.04
+	gamma := math.Log(humidity/100) + a*tempC/(b+tempC)
+
+	return b * gamma / (a - gamma)
+}
+
+// Humidex - índice canadense de desconforto a partir da temperatura e do ponto de orvalho, em °C
+func Humidex(tempC float64, dewPointC float64) float64 {
+	e := 6.11 * math.Exp(5417.7530*(1/273.16-1/CelsiusToKelvin(dewPointC)))
+
+	return tempC + 0.5555*(e-10)
+}
+
+// ApparentTemperature - temperatura aparente do Australian Bureau of Meteorology (sem radiação), em °C
+func ApparentTemperature(tempC float64, humidity float64, windKph float64) float64 {
+	e := humidity / 100 * 6.105 * math.Exp(17.27*tempC/(237.7+tempC))
+	windMs := windKph / 3.6
+
+	return tempC + 0.33*e - 0.70*windMs - 4.00
+}
+
+// beaufortLimits - limite superior (km/h), inclusivo, de cada grau da escala Beaufort, do 0 ao 11
+var beaufortLimits = []float64{1, 5, 11, 19, 28, 38, 49, 61, 74, 88, 102, 117}
+
+var beaufortDescriptions = []string{
+	"Calm",
+	"Light air",
+	"Light breeze",
+	"Gentle breeze",
+	"Moderate breeze",
+	"Fresh breeze",
+	"Strong breeze",
+	"Near gale",
+	"Gale",
+	"Strong gale",
+	"Storm",
+	"Violent storm",
+	"Hurricane force",
+}
+
+// Beaufort - grau da escala Beaufort (0 a 12) para a velocidade do vento em km/h
+func Beaufort(windKph float64) int {
+	for scale, limit := range beaufortLimits {
+		if windKph <= limit {
+			return scale
+		}
+	}
+
+	return 12
+}
+
+// BeaufortDescription - descrição do grau da escala Beaufort
+func BeaufortDescription(scale int) string {
+	if scale < 0 || scale >= len(beaufortDescriptions) {
+		return ""
+	}
+
+	return beaufortDescriptions[scale]
+}
@@ -0,0 +1,108 @@
+package meteorology
+
+import (
+	"math"
+	"testing"
+)
+
+func assertNear(t *testing.T, name string, got float64, want float64, tolerance float64) {
+	t.Helper()
+
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %.3f, want %.3f (±%.2f)", name, got, want, tolerance)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	assertNear(t, "CelsiusToFahrenheit(27.5)", CelsiusToFahrenheit(27.5), 81.5, 1e-9)
+	assertNear(t, "CelsiusToFahrenheit(-40)", CelsiusToFahrenheit(-40), -40, 1e-9)
+	assertNear(t, "FahrenheitToCelsius(212)", FahrenheitToCelsius(212), 100, 1e-9)
+	assertNear(t, "CelsiusToKelvin(27.5)", CelsiusToKelvin(27.5), 300.65, 1e-9)
+}
+
+func TestHeatIndex(t *testing.T) {
+	// valores de referência da tabela de índice de calor da NOAA
+	tests := []struct {
+		tempF    float64
+		humidity float64
+		wantF    float64
+	}{
+		{tempF: 90, humidity: 50, wantF: 95},
+		{tempF: 96, humidity: 65, wantF: 121},
+		{tempF: 80, humidity: 40, wantF: 80},
+		{tempF: 100, humidity: 40, wantF: 109},
+		{tempF: 70, humidity: 50, wantF: 69.4},
+	}
+
+	for _, tt := range tests {
+		got := CelsiusToFahrenheit(HeatIndex(FahrenheitToCelsius(tt.tempF), tt.humidity))
+		assertNear(t, "HeatIndex", got, tt.wantF, 1.5)
+	}
+}
+
+func TestWindChill(t *testing.T) {
+	// tabela de wind chill do Environment Canada
+	assertNear(t, "WindChill(-10, 20)", WindChill(-10, 20), -17.9, 0.1)
+	assertNear(t, "WindChill(0, 50)", WindChill(0, 50), -8.1, 0.1)
+	assertNear(t, "WindChill(5, 10)", WindChill(5, 10), 2.7, 0.1)
+
+	// fora da faixa de validade devolve a própria temperatura
+	assertNear(t, "WindChill(25, 30)", WindChill(25, 30), 25, 0)
+	assertNear(t, "WindChill(0, 3)", WindChill(0, 3), 0, 0)
+}
+
+func TestDewPoint(t *testing.T) {
+	assertNear(t, "DewPoint(25, 60)", DewPoint(25, 60), 16.7, 0.1)
+	assertNear(t, "DewPoint(30, 80)", DewPoint(30, 80), 26.2, 0.1)
+	assertNear(t, "DewPoint(20, 100)", DewPoint(20, 100), 20, 1e-9)
+
+	if !math.IsNaN(DewPoint(20, 0)) {
+		t.Errorf("DewPoint(20, 0) should be NaN")
+	}
+}
+
+func TestHumidex(t *testing.T) {
+	// exemplo do Environment Canada: 30°C com ponto de orvalho de 15°C resulta em humidex 34
+	assertNear(t, "Humidex(30, 15)", Humidex(30, 15), 34, 0.5)
+	assertNear(t, "Humidex(35, 25)", Humidex(35, 25), 47, 0.5)
+}
+
+func TestApparentTemperature(t *testing.T) {
+	// AT = Ta + 0.33e - 0.70ws - 4.00, com e = 21.14 hPa e 6.14 hPa respectivamente
+	assertNear(t, "ApparentTemperature(30, 50, 0)", ApparentTemperature(30, 50, 0), 32.98, 0.05)
+	assertNear(t, "ApparentTemperature(10, 50, 36)", ApparentTemperature(10, 50, 36), 1.03, 0.05)
+}
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		windKph float64
+		want    int
+	}{
+		{windKph: 0, want: 0},
+		{windKph: 0.9, want: 0},
+		{windKph: 1, want: 0},
+		{windKph: 1.5, want: 1},
+		{windKph: 5, want: 1},
+		{windKph: 10, want: 2},
+		{windKph: 11, want: 2},
+		{windKph: 20, want: 4},
+		{windKph: 50, want: 7},
+		{windKph: 90, want: 10},
+		{windKph: 117, want: 11},
+		{windKph: 117.5, want: 12},
+		{windKph: 200, want: 12},
+	}
+
+	for _, tt := range tests {
+		if got := Beaufort(tt.windKph); got != tt.want {
+			t.Errorf("Beaufort(%.1f) = %d, want %d", tt.windKph, got, tt.want)
+		}
+	}
+
+	if got := BeaufortDescription(8); got != "Gale" {
+		t.Errorf("BeaufortDescription(8) = %q, want %q", got, "Gale")
+	}
+	if got := BeaufortDescription(13); got != "" {
+		t.Errorf("BeaufortDescription(13) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,182 @@
+// Package metrics concentra os instrumentos OpenTelemetry do serviço: RED por rota e tenant, latência
+// das chamadas aos provedores por tenant, acertos do cache e a temperatura por cidade. Os instrumentos são criados
+// no meter global, que repassa as medições ao MeterProvider configurado em otel.SetupOTelSDK; sem
+// ele, as medições são descartadas.
+package metrics
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/tenant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Nomes dos provedores no atributo provider de upstream.request.duration
+const (
+	ProviderBrasilAPI  = "brasilapi"
+	ProviderWeatherAPI = "weatherapi"
+	ProviderOpenMeteo  = "open-meteo"
+	ProviderServiceB   = "service-b"
+)
+
+// durationBuckets - limites, em segundos, dos histogramas de latência: de 5ms até o timeout de 30s
+// dos clients
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var (
+	requests         metric.Int64Counter
+	serverErrors     metric.Int64Counter
+	requestDuration  metric.Float64Histogram
+	upstreamDuration metric.Float64Histogram
+	cacheRequests    metric.Int64Counter
+	temperature      metric.Float64Gauge
+)
+
+func init() {
+	meter := otel.Meter("metrics")
+
+	var err error
+	requests, err = meter.Int64Counter(
+		"http.server.requests",
+		metric.WithDescription("Requisições atendidas, por rota, método, status e tenant"),
+		metric.WithUnit("{request}"),
+	)
+	handle(err)
+
+	serverErrors, err = meter.Int64Counter(
+		"http.server.errors",
+		metric.WithDescription("Requisições respondidas com status 5xx, por rota, método, status e tenant"),
+		metric.WithUnit("{request}"),
+	)
+	handle(err)
+
+	requestDuration, err = meter.Float64Histogram(
+		"http.server.request.duration",
+		metric.WithDescription("Duração das requisições, por rota, método, status e tenant"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	)
+	handle(err)
+
+	upstreamDuration, err = meter.Float64Histogram(
+		"upstream.request.duration",
+		metric.WithDescription("Duração das chamadas aos provedores externos, por provedor, resultado e tenant"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	)
+	handle(err)
+
+	cacheRequests, err = meter.Int64Counter(
+		"cache.requests",
+		metric.WithDescription("Consultas ao cache, por cache e resultado (hit ou miss)"),
+		metric.WithUnit("{request}"),
+	)
+	handle(err)
+
+	temperature, err = meter.Float64Gauge(
+		"weather.temperature",
+		metric.WithDescription("Última temperatura observada, por cidade"),
+		metric.WithUnit("Cel"),
+	)
+	handle(err)
+}
+
+// handle - instrumento inválido não impede o serviço de subir; o erro vai para o handler do OpenTelemetry
+func handle(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
+
+// UnknownTenant - valor de tenant.id nas métricas para os tenants fora de SetTenants
+const UnknownTenant = "unknown"
+
+// tenants - tenants que ganham série própria nas métricas
+var tenants atomic.Pointer[map[string]bool]
+
+// SetTenants - tenants conhecidos, que aparecem pelo nome no atributo tenant.id; os demais viram
+// UnknownTenant, para que valores inventados pelos clientes não abram novas séries
+func SetTenants(ids []string) {
+	known := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		known[id] = true
+	}
+	tenants.Store(&known)
+}
+
+// withTenant - atributos com o tenant do baggage do contexto, quando há um
+func withTenant(ctx context.Context, attributes []attribute.KeyValue) []attribute.KeyValue {
+	tenantID := tenant.FromContext(ctx)
+	if tenantID == "" {
+		return attributes
+	}
+
+	if known := tenants.Load(); known == nil || !(*known)[tenantID] {
+		tenantID = UnknownTenant
+	}
+	attributes = append(attributes, attribute.String(tenant.KeyTenant, tenantID))
+
+	return attributes
+}
+
+// RecordRequest - uma requisição atendida; route é o padrão da rota sem o método (ex.:
+// /v1/weather/cep/{cep}), vazio quando nenhuma rota atendeu
+func RecordRequest(ctx context.Context, method string, route string, statusCode int, duration time.Duration) {
+	attributes := []attribute.KeyValue{
+		attribute.String("http.request.method", method),
+		attribute.Int("http.response.status_code", statusCode),
+	}
+	if route != "" {
+		attributes = append(attributes, attribute.String("http.route", route))
+	}
+	attributes = withTenant(ctx, attributes)
+	set := metric.WithAttributes(attributes...)
+
+	requests.Add(ctx, 1, set)
+	requestDuration.Record(ctx, duration.Seconds(), set)
+	if statusCode >= 500 {
+		serverErrors.Add(ctx, 1, set)
+	}
+}
+
+// RecordUpstream - uma chamada ao provedor; statusCode zero quando não houve resposta
+func RecordUpstream(ctx context.Context, provider string, statusCode int, duration time.Duration) {
+	outcome := "success"
+	if statusCode == 0 || statusCode >= 400 {
+		outcome = "error"
+	}
+
+	attributes := []attribute.KeyValue{
+		attribute.String("provider", provider),
+		attribute.String("outcome", outcome),
+	}
+	if statusCode != 0 {
+		attributes = append(attributes, attribute.Int("http.response.status_code", statusCode))
+	}
+	attributes = withTenant(ctx, attributes)
+
+	upstreamDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attributes...))
+}
+
+// RecordCache - uma consulta ao cache name; a taxa de acerto é hit sobre o total
+func RecordCache(ctx context.Context, name string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	cacheRequests.Add(ctx, 1, metric.WithAttributes(attribute.String("cache", name), attribute.String("result", result)))
+}
+
+// RecordTemperature - temperatura, em °C, da observação mais recente da cidade
+func RecordTemperature(ctx context.Context, city string, celsius float64) {
+	if city == "" {
+		return
+	}
+
+	temperature.Record(ctx, celsius, metric.WithAttributes(attribute.String("city", city)))
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/tenant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var (
+	readerOnce sync.Once
+	reader     *sdkmetric.ManualReader
+)
+
+// manualReader - leitor do MeterProvider global; os instrumentos do pacote são criados no meter
+// global, que só repassa ao primeiro MeterProvider configurado, então todos os testes o dividem
+func manualReader() *sdkmetric.ManualReader {
+	readerOnce.Do(func() {
+		reader = sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	})
+
+	return reader
+}
+
+// collect - pontos da métrica name coletados agora
+func collect(t *testing.T, name string) []attribute.Set {
+	t.Helper()
+
+	var data metricdata.ResourceMetrics
+	if err := manualReader().Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	var sets []attribute.Set
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch points := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range points.DataPoints {
+					sets = append(sets, point.Attributes)
+				}
+			case metricdata.Gauge[float64]:
+				for _, point := range points.DataPoints {
+					sets = append(sets, point.Attributes)
+				}
+			}
+		}
+	}
+
+	return sets
+}
+
+func TestRecordRequestUnknownTenant(t *testing.T) {
+	manualReader()
+	SetTenants([]string{"acme"})
+	defer SetTenants(nil)
+
+	for _, tenantID := range []string{"acme", "invented-1", "invented-2"} {
+		ctx, err := tenant.NewContext(context.Background(), tenantID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		RecordRequest(ctx, "GET", "/tenant-test", 200, time.Millisecond)
+	}
+
+	tenants := map[string]bool{}
+	for _, set := range collect(t, "http.server.requests") {
+		if route, _ := set.Value("http.route"); route.AsString() != "/tenant-test" {
+			continue
+		}
+		value, _ := set.Value(tenant.KeyTenant)
+		tenants[value.AsString()] = true
+	}
+
+	if len(tenants) != 2 || !tenants["acme"] || !tenants[UnknownTenant] {
+		t.Errorf("tenants nas séries = %v, esperado acme e %s", tenants, UnknownTenant)
+	}
+}
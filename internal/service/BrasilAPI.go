@@ -2,18 +2,29 @@ package service
 
 import (
 	"context"
-	"errors"
 	"io"
+	"net/http"
+	"os"
+	"strings"
 
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
 	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
 	"github.com/valyala/fastjson"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
-
-	PKGHttpClient "github.com/nagahshi/pos_go_weather_otel/pkg/http"
 )
 
+// brasilAPIURL - endereço da BrasilAPI; BRASILAPI_URL aponta para outra instância, como um mock
+func brasilAPIURL() string {
+	if value := os.Getenv("BRASILAPI_URL"); value != "" {
+		return strings.TrimSuffix(value, "/")
+	}
+
+	return "https://brasilapi.com.br"
+}
+
 type BrasilAPI struct {
 	CEP string
 }
@@ -26,27 +37,27 @@ func NewBrasilAPIService(CEP string) *BrasilAPI {
 
 // Search - busca de clima pelo CEP
 func (c *BrasilAPI) Search(ctx context.Context) (CEPOutput dto.CEPOutput, err error) {
-	tracer := otel.Tracer("service-BrasilAPI-search")
-
-	_, spanRequest := tracer.Start(ctx, "service_BrasilAPI_request")
+	ctx, spanRequest := tracer.Start(ctx, "service_BrasilAPI_request")
 
 	spanRequest.AddEvent("new client http")
-	var client = PKGHttpClient.GetNewClient()
+	var client = upstreamClient(metrics.ProviderBrasilAPI)
 
 	spanRequest.AddEvent("zipcode to search", trace.WithAttributes(attribute.String("zipcode", c.CEP)))
-	resp, err := client.Get("https://brasilapi.com.br/api/cep/v2/" + c.CEP)
+	resp, err := get(ctx, client, brasilAPIURL()+"/api/cep/v2/"+c.CEP)
 	if err != nil {
-		spanRequest.AddEvent("error on search", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on search")
 		spanRequest.End()
-		return CEPOutput, errors.New("ocorreu um erro, ao buscar informações")
+		return CEPOutput, upstreamRequestError(err)
 	}
 
 	spanRequest.AddEvent("read response")
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		spanRequest.AddEvent("error on read response", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on read response")
 		spanRequest.End()
-		return CEPOutput, errors.New("ocorreu um erro, ao ler informações")
+		return CEPOutput, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao ler informações", err)
 	}
 
 	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
@@ -54,9 +65,10 @@ func (c *BrasilAPI) Search(ctx context.Context) (CEPOutput dto.CEPOutput, err er
 		var p fastjson.Parser
 		v, err := p.Parse(string(respBody))
 		if err != nil {
-			spanRequest.AddEvent("error on parse response", trace.WithAttributes(attribute.String("error", err.Error())))
+			spanRequest.RecordError(err)
+			spanRequest.SetStatus(codes.Error, "error on parse response")
 			spanRequest.End()
-			return CEPOutput, errors.New("ocorreu um erro, ao tratar informações")
+			return CEPOutput, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
 		}
 
 		CEPOutput.Logradouro = string(v.GetStringBytes("street"))
@@ -77,7 +89,14 @@ func (c *BrasilAPI) Search(ctx context.Context) (CEPOutput dto.CEPOutput, err er
 		return CEPOutput, nil
 	}
 
-	spanRequest.AddEvent("response error", trace.WithAttributes(attribute.String("error", string(respBody))))
+	if resp.StatusCode == http.StatusNotFound {
+		err = apperror.New(apperror.CodeCEPNotFound, "CEP não encontrado: "+c.CEP)
+	} else {
+		err = upstreamStatusError(resp.StatusCode, "ocorreu um erro, ao buscar informações")
+	}
+	spanRequest.RecordError(err, trace.WithAttributes(attribute.String("response", string(respBody))))
+	spanRequest.SetStatus(codes.Error, "response error")
 	spanRequest.End()
-	return CEPOutput, errors.New("ocorreu um erro, ao buscar informações")
+
+	return CEPOutput, err
 }
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
+	"github.com/valyala/fastjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type OpenMeteoAirQuality struct {
+	Latitude  string
+	Longitude string
+}
+
+func NewOpenMeteoAirQualityService(latitude string, longitude string) *OpenMeteoAirQuality {
+	return &OpenMeteoAirQuality{
+		Latitude:  latitude,
+		Longitude: longitude,
+	}
+}
+
+// Search - busca da qualidade do ar pela latitude e longitude na API aberta da Open-Meteo
+func (c *OpenMeteoAirQuality) Search(ctx context.Context) (airQualityOutput dto.AirQualityOutput, err error) {
+	ctx, spanRequest := tracer.Start(ctx, "service_openMeteo_air_quality_request")
+	defer spanRequest.End()
+
+	spanRequest.AddEvent("new client http")
+	var client = upstreamClient(metrics.ProviderOpenMeteo)
+
+	if c.Latitude == "" || c.Longitude == "" {
+		spanRequest.AddEvent("latitude and longitude not found")
+		return airQualityOutput, apperror.New(apperror.CodeLocationNotFound, "latitude e longitude não informadas")
+	}
+
+	spanRequest.AddEvent(
+		"location to search",
+		trace.WithAttributes(attribute.String("latitude", c.Latitude), attribute.String("longitude", c.Longitude)),
+	)
+	resp, err := get(ctx, client,
+		"https://air-quality-api.open-meteo.com/v1/air-quality?current=pm10,pm2_5,ozone,nitrogen_dioxide,us_aqi"+
+			"&latitude="+c.Latitude+"&longitude="+c.Longitude,
+	)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on search")
+		return airQualityOutput, upstreamRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	spanRequest.AddEvent("read response")
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on read response")
+		return airQualityOutput, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao ler informações", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = upstreamStatusError(resp.StatusCode, "ocorreu um erro, ao buscar informações: "+string(respBody))
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "response error")
+		return airQualityOutput, err
+	}
+
+	spanRequest.AddEvent("parse response")
+	var p fastjson.Parser
+	v, err := p.Parse(string(respBody))
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on parse response")
+		return airQualityOutput, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
+	}
+
+	current := v.Get("current")
+	if current == nil {
+		spanRequest.AddEvent("air quality not available")
+		return airQualityOutput, apperror.New(apperror.CodeUpstreamUnavailable, "qualidade do ar não disponível para o local")
+	}
+
+	airQualityOutput.PM25 = current.GetFloat64("pm2_5")
+	airQualityOutput.PM10 = current.GetFloat64("pm10")
+	airQualityOutput.O3 = current.GetFloat64("ozone")
+	airQualityOutput.NO2 = current.GetFloat64("nitrogen_dioxide")
+	// a Open-Meteo não entrega os índices EPA e DEFRA, então são derivados localmente
+	airQualityOutput.USEPAIndex = usEPAIndexFromAQI(current.GetFloat64("us_aqi"))
+	airQualityOutput.GBDefraIndex = gbDefraIndexFromPM25(airQualityOutput.PM25)
+	airQualityOutput.Provider = "open-meteo"
+
+	spanRequest.AddEvent(
+		"response success",
+		trace.WithAttributes(
+			attribute.Int("us_epa_index", airQualityOutput.USEPAIndex),
+			attribute.Int("gb_defra_index", airQualityOutput.GBDefraIndex),
+		),
+	)
+
+	return airQualityOutput, nil
+}
+
+// usEPAIndexFromAQI - converte o valor do US AQI para a banda de 1 a 6 usada pela WeatherAPI
+func usEPAIndexFromAQI(aqi float64) int {
+	switch {
+	case aqi <= 50:
+		return 1
+	case aqi <= 100:
+		return 2
+	case aqi <= 150:
+		return 3
+	case aqi <= 200:
+		return 4
+	case aqi <= 300:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// gbDefraIndexFromPM25 - converte a concentração de PM2.5 (µg/m³) para o índice DEFRA de 1 a 10
+func gbDefraIndexFromPM25(pm25 float64) int {
+	bands := []float64{11, 23, 35, 41, 47, 53, 58, 64, 70}
+	for i, limit := range bands {
+		if pm25 <= limit {
+			return i + 1
+		}
+	}
+
+	return 10
+}
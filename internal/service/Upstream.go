@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+
+	PKGHttpClient "github.com/nagahshi/pos_go_weather_otel/pkg/http"
+)
+
+// tracer - escopo de instrumentação dos spans dos provedores
+var tracer = otel.Tracer("github.com/nagahshi/pos_go_weather_otel/internal/service")
+
+// meteredTransport - registra a latência de cada chamada ao provedor, até a chegada dos headers da
+// resposta, em upstream.request.duration
+type meteredTransport struct {
+	provider string
+	next     http.RoundTripper
+}
+
+func (t meteredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	statusCode := 0
+	if err == nil {
+		statusCode = resp.StatusCode
+	}
+	metrics.RecordUpstream(req.Context(), t.provider, statusCode, time.Since(start))
+
+	return resp, err
+}
+
+// upstreamClient - client http do provedor, com a latência das chamadas registrada por provedor e
+// um span de cliente HTTP por chamada, com os atributos das convenções semânticas
+func upstreamClient(provider string) http.Client {
+	client := PKGHttpClient.GetNewClient()
+	client.Transport = otelhttp.NewTransport(meteredTransport{provider: provider, next: http.DefaultTransport})
+
+	return client
+}
+
+// get - GET com o contexto do span do provedor, para o span de cliente HTTP ficar abaixo dele
+func get(ctx context.Context, client http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
+
+// upstreamRequestError - classifica a falha de transporte: timeout vira UPSTREAM_TIMEOUT,
+// o restante UPSTREAM_UNAVAILABLE
+func upstreamRequestError(err error) *apperror.Error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return apperror.Wrap(apperror.CodeUpstreamTimeout, "tempo esgotado ao buscar informações", err)
+	}
+
+	return apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao buscar informações", err)
+}
+
+// upstreamStatusError - classifica respostas não 2xx comuns a todos os provedores
+func upstreamStatusError(statusCode int, message string) *apperror.Error {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return apperror.New(apperror.CodeUpstreamRateLimited, message)
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return apperror.New(apperror.CodeUpstreamTimeout, message)
+	}
+
+	return apperror.New(apperror.CodeUpstreamUnavailable, fmt.Sprintf("%s status: %d", message, statusCode))
+}
@@ -2,61 +2,87 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
 	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
 	"github.com/valyala/fastjson"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
-
-	PKGHttpClient "github.com/nagahshi/pos_go_weather_otel/pkg/http"
 )
 
+// weatherAPIURL - endereço da WeatherAPI; WEATHERAPI_URL aponta para outra instância, como um mock
+func weatherAPIURL() string {
+	if value := os.Getenv("WEATHERAPI_URL"); value != "" {
+		return strings.TrimSuffix(value, "/")
+	}
+
+	return "http://api.weatherapi.com"
+}
+
 type WeatherAPI struct {
 	key        string
 	Localidade string
+	Lang       string
+	// AirQuality - pede a qualidade do ar na mesma consulta (aqi=yes); o bloco fica vazio quando a WeatherAPI
+	// não tem a medição do local
+	AirQuality bool
 }
 
-func NewWeatherAPIService(key string, localidade string) *WeatherAPI {
+func NewWeatherAPIService(key string, localidade string, lang string) *WeatherAPI {
 	return &WeatherAPI{
 		key:        key,
 		Localidade: localidade,
+		Lang:       lang,
 	}
 }
 
 // Search - busca de clima pelo local
 func (c *WeatherAPI) Search(ctx context.Context) (weatherAPIOutput dto.WeatherOutput, err error) {
-	tracer := otel.Tracer("service-weatherAPI-search")
-
-	_, spanRequest := tracer.Start(ctx, "service_weatherAPI_request")
+	ctx, spanRequest := tracer.Start(ctx, "service_weatherAPI_request")
 
 	spanRequest.AddEvent("new client http")
-	var client = PKGHttpClient.GetNewClient()
+	var client = upstreamClient(metrics.ProviderWeatherAPI)
 
 	if c.key == "" {
-		spanRequest.AddEvent("key[WEATHER_API_KEY] not found")
+		err = apperror.New(apperror.CodeMissingKey, "chave de acesso não informada")
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "key[WEATHER_API_KEY] not found")
 		spanRequest.End()
-		return weatherAPIOutput, errors.New("chave de acesso não informada")
+		return weatherAPIOutput, err
 	}
 
 	spanRequest.AddEvent("localidade to search", trace.WithAttributes(attribute.String("localidade", c.Localidade)))
 	// realizo pesquisas cada um em sua rotina
-	resp, err := client.Get("http://api.weatherapi.com/v1/current.json?key=" + c.key + "&q=" + c.Localidade)
+	url := weatherAPIURL() + "/v1/current.json?key=" + c.key + "&q=" + c.Localidade
+	if c.Lang != "" {
+		// a WeatherAPI traduz o texto da condição pelo parâmetro lang
+		url += "&lang=" + c.Lang
+	}
+	if c.AirQuality {
+		url += "&aqi=yes"
+	}
+	resp, err := get(ctx, client, url)
 	if err != nil {
-		spanRequest.AddEvent("error on search", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on search")
 		spanRequest.End()
-		return weatherAPIOutput, errors.New("ocorreu um erro, ao buscar informações: " + err.Error())
+		return weatherAPIOutput, upstreamRequestError(err)
 	}
 
 	spanRequest.AddEvent("read response")
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		spanRequest.AddEvent("error on read response", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on read response")
 		spanRequest.End()
-		return weatherAPIOutput, errors.New("ocorreu um erro, ao ler informações")
+		return weatherAPIOutput, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao ler informações", err)
 	}
 
 	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
@@ -64,21 +90,32 @@ func (c *WeatherAPI) Search(ctx context.Context) (weatherAPIOutput dto.WeatherOu
 		var p fastjson.Parser
 		v, err := p.Parse(string(respBody))
 		if err != nil {
-			spanRequest.AddEvent("error on parse response", trace.WithAttributes(attribute.String("error", err.Error())))
+			spanRequest.RecordError(err)
+			spanRequest.SetStatus(codes.Error, "error on parse response")
 			spanRequest.End()
-			return weatherAPIOutput, errors.New("ocorreu um erro, ao tratar informações")
+			return weatherAPIOutput, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
 		}
 
-		weatherAPIOutput.C = v.Get("current").Get("temp_c").GetFloat64()
-		weatherAPIOutput.F = (weatherAPIOutput.C * 1.8) + 32
-		weatherAPIOutput.K = weatherAPIOutput.C + 273.15
+		weatherAPIOutput.City = string(v.GetStringBytes("location", "name"))
+		current := v.Get("current")
+		weatherAPIOutput.C = current.GetFloat64("temp_c")
+		weatherAPIOutput.Humidity = current.GetFloat64("humidity")
+		weatherAPIOutput.WindSpeed = current.GetFloat64("wind_kph")
+		weatherAPIOutput.Pressure = current.GetFloat64("pressure_mb")
+		weatherAPIOutput.Condition = string(current.GetStringBytes("condition", "text"))
+		if observedAt := current.GetInt64("last_updated_epoch"); observedAt > 0 {
+			weatherAPIOutput.ObservedAt = time.Unix(observedAt, 0).UTC().Format(time.RFC3339)
+		}
+		if c.AirQuality {
+			weatherAPIOutput.AirQuality = searchWeatherAPIAirQuality(ctx, current)
+		}
 
 		spanRequest.AddEvent(
 			"response success",
 			trace.WithAttributes(
 				attribute.Float64("temp_C", weatherAPIOutput.C),
-				attribute.Float64("temp_F", weatherAPIOutput.F),
-				attribute.Float64("temp_K", weatherAPIOutput.K),
+				attribute.Float64("humidity", weatherAPIOutput.Humidity),
+				attribute.Float64("wind_kph", weatherAPIOutput.WindSpeed),
 			),
 		)
 		spanRequest.End()
@@ -86,13 +123,68 @@ func (c *WeatherAPI) Search(ctx context.Context) (weatherAPIOutput dto.WeatherOu
 		return weatherAPIOutput, nil
 	}
 
-	spanRequest.AddEvent(
-		"ocorreu um erro",
-		trace.WithAttributes(
-			attribute.String("erro", fmt.Sprintf("ocorreu um erro, ao buscar informações: %s status: %d", string(respBody), resp.StatusCode)),
-		),
-	)
+	err = weatherAPIError(resp.StatusCode, respBody)
+	spanRequest.RecordError(err)
+	spanRequest.SetStatus(codes.Error, "response error")
 	spanRequest.End()
 
-	return weatherAPIOutput, fmt.Errorf("ocorreu um erro, ao buscar informações: %s status: %d", string(respBody), resp.StatusCode)
+	return weatherAPIOutput, err
+}
+
+// searchWeatherAPIAirQuality - qualidade do ar da resposta com aqi=yes, nil quando a WeatherAPI não tem
+// a medição do local. A leitura tem span próprio sob o da requisição, como a consulta à Open-Meteo tem
+// o seu, para que a qualidade do ar apareça no trace com qualquer provedor.
+func searchWeatherAPIAirQuality(ctx context.Context, current *fastjson.Value) *dto.AirQualityOutput {
+	_, spanSearch := tracer.Start(ctx, "air_quality_search", trace.WithAttributes(attribute.String("provider", "weatherapi")))
+	defer spanSearch.End()
+
+	aq := current.Get("air_quality")
+	if aq == nil {
+		spanSearch.AddEvent("air quality not available")
+		return nil
+	}
+
+	airQuality := weatherAPIAirQuality(aq)
+	spanSearch.AddEvent("air quality found", trace.WithAttributes(attribute.Int("us_epa_index", airQuality.USEPAIndex)))
+
+	return &airQuality
+}
+
+// weatherAPIAirQuality - bloco current.air_quality da resposta com aqi=yes
+func weatherAPIAirQuality(aq *fastjson.Value) dto.AirQualityOutput {
+	return dto.AirQualityOutput{
+		PM25:         aq.GetFloat64("pm2_5"),
+		PM10:         aq.GetFloat64("pm10"),
+		O3:           aq.GetFloat64("o3"),
+		NO2:          aq.GetFloat64("no2"),
+		USEPAIndex:   aq.GetInt("us-epa-index"),
+		GBDefraIndex: aq.GetInt("gb-defra-index"),
+		Provider:     "weatherapi",
+	}
+}
+
+// weatherAPIError - converte a resposta de erro da WeatherAPI ({"error":{"code":...}}) em erro tipado.
+// Códigos documentados em https://www.weatherapi.com/docs/#intro-error-codes
+func weatherAPIError(statusCode int, respBody []byte) *apperror.Error {
+	message := fmt.Sprintf("ocorreu um erro, ao buscar informações: %s status: %d", string(respBody), statusCode)
+
+	var p fastjson.Parser
+	v, err := p.ParseBytes(respBody)
+	if err != nil {
+		return upstreamStatusError(statusCode, "ocorreu um erro, ao buscar informações: "+string(respBody))
+	}
+
+	switch v.GetInt("error", "code") {
+	case 1002, 2006:
+		// chave não informada ou inválida
+		return apperror.New(apperror.CodeMissingKey, message)
+	case 1006:
+		// nenhum local encontrado para o parâmetro q
+		return apperror.New(apperror.CodeLocationNotFound, message)
+	case 2007, 2008, 2009:
+		// cota mensal excedida, chave desabilitada ou sem acesso ao recurso no plano
+		return apperror.New(apperror.CodeQuotaExceeded, message)
+	}
+
+	return upstreamStatusError(statusCode, "ocorreu um erro, ao buscar informações: "+string(respBody))
 }
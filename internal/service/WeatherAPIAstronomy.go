@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
+	"github.com/valyala/fastjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WeatherAPIAstronomy struct {
+	key        string
+	Localidade string
+	Date       time.Time
+}
+
+// WeatherAPIAstronomyOutput - horários retornados pela WeatherAPI, convertidos para o fuso de Date
+type WeatherAPIAstronomyOutput struct {
+	Sunrise          time.Time
+	Sunset           time.Time
+	MoonPhase        string
+	MoonIllumination float64
+}
+
+func NewWeatherAPIAstronomyService(key string, localidade string, date time.Time) *WeatherAPIAstronomy {
+	return &WeatherAPIAstronomy{
+		key:        key,
+		Localidade: localidade,
+		Date:       date,
+	}
+}
+
+// Search - busca de dados astronômicos pelo local e data
+func (c *WeatherAPIAstronomy) Search(ctx context.Context) (output WeatherAPIAstronomyOutput, err error) {
+	ctx, spanRequest := tracer.Start(ctx, "service_weatherAPI_astronomy_request")
+	defer spanRequest.End()
+
+	spanRequest.AddEvent("new client http")
+	var client = upstreamClient(metrics.ProviderWeatherAPI)
+
+	if c.key == "" {
+		err = apperror.New(apperror.CodeMissingKey, "chave de acesso não informada")
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "key[WEATHER_API_KEY] not found")
+		return output, err
+	}
+
+	date := c.Date.Format(time.DateOnly)
+	spanRequest.AddEvent(
+		"localidade to search",
+		trace.WithAttributes(attribute.String("localidade", c.Localidade), attribute.String("date", date)),
+	)
+	resp, err := get(ctx, client, weatherAPIURL()+"/v1/astronomy.json?key="+c.key+"&q="+c.Localidade+"&dt="+date)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on search")
+		return output, upstreamRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	spanRequest.AddEvent("read response")
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on read response")
+		return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao ler informações", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = weatherAPIError(resp.StatusCode, respBody)
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "response error")
+		return output, err
+	}
+
+	spanRequest.AddEvent("parse response")
+	var p fastjson.Parser
+	v, err := p.Parse(string(respBody))
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on parse response")
+		return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
+	}
+
+	astro := v.Get("astronomy", "astro")
+	if astro == nil {
+		spanRequest.AddEvent("astronomy not available")
+		return output, apperror.New(apperror.CodeUpstreamUnavailable, "dados astronômicos não disponíveis para o local")
+	}
+
+	// os horários vêm no fuso da localidade, informado na mesma resposta
+	var location *time.Location
+	if tzID := string(v.GetStringBytes("location", "tz_id")); tzID != "" {
+		location, err = time.LoadLocation(tzID)
+	} else {
+		err = errors.New("fuso da localidade não informado")
+	}
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on parse timezone")
+		return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
+	}
+
+	output.Sunrise, err = c.parseClock(string(astro.GetStringBytes("sunrise")), location)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on parse sunrise")
+		return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
+	}
+	output.Sunset, err = c.parseClock(string(astro.GetStringBytes("sunset")), location)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on parse sunset")
+		return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
+	}
+	output.MoonPhase = string(astro.GetStringBytes("moon_phase"))
+	// a WeatherAPI já devolveu a iluminação como string e como número, em percentual
+	illumination := astro.Get("moon_illumination")
+	if illumination != nil && illumination.Type() == fastjson.TypeString {
+		illumination, _ = fastjson.Parse(string(illumination.GetStringBytes()))
+	}
+	if illumination != nil {
+		output.MoonIllumination = illumination.GetFloat64() / 100
+	}
+
+	spanRequest.AddEvent(
+		"response success",
+		trace.WithAttributes(
+			attribute.String("sunrise", output.Sunrise.Format(time.RFC3339)),
+			attribute.String("sunset", output.Sunset.Format(time.RFC3339)),
+		),
+	)
+
+	return output, nil
+}
+
+// parseClock - converte horários como "05:47 AM", no fuso location da localidade, para a data da
+// consulta, e devolve o instante no fuso de Date
+func (c *WeatherAPIAstronomy) parseClock(clock string, location *time.Location) (time.Time, error) {
+	parsed, err := time.Parse("03:04 PM", strings.TrimSpace(clock))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := time.Date(c.Date.Year(), c.Date.Month(), c.Date.Day(), parsed.Hour(), parsed.Minute(), 0, 0, location)
+
+	return local.In(c.Date.Location()), nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWeatherAPIAstronomyTimezone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"location":{"name":"Maringa","tz_id":"America/Sao_Paulo"},"astronomy":{"astro":{"sunrise":"06:58 AM","sunset":"05:35 PM","moon_phase":"Waxing Gibbous","moon_illumination":96}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("WEATHERAPI_URL", server.URL)
+
+	// a data pedida no fuso do cliente (?tz=Asia/Tokyo); os horários são os da localidade
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2024, time.June, 20, 0, 0, 0, 0, tokyo)
+
+	output, err := NewWeatherAPIAstronomyService("key", "-23.4,-51.9", date).Search(context.Background())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	if want := time.Date(2024, time.June, 20, 9, 58, 0, 0, time.UTC); !output.Sunrise.Equal(want) {
+		t.Errorf("nascer do sol = %s, esperado %s", output.Sunrise, want)
+	}
+	if want := time.Date(2024, time.June, 20, 20, 35, 0, 0, time.UTC); !output.Sunset.Equal(want) {
+		t.Errorf("pôr do sol = %s, esperado %s", output.Sunset, want)
+	}
+	if output.Sunrise.Location() != tokyo {
+		t.Errorf("fuso do nascer do sol = %s, esperado o da consulta", output.Sunrise.Location())
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
+	"github.com/valyala/fastjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WeatherAPIForecast struct {
+	key        string
+	Localidade string
+	Days       int
+	Lang       string
+}
+
+func NewWeatherAPIForecastService(key string, localidade string, days int, lang string) *WeatherAPIForecast {
+	return &WeatherAPIForecast{
+		key:        key,
+		Localidade: localidade,
+		Days:       days,
+		Lang:       lang,
+	}
+}
+
+// Search - previsão diária pelo local (forecast.json)
+func (c *WeatherAPIForecast) Search(ctx context.Context) (output []dto.ForecastDay, err error) {
+	ctx, spanRequest := tracer.Start(ctx, "service_weatherAPI_forecast_request")
+	defer spanRequest.End()
+
+	spanRequest.AddEvent("new client http")
+	var client = upstreamClient(metrics.ProviderWeatherAPI)
+
+	if c.key == "" {
+		err = apperror.New(apperror.CodeMissingKey, "chave de acesso não informada")
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "key[WEATHER_API_KEY] not found")
+		return output, err
+	}
+
+	spanRequest.AddEvent(
+		"localidade to search",
+		trace.WithAttributes(attribute.String("localidade", c.Localidade), attribute.Int("days", c.Days)),
+	)
+	url := weatherAPIURL() + "/v1/forecast.json?key=" + c.key + "&q=" + c.Localidade + "&days=" + strconv.Itoa(c.Days)
+	if c.Lang != "" {
+		// a WeatherAPI traduz o texto da condição pelo parâmetro lang
+		url += "&lang=" + c.Lang
+	}
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on search")
+		return output, upstreamRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	spanRequest.AddEvent("read response")
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on read response")
+		return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao ler informações", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = weatherAPIError(resp.StatusCode, respBody)
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "response error")
+		return output, err
+	}
+
+	spanRequest.AddEvent("parse response")
+	var p fastjson.Parser
+	v, err := p.Parse(string(respBody))
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on parse response")
+		return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "ocorreu um erro, ao tratar informações", err)
+	}
+
+	for _, forecastDay := range v.GetArray("forecast", "forecastday") {
+		day := forecastDay.Get("day")
+		if day == nil {
+			continue
+		}
+
+		output = append(output, dto.ForecastDay{
+			Date:          string(forecastDay.GetStringBytes("date")),
+			MaxTempC:      day.GetFloat64("maxtemp_c"),
+			MinTempC:      day.GetFloat64("mintemp_c"),
+			AvgTempC:      day.GetFloat64("avgtemp_c"),
+			MaxWindSpeed:  day.GetFloat64("maxwind_kph"),
+			Precipitation: day.GetFloat64("totalprecip_mm"),
+			Humidity:      day.GetFloat64("avghumidity"),
+			ChanceOfRain:  day.GetFloat64("daily_chance_of_rain"),
+			UV:            day.GetFloat64("uv"),
+			Condition:     string(day.GetStringBytes("condition", "text")),
+		})
+	}
+
+	spanRequest.AddEvent("response success", trace.WithAttributes(attribute.Int("days", len(output))))
+
+	return output, nil
+}
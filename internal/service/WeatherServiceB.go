@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/traceid"
+	"github.com/go-chi/transport"
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrServiceBProblem - o serviço B respondeu um problem+json; a mensagem do erro é o detail
+	// recebido, já no idioma pedido
+	ErrServiceBProblem = errors.New("erro devolvido pelo serviço B")
+	// ErrServiceBResponse - resposta do serviço B fora do contrato
+	ErrServiceBResponse = errors.New("resposta inesperada do serviço B")
+)
+
+// serviceBTransport - montado uma única vez: propaga o contexto de trace (W3C traceparent), o
+// TraceId do go-chi e identifica o cliente no User-Agent
+var serviceBTransport = transport.Chain(
+	otelhttp.NewTransport(http.DefaultTransport),
+	transport.SetHeader("User-Agent", "my-app/v1.0.0"),
+	traceid.Transport,
+)
+
+type WeatherServiceB struct {
+	host   string
+	client *http.Client
+}
+
+// NewWeatherServiceB - cliente do serviço B (weather_api) no endereço host, ex.: http://weather_api:8081
+func NewWeatherServiceB(host string) *WeatherServiceB {
+	return &WeatherServiceB{
+		host: strings.TrimSuffix(host, "/"),
+		client: &http.Client{
+			Transport: meteredTransport{provider: metrics.ProviderServiceB, next: serviceBTransport},
+			Timeout:   30 * time.Second,
+		},
+	}
+}
+
+// Search - clima nas coordenadas pela rota GET /v1/weather/coordinates do serviço B, repassando as
+// opções da query (unidades, qualidade do ar) e o idioma
+func (c *WeatherServiceB) Search(ctx context.Context, latitude string, longitude string, query url.Values, lang string) (output dto.WeatherOutput, err error) {
+	ctx, spanRequest := tracer.Start(ctx, "service_B_request")
+	defer spanRequest.End()
+
+	spanRequest.AddEvent("prepare request")
+	values := url.Values{}
+	for key, value := range query {
+		values[key] = value
+	}
+	values.Set("lat", latitude)
+	values.Set("lon", longitude)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+"/v1/weather/coordinates?"+values.Encode(), nil)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error on create request")
+		return output, apperror.Wrap(apperror.CodeInternal, "falha ao montar requisição ao serviço B", err)
+	}
+	// o serviço B sempre responde JSON para este cliente; o formato pedido pelo usuário é aplicado no serviço A
+	req.Header.Set("Accept", "application/json")
+	if lang != "" {
+		req.Header.Set("Accept-Language", lang)
+	}
+
+	spanRequest.AddEvent("try request service B", trace.WithAttributes(attribute.String("url", req.URL.String())))
+	resp, err := c.client.Do(req)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "request error service B")
+		return output, upstreamRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	spanRequest.AddEvent("read data response service B")
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		spanRequest.RecordError(err)
+		spanRequest.SetStatus(codes.Error, "error read body service B")
+		return output, upstreamRequestError(err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		if err := json.Unmarshal(respBody, &output); err != nil {
+			spanRequest.RecordError(err)
+			spanRequest.SetStatus(codes.Error, "error parse body data service B")
+			return output, apperror.Wrap(apperror.CodeUpstreamUnavailable, "falha ao interpretar resposta do serviço B: "+err.Error(), ErrServiceBResponse)
+		}
+
+		// a validade vem do serviço B, que conhece a observação e o próprio cache
+		if maxAge, ok := maxAgeOf(resp.Header.Get("Cache-Control")); ok {
+			output.ExpiresAt = time.Now().Add(maxAge)
+		}
+
+		spanRequest.AddEvent("response service B success", trace.WithAttributes(attribute.Float64("temp_C", output.C)))
+		return output, nil
+	}
+
+	// o código estável do serviço B é preservado; quem responde refaz o problem com o próprio trace ID
+	problem := struct {
+		Code   string `json:"code"`
+		Detail string `json:"detail"`
+	}{}
+	if jsonErr := json.Unmarshal(respBody, &problem); jsonErr != nil || problem.Code == "" {
+		err = apperror.Wrap(apperror.CodeUpstreamUnavailable, fmt.Sprintf("resposta inesperada do serviço B, status: %d", resp.StatusCode), ErrServiceBResponse)
+	} else {
+		err = apperror.Wrap(apperror.Code(problem.Code), problem.Detail, ErrServiceBProblem)
+	}
+	spanRequest.RecordError(err)
+	spanRequest.SetStatus(codes.Error, "response service B error")
+
+	return output, err
+}
+
+// maxAgeOf - diretiva max-age do Cache-Control
+func maxAgeOf(cacheControl string) (time.Duration, bool) {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(directive), "max-age=")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	return 0, false
+}
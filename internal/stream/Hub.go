@@ -0,0 +1,161 @@
+// Package stream compartilha uma consulta periódica entre os assinantes de uma mesma chave
+// (ex.: CEP, idioma e unidades), para que N conexões abertas custem uma consulta por intervalo.
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer - escopo de instrumentação das consultas dos streams
+var tracer = otel.Tracer("github.com/nagahshi/pos_go_weather_otel/internal/stream")
+
+// Update - resultado de uma consulta: o valor ou o erro, e o contexto do span da consulta que o
+// produziu, para quem entrega a atualização correlacionar com o trace da busca
+type Update[V any] struct {
+	Value       V
+	Err         error
+	SpanContext trace.SpanContext
+}
+
+// FetchFunc - consulta executada a cada intervalo; o contexto é cancelado quando o último assinante sai
+type FetchFunc[V any] func(ctx context.Context) (V, error)
+
+type topic[V any] struct {
+	key         string
+	subscribers map[chan Update[V]]struct{}
+	last        *Update[V]
+	cancel      context.CancelFunc
+	// link - span de quem iniciou a rotina, referenciado por todas as consultas
+	link trace.Link
+}
+
+// Hub - uma rotina de consulta por chave, iniciada com o primeiro assinante e encerrada com o último
+type Hub[V any] struct {
+	mu       sync.Mutex
+	interval time.Duration
+	topics   map[string]*topic[V]
+}
+
+// NewHub - cria o hub consultando cada chave a cada interval
+func NewHub[V any](interval time.Duration) *Hub[V] {
+	return &Hub[V]{
+		interval: interval,
+		topics:   map[string]*topic[V]{},
+	}
+}
+
+// Interval - intervalo entre as consultas
+func (h *Hub[V]) Interval() time.Duration {
+	return h.interval
+}
+
+// Subscribe - assina as atualizações da chave; fetch só é usado quando a chave ainda não tem rotina.
+// O canal guarda apenas a atualização mais recente: um assinante lento perde as intermediárias, mas
+// não atrasa os demais. O assinante recebe de imediato a última atualização conhecida, se houver.
+// A função devolvida cancela a assinatura e pode ser chamada mais de uma vez.
+//
+// A rotina sobrevive a quem a iniciou, então cada consulta é um trace próprio (stream_poll) com
+// link para o span em ctx do primeiro assinante.
+func (h *Hub[V]) Subscribe(ctx context.Context, key string, fetch FetchFunc[V]) (<-chan Update[V], func()) {
+	updates := make(chan Update[V], 1)
+
+	h.mu.Lock()
+	t, ok := h.topics[key]
+	if !ok {
+		pollCtx, cancel := context.WithCancel(context.Background())
+		t = &topic[V]{
+			key:         key,
+			subscribers: map[chan Update[V]]struct{}{},
+			cancel:      cancel,
+			link:        trace.LinkFromContext(ctx),
+		}
+		h.topics[key] = t
+		go h.poll(pollCtx, t, fetch)
+	}
+	t.subscribers[updates] = struct{}{}
+	if t.last != nil {
+		updates <- *t.last
+	}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(t.subscribers, updates)
+			if len(t.subscribers) == 0 {
+				t.cancel()
+				if h.topics[key] == t {
+					delete(h.topics, key)
+				}
+			}
+		})
+	}
+
+	return updates, unsubscribe
+}
+
+// Subscribers - quantidade de assinantes da chave
+func (h *Hub[V]) Subscribers(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.topics[key]; ok {
+		return len(t.subscribers)
+	}
+
+	return 0
+}
+
+// poll - consulta de imediato e depois a cada intervalo, até o cancelamento
+func (h *Hub[V]) poll(ctx context.Context, t *topic[V], fetch FetchFunc[V]) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		fetchCtx, spanPoll := tracer.Start(
+			ctx,
+			"stream_poll",
+			trace.WithNewRoot(),
+			trace.WithLinks(t.link),
+			trace.WithAttributes(attribute.String("stream.key", t.key)),
+		)
+		value, err := fetch(fetchCtx)
+		if err != nil {
+			spanPoll.RecordError(err)
+			spanPoll.SetStatus(codes.Error, "error on fetch")
+		}
+		spanPoll.End()
+		if ctx.Err() != nil {
+			return
+		}
+
+		update := Update[V]{Value: value, Err: err, SpanContext: spanPoll.SpanContext()}
+		h.mu.Lock()
+		t.last = &update
+		for updates := range t.subscribers {
+			// descarta a atualização ainda não lida; só o hub escreve no canal, então o envio não bloqueia
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- update
+		}
+		h.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
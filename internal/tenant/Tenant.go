@@ -0,0 +1,158 @@
+// Package tenant guarda o cliente da requisição no baggage do OpenTelemetry, que é propagado junto
+// com o trace nas chamadas ao serviço B. Assim os spans e as métricas dos dois serviços podem ser
+// separados por cliente.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// Chaves do baggage com o tenant e o cliente da requisição
+const (
+	KeyTenant = "tenant.id"
+	KeyClient = "client.id"
+)
+
+// validID - identificador aceito no baggage; limita o tamanho e os caracteres, já que o valor vira
+// atributo de spans e de métricas
+var validID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Valid - indica se o identificador pode ir para o baggage
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// FromContext - tenant guardado no baggage do contexto, vazio quando não há
+func FromContext(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(KeyTenant).Value()
+}
+
+// ClientFromContext - cliente guardado no baggage do contexto, vazio quando não há
+func ClientFromContext(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(KeyClient).Value()
+}
+
+// NewContext - contexto com o tenant e o cliente no baggage, mantendo os demais membros; um valor
+// vazio mantém o que já estava no baggage
+func NewContext(ctx context.Context, tenantID string, clientID string) (context.Context, error) {
+	bag := baggage.FromContext(ctx)
+
+	for key, value := range map[string]string{KeyTenant: tenantID, KeyClient: clientID} {
+		if value == "" {
+			continue
+		}
+		if !Valid(value) {
+			return ctx, fmt.Errorf("invalid %s: %q", key, value)
+		}
+
+		member, err := baggage.NewMember(key, value)
+		if err != nil {
+			return ctx, err
+		}
+		bag, err = bag.SetMember(member)
+		if err != nil {
+			return ctx, err
+		}
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
+
+// Sanitize - contexto sem os valores inválidos de tenant e cliente vindos de fora no header
+// baggage, para que não virem atributos
+func Sanitize(ctx context.Context) context.Context {
+	bag := baggage.FromContext(ctx)
+
+	changed := false
+	for _, key := range []string{KeyTenant, KeyClient} {
+		if value := bag.Member(key).Value(); value != "" && !Valid(value) {
+			bag = bag.DeleteMember(key)
+			changed = true
+		}
+	}
+	if !changed {
+		return ctx
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// Discard - contexto sem o tenant e o cliente recebidos no header baggage, para requisições de
+// pares em que não se confia: qualquer um poderia se passar por outro tenant
+func Discard(ctx context.Context) context.Context {
+	bag := baggage.FromContext(ctx)
+	if bag.Member(KeyTenant).Value() == "" && bag.Member(KeyClient).Value() == "" {
+		return ctx
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag.DeleteMember(KeyTenant).DeleteMember(KeyClient))
+}
+
+// ParseAPIKeys - chaves de API e seus tenants, no formato chave=tenant separado por vírgulas
+// (ex.: TENANT_API_KEYS=abc123=acme,def456=globex)
+func ParseAPIKeys(value string) (map[string]string, error) {
+	keys := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, tenantID, ok := strings.Cut(entry, "=")
+		key, tenantID = strings.TrimSpace(key), strings.TrimSpace(tenantID)
+		if !ok || key == "" || !Valid(tenantID) {
+			return nil, fmt.Errorf("invalid api key entry: want key=tenant")
+		}
+		keys[key] = tenantID
+	}
+
+	return keys, nil
+}
+
+// ParseTrustedPeers - endereços dos pares que podem informar o tenant no header X-Tenant-ID ou no
+// baggage, como o serviço A para o serviço B: IPs ou faixas CIDR separados por vírgulas (ex.:
+// TENANT_TRUSTED_PEERS=172.28.0.10,10.0.0.0/8)
+func ParseTrustedPeers(value string) ([]netip.Prefix, error) {
+	var peers []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			peers = append(peers, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted peer %q: want an IP or a CIDR", entry)
+		}
+		peers = append(peers, prefix.Masked())
+	}
+
+	return peers, nil
+}
+
+// ParseIDs - tenants separados por vírgulas (ex.: TENANT_IDS=acme,globex)
+func ParseIDs(value string) ([]string, error) {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if !Valid(id) {
+			return nil, fmt.Errorf("invalid tenant %q", id)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
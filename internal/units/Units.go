@@ -0,0 +1,279 @@
+// Package units trata a negociação de unidades, precisão decimal e modo de arredondamento
+// das respostas de clima. Os valores internos são sempre °C, km/h e hPa.
+package units
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/meteorology"
+)
+
+type TemperatureUnit string
+
+const (
+	Celsius    TemperatureUnit = "C"
+	Fahrenheit TemperatureUnit = "F"
+	Kelvin     TemperatureUnit = "K"
+	Rankine    TemperatureUnit = "R"
+)
+
+type WindUnit string
+
+const (
+	KilometersPerHour WindUnit = "km/h"
+	MilesPerHour      WindUnit = "mph"
+)
+
+type PressureUnit string
+
+const (
+	Hectopascal     PressureUnit = "hPa"
+	InchesOfMercury PressureUnit = "inHg"
+)
+
+type RoundingMode string
+
+const (
+	HalfUp   RoundingMode = "half_up"
+	HalfEven RoundingMode = "half_even"
+	Floor    RoundingMode = "floor"
+	Ceil     RoundingMode = "ceil"
+	Truncate RoundingMode = "truncate"
+)
+
+const (
+	PresetMetric   = "metric"
+	PresetImperial = "imperial"
+
+	DefaultPrecision = 2
+	MaxPrecision     = 6
+)
+
+var (
+	ErrInvalidUnit      = errors.New("unidade de temperatura inválida, use C, F, K ou R")
+	ErrInvalidPreset    = errors.New("preset de unidades inválido, use metric ou imperial")
+	ErrInvalidPrecision = errors.New("precisão inválida, use um inteiro entre 0 e 6")
+	ErrInvalidRounding  = errors.New("modo de arredondamento inválido, use half_up, half_even, floor, ceil ou truncate")
+)
+
+// Options - unidades e formatação escolhidas para a resposta
+type Options struct {
+	Temperature TemperatureUnit
+	Wind        WindUnit
+	Pressure    PressureUnit
+	Precision   int
+	Rounding    RoundingMode
+}
+
+// Default - preset métrico com duas casas decimais e arredondamento half_up
+func Default() Options {
+	options, _ := Preset(PresetMetric)
+
+	return options
+}
+
+// Preset - opções completas para os presets metric e imperial
+func Preset(name string) (Options, error) {
+	switch strings.ToLower(name) {
+	case PresetMetric:
+		return Options{Temperature: Celsius, Wind: KilometersPerHour, Pressure: Hectopascal, Precision: DefaultPrecision, Rounding: HalfUp}, nil
+	case PresetImperial:
+		return Options{Temperature: Fahrenheit, Wind: MilesPerHour, Pressure: InchesOfMercury, Precision: DefaultPrecision, Rounding: HalfUp}, nil
+	}
+
+	return Options{}, ErrInvalidPreset
+}
+
+// Parse - monta as opções a partir dos valores brutos; valores vazios mantêm o padrão.
+// O preset é aplicado primeiro e as demais opções sobrescrevem o que ele definiu.
+func Parse(preset string, temperature string, precision string, rounding string) (options Options, err error) {
+	options = Default()
+
+	if preset != "" {
+		if options, err = Preset(preset); err != nil {
+			return options, err
+		}
+	}
+
+	if temperature != "" {
+		switch unit := TemperatureUnit(strings.ToUpper(temperature)); unit {
+		case Celsius, Fahrenheit, Kelvin, Rankine:
+			options.Temperature = unit
+		default:
+			return options, ErrInvalidUnit
+		}
+	}
+
+	if precision != "" {
+		value, err := strconv.Atoi(precision)
+		if err != nil || value < 0 || value > MaxPrecision {
+			return options, ErrInvalidPrecision
+		}
+		options.Precision = value
+	}
+
+	if rounding != "" {
+		switch mode := RoundingMode(strings.ToLower(rounding)); mode {
+		case HalfUp, HalfEven, Floor, Ceil, Truncate:
+			options.Rounding = mode
+		default:
+			return options, ErrInvalidRounding
+		}
+	}
+
+	return options, nil
+}
+
+// Query - serializa as opções como query string, para repassar a outro serviço
+func (o Options) Query() url.Values {
+	query := url.Values{}
+	query.Set("units", string(o.Temperature))
+	query.Set("precision", strconv.Itoa(o.Precision))
+	query.Set("rounding", string(o.Rounding))
+	if o.Wind == MilesPerHour {
+		query.Set("preset", PresetImperial)
+	}
+
+	return query
+}
+
+// Round - arredonda na precisão e modo escolhidos.
+// O cálculo é feito em base 10 sobre os 15 dígitos significativos que um float64 garante, então
+// o ruído de 81.50000000000001 é descartado e 2.675 arredonda para 2.68, como seria esperado.
+func (o Options) Round(value float64) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', 15, 64))
+	if !ok {
+		return value
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(o.Precision)), nil)
+	rat.Mul(rat, new(big.Rat).SetInt(scale))
+
+	quotient, remainder := new(big.Int).QuoRem(rat.Num(), rat.Denom(), new(big.Int))
+	if remainder.Sign() != 0 {
+		// compara 2*|resto| com o denominador para saber se passou da metade
+		half := new(big.Int).Abs(remainder)
+		half.Mul(half, big.NewInt(2))
+		cmp := half.Cmp(rat.Denom())
+		negative := remainder.Sign() < 0
+
+		awayFromZero := false
+		switch o.Rounding {
+		case HalfEven:
+			awayFromZero = cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1)
+		case Floor:
+			awayFromZero = negative
+		case Ceil:
+			awayFromZero = !negative
+		case Truncate:
+			awayFromZero = false
+		default:
+			awayFromZero = cmp >= 0
+		}
+
+		if awayFromZero {
+			if negative {
+				quotient.Sub(quotient, big.NewInt(1))
+			} else {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	}
+
+	rounded, _ := strconv.ParseFloat(new(big.Rat).SetFrac(quotient, scale).FloatString(o.Precision), 64)
+
+	return rounded
+}
+
+// ConvertTemperature - converte um valor em °C para a unidade escolhida
+func (o Options) ConvertTemperature(tempC float64) float64 {
+	switch o.Temperature {
+	case Fahrenheit:
+		return meteorology.CelsiusToFahrenheit(tempC)
+	case Kelvin:
+		return meteorology.CelsiusToKelvin(tempC)
+	case Rankine:
+		return meteorology.CelsiusToKelvin(tempC) * 1.8
+	}
+
+	return tempC
+}
+
+// TemperatureToCelsius - inverso de ConvertTemperature, para valores já expressos na unidade informada
+func TemperatureToCelsius(unit TemperatureUnit, value float64) float64 {
+	switch unit {
+	case Fahrenheit:
+		return meteorology.FahrenheitToCelsius(value)
+	case Kelvin:
+		return value - 273.15
+	case Rankine:
+		return (value - 491.67) * 5 / 9
+	}
+
+	return value
+}
+
+// ConvertWindSpeed - converte um valor em km/h para a unidade escolhida
+func (o Options) ConvertWindSpeed(kph float64) float64 {
+	if o.Wind == MilesPerHour {
+		return kph / 1.609344
+	}
+
+	return kph
+}
+
+// ConvertPressure - converte um valor em hPa para a unidade escolhida
+func (o Options) ConvertPressure(hPa float64) float64 {
+	if o.Pressure == InchesOfMercury {
+		return hPa / 33.8638866667
+	}
+
+	return hPa
+}
+
+// Apply - converte e arredonda a saída de clima conforme as opções.
+// temp_C, temp_F e temp_K seguem sempre presentes; temp_R só aparece quando Rankine é escolhido.
+func Apply(options Options, output *dto.WeatherOutput) {
+	tempC := output.C
+	output.C = options.Round(tempC)
+	output.F = options.Round(meteorology.CelsiusToFahrenheit(tempC))
+	output.K = options.Round(meteorology.CelsiusToKelvin(tempC))
+	if options.Temperature == Rankine {
+		output.R = options.Round(meteorology.CelsiusToKelvin(tempC) * 1.8)
+	}
+
+	output.FeelsLike = options.Round(options.ConvertTemperature(output.FeelsLike))
+	output.HeatIndex = options.Round(options.ConvertTemperature(output.HeatIndex))
+	output.WindChill = options.Round(options.ConvertTemperature(output.WindChill))
+	// ponteiros novos: os atuais podem ser compartilhados com a entrada do cache
+	output.DewPoint = options.convertOptional(output.DewPoint)
+	output.Humidex = options.convertOptional(output.Humidex)
+	output.WindSpeed = options.Round(options.ConvertWindSpeed(output.WindSpeed))
+	output.Pressure = options.Round(options.ConvertPressure(output.Pressure))
+
+	output.Units = &dto.Units{
+		Temperature: string(options.Temperature),
+		Wind:        string(options.Wind),
+		Pressure:    string(options.Pressure),
+	}
+}
+
+// convertOptional - temperatura opcional convertida e arredondada em um novo ponteiro, sem alterar o original
+func (o Options) convertOptional(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	converted := o.Round(o.ConvertTemperature(*value))
+
+	return &converted
+}
@@ -0,0 +1,141 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value     float64
+		precision int
+		rounding  RoundingMode
+		want      float64
+	}{
+		// empates: half_up se afasta do zero, half_even vai para o dígito par
+		{2.5, 0, HalfUp, 3},
+		{-2.5, 0, HalfUp, -3},
+		{2.5, 0, HalfEven, 2},
+		{3.5, 0, HalfEven, 4},
+		{-2.5, 0, HalfEven, -2},
+		{-3.5, 0, HalfEven, -4},
+		{0.125, 2, HalfUp, 0.13},
+		{0.125, 2, HalfEven, 0.12},
+		{0.135, 2, HalfEven, 0.14},
+		// a representação binária de 2.675 fica abaixo do empate; os 15 dígitos significativos o recuperam
+		{2.675, 2, HalfUp, 2.68},
+		{2.665, 2, HalfEven, 2.66},
+		{81.50000000000001, 0, HalfEven, 82},
+		{81.50000000000001, 0, HalfUp, 82},
+		// fora do empate os dois modos concordam
+		{2.51, 0, HalfEven, 3},
+		{-2.49, 0, HalfUp, -2},
+		{1.234567, 4, HalfUp, 1.2346},
+		// direção fixa, com negativos
+		{2.71, 1, Floor, 2.7},
+		{-2.71, 1, Floor, -2.8},
+		{2.71, 1, Ceil, 2.8},
+		{-2.71, 1, Ceil, -2.7},
+		{2.79, 1, Truncate, 2.7},
+		{-2.79, 1, Truncate, -2.7},
+		// valores exatos não mudam em nenhum modo
+		{-40, 2, Floor, -40},
+		{21.25, 2, Ceil, 21.25},
+		{0, 6, HalfEven, 0},
+		// modo vazio segue half_up
+		{0.5, 0, "", 1},
+	}
+
+	for _, test := range tests {
+		options := Options{Precision: test.precision, Rounding: test.rounding}
+		if got := options.Round(test.value); got != test.want {
+			t.Errorf("Round(%v) com precisão %d e %q = %v, esperado %v", test.value, test.precision, test.rounding, got, test.want)
+		}
+	}
+}
+
+func TestRoundNonFinite(t *testing.T) {
+	options := Default()
+
+	if got := options.Round(math.NaN()); !math.IsNaN(got) {
+		t.Errorf("Round(NaN) = %v, esperado NaN", got)
+	}
+	for _, value := range []float64{math.Inf(1), math.Inf(-1)} {
+		if got := options.Round(value); got != value {
+			t.Errorf("Round(%v) = %v", value, got)
+		}
+	}
+}
+
+func TestPreset(t *testing.T) {
+	tests := []struct {
+		name string
+		want Options
+		err  error
+	}{
+		{"metric", Options{Temperature: Celsius, Wind: KilometersPerHour, Pressure: Hectopascal, Precision: DefaultPrecision, Rounding: HalfUp}, nil},
+		{"IMPERIAL", Options{Temperature: Fahrenheit, Wind: MilesPerHour, Pressure: InchesOfMercury, Precision: DefaultPrecision, Rounding: HalfUp}, nil},
+		{"si", Options{}, ErrInvalidPreset},
+		{"", Options{}, ErrInvalidPreset},
+	}
+
+	for _, test := range tests {
+		got, err := Preset(test.name)
+		if !errors.Is(err, test.err) || got != test.want {
+			t.Errorf("Preset(%q) = %+v, %v, esperado %+v, %v", test.name, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		preset      string
+		temperature string
+		precision   string
+		rounding    string
+		want        Options
+		err         error
+	}{
+		{name: "padrão", want: Default()},
+		{
+			name:   "preset imperial",
+			preset: "imperial",
+			want:   Options{Temperature: Fahrenheit, Wind: MilesPerHour, Pressure: InchesOfMercury, Precision: 2, Rounding: HalfUp},
+		},
+		{
+			name:        "opções sobrescrevem o preset",
+			preset:      "imperial",
+			temperature: "k",
+			precision:   "0",
+			rounding:    "HALF_EVEN",
+			want:        Options{Temperature: Kelvin, Wind: MilesPerHour, Pressure: InchesOfMercury, Precision: 0, Rounding: HalfEven},
+		},
+		{
+			name:        "rankine no preset métrico",
+			temperature: "R",
+			precision:   "6",
+			rounding:    "truncate",
+			want:        Options{Temperature: Rankine, Wind: KilometersPerHour, Pressure: Hectopascal, Precision: 6, Rounding: Truncate},
+		},
+		{name: "preset inválido", preset: "nautical", err: ErrInvalidPreset},
+		{name: "unidade inválida", temperature: "X", err: ErrInvalidUnit},
+		{name: "precisão negativa", precision: "-1", err: ErrInvalidPrecision},
+		{name: "precisão acima do máximo", precision: "7", err: ErrInvalidPrecision},
+		{name: "precisão não numérica", precision: "2.5", err: ErrInvalidPrecision},
+		{name: "arredondamento inválido", rounding: "bankers", err: ErrInvalidRounding},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.preset, test.temperature, test.precision, test.rounding)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("err = %v, esperado %v", err, test.err)
+			}
+			if err == nil && got != test.want {
+				t.Errorf("opções = %+v, esperado %+v", got, test.want)
+			}
+		})
+	}
+}
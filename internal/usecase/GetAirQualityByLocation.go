@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"github.com/nagahshi/pos_go_weather_otel/internal/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	AirQualityProviderWeatherAPI = "weatherapi"
+	AirQualityProviderOpenMeteo  = "open-meteo"
+)
+
+type GetAirQualityUseCase struct {
+	key      string
+	provider string
+}
+
+func NewGetAirQualityUseCase(key string, provider string) *GetAirQualityUseCase {
+	if provider == "" {
+		provider = AirQualityProviderWeatherAPI
+	}
+
+	return &GetAirQualityUseCase{
+		key:      key,
+		provider: strings.ToLower(provider),
+	}
+}
+
+// InWeatherResponse - se a qualidade do ar vem na própria consulta de clima (aqi=yes na WeatherAPI),
+// sem uma segunda requisição ao provedor
+func (c *GetAirQualityUseCase) InWeatherResponse() bool {
+	return c.provider != AirQualityProviderOpenMeteo
+}
+
+// Execute - busca da qualidade do ar pelas coordenadas
+func (c *GetAirQualityUseCase) Execute(ctx context.Context, airQualityInput dto.AirQualityInput) (output dto.AirQualityOutput, err error) {
+	ctx, spanSearch := tracer.Start(ctx, "service_search_air_quality")
+	defer spanSearch.End()
+
+	spanSearch.AddEvent(
+		"air quality input",
+		trace.WithAttributes(
+			attribute.String("latitude", airQualityInput.Latitude),
+			attribute.String("longitude", airQualityInput.Longitude),
+			attribute.String("provider", c.provider),
+		),
+	)
+
+	if airQualityInput.Latitude == "" || airQualityInput.Longitude == "" {
+		err = apperror.New(apperror.CodeInvalidRequest, "coordenadas não informadas")
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "coordinates not informed")
+		return output, err
+	}
+
+	if c.provider == AirQualityProviderOpenMeteo {
+		spanSearch.AddEvent("try search open-meteo")
+		output, err = service.NewOpenMeteoAirQualityService(airQualityInput.Latitude, airQualityInput.Longitude).Search(ctx)
+	} else {
+		spanSearch.AddEvent("try search weatherapi")
+		output, err = c.searchWeatherAPI(ctx, airQualityInput)
+	}
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search")
+		return output, err
+	}
+
+	output.Category = i18n.AirQualityCategory(i18n.Default, output.USEPAIndex)
+
+	spanSearch.AddEvent(
+		"search success",
+		trace.WithAttributes(
+			attribute.Int("us_epa_index", output.USEPAIndex),
+			attribute.String("category", output.Category),
+		),
+	)
+
+	return output, nil
+}
+
+// searchWeatherAPI - qualidade do ar pela consulta de clima da WeatherAPI com aqi=yes
+func (c *GetAirQualityUseCase) searchWeatherAPI(ctx context.Context, airQualityInput dto.AirQualityInput) (dto.AirQualityOutput, error) {
+	srvc := service.NewWeatherAPIService(c.key, airQualityInput.Latitude+","+airQualityInput.Longitude, "")
+	srvc.AirQuality = true
+	weather, err := srvc.Search(ctx)
+	if err != nil {
+		return dto.AirQualityOutput{}, err
+	}
+	if weather.AirQuality == nil {
+		return dto.AirQualityOutput{}, apperror.New(apperror.CodeUpstreamUnavailable, "qualidade do ar não disponível para o local")
+	}
+
+	return *weather.AirQuality, nil
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGetWeatherAirQualityInSameRequest(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("aqi") != "yes" {
+			t.Errorf("consulta sem aqi=yes: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"location":{"name":"Maringa"},"current":{"temp_c":27.5,"humidity":60,"condition":{"text":"Sunny"},"air_quality":{"pm2_5":12.4,"pm10":20.1,"o3":50,"no2":8.2,"us-epa-index":2,"gb-defra-index":3}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("WEATHERAPI_URL", server.URL)
+
+	output, err := NewGetWeatherUseCase("key", 0).Execute(context.Background(), dto.WeatherInput{Latitude: "-23.4", Longitude: "-51.9", AirQuality: true})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d consultas à WeatherAPI, esperado 1", n)
+	}
+	if output.AirQuality == nil {
+		t.Fatal("resposta sem air_quality")
+	}
+	if aq := *output.AirQuality; aq.PM25 != 12.4 || aq.USEPAIndex != 2 || aq.GBDefraIndex != 3 || aq.Provider != "weatherapi" || aq.Category == "" {
+		t.Errorf("air_quality = %+v", aq)
+	}
+}
+
+func TestGetAirQualityRequiresCoordinates(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+	t.Setenv("WEATHERAPI_URL", server.URL)
+
+	for _, provider := range []string{AirQualityProviderWeatherAPI, AirQualityProviderOpenMeteo} {
+		for _, input := range []dto.AirQualityInput{{}, {Latitude: "-23.4"}, {Longitude: "-51.9"}} {
+			_, err := NewGetAirQualityUseCase("key", provider).Execute(context.Background(), input)
+			if apperror.CodeOf(err) != apperror.CodeInvalidRequest {
+				t.Errorf("%s com %+v: err = %v, esperado %s", provider, input, err, apperror.CodeInvalidRequest)
+			}
+		}
+	}
+
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d consultas ao provedor sem coordenadas", n)
+	}
+}
+
+// TestGetWeatherAirQualitySpan - com a WeatherAPI, a leitura da qualidade do ar tem span próprio sob o da
+// consulta de clima, com ou sem a medição do local. O provedor de trace global só é definido aqui.
+func TestGetWeatherAirQualitySpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	tests := []struct {
+		name       string
+		body       string
+		event      string
+		airQuality bool
+	}{
+		{
+			name:       "com medição",
+			body:       `{"location":{"name":"Maringa"},"current":{"temp_c":27.5,"air_quality":{"pm2_5":12.4,"us-epa-index":2}}}`,
+			event:      "air quality found",
+			airQuality: true,
+		},
+		{
+			name:  "sem medição",
+			body:  `{"location":{"name":"Maringa"},"current":{"temp_c":27.5}}`,
+			event: "air quality not available",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+			t.Setenv("WEATHERAPI_URL", server.URL)
+
+			// latitude própria em cada caso, para não reaproveitar o cache
+			latitude := []string{"-23.41", "-23.42"}[i]
+			output, err := NewGetWeatherUseCase("key", 0).Execute(context.Background(), dto.WeatherInput{Latitude: latitude, Longitude: "-51.9", AirQuality: true})
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if (output.AirQuality != nil) != test.airQuality {
+				t.Errorf("air_quality = %+v, esperado presente %v", output.AirQuality, test.airQuality)
+			}
+
+			spans := recorder.Ended()
+			var request, search sdktrace.ReadOnlySpan
+			for _, span := range spans[len(spans)-4:] {
+				switch span.Name() {
+				case "service_weatherAPI_request":
+					request = span
+				case "air_quality_search":
+					search = span
+				}
+			}
+			if request == nil || search == nil {
+				t.Fatalf("spans sem service_weatherAPI_request e air_quality_search")
+			}
+			if search.Parent().SpanID() != request.SpanContext().SpanID() {
+				t.Error("air_quality_search fora do span da consulta à WeatherAPI")
+			}
+			if events := search.Events(); len(events) != 1 || events[0].Name != test.event {
+				t.Errorf("eventos de air_quality_search = %v, esperado %q", events, test.event)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/astronomy"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type GetAstronomyUseCase struct {
+	key string
+}
+
+func NewGetAstronomyUseCase(key string) *GetAstronomyUseCase {
+	return &GetAstronomyUseCase{
+		key: key,
+	}
+}
+
+// Execute - calcula nascer e pôr do sol, duração do dia e fase da lua pela latitude e longitude
+func (c *GetAstronomyUseCase) Execute(ctx context.Context, astronomyInput dto.AstronomyInput) (output dto.AstronomyOutput, err error) {
+	ctx, spanCalculate := tracer.Start(ctx, "calculate_astronomy")
+	defer spanCalculate.End()
+
+	spanCalculate.AddEvent(
+		"astronomy input",
+		trace.WithAttributes(
+			attribute.String("latitude", astronomyInput.Latitude),
+			attribute.String("longitude", astronomyInput.Longitude),
+			attribute.String("date", astronomyInput.Date.Format(time.DateOnly)),
+		),
+	)
+
+	latitude, errLat := strconv.ParseFloat(astronomyInput.Latitude, 64)
+	longitude, errLon := strconv.ParseFloat(astronomyInput.Longitude, 64)
+	if err = errors.Join(errLat, errLon); err != nil {
+		spanCalculate.RecordError(err)
+		spanCalculate.SetStatus(codes.Error, "error on parse location")
+		return output, apperror.Wrap(apperror.CodeLocationNotFound, "latitude e longitude inválidas para o cálculo", err)
+	}
+
+	spanCalculate.AddEvent("calculate sun and moon")
+	sun := astronomy.SunTimes(astronomyInput.Date, latitude, longitude)
+	// fase da lua considerada ao meio-dia local do dia consultado
+	date := astronomyInput.Date
+	moon := astronomy.MoonPhase(time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, date.Location()))
+
+	output.Date = date.Format(time.DateOnly)
+	output.Timezone = date.Location().String()
+	output.SolarNoon = sun.SolarNoon.Format(time.RFC3339)
+	if !sun.Sunrise.IsZero() {
+		output.Sunrise = sun.Sunrise.Format(time.RFC3339)
+		output.Sunset = sun.Sunset.Format(time.RFC3339)
+	}
+	output.DayLength = sun.DayLength.String()
+	output.DayLengthSeconds = int64(sun.DayLength.Seconds())
+	output.MoonPhase = moon.Phase
+	output.MoonIllumination = math.Round(moon.Illumination*1000) / 1000
+	output.MoonAgeDays = math.Round(moon.AgeDays*100) / 100
+
+	spanCalculate.AddEvent(
+		"calculate success",
+		trace.WithAttributes(
+			attribute.String("sunrise", output.Sunrise),
+			attribute.String("sunset", output.Sunset),
+			attribute.String("moon_phase", output.MoonPhase),
+		),
+	)
+
+	if !astronomyInput.CrossCheck {
+		return output, nil
+	}
+
+	// conferência opcional com a WeatherAPI, uma falha aqui não invalida o cálculo local
+	spanCalculate.AddEvent("try cross check")
+	srvc := service.NewWeatherAPIAstronomyService(c.key, astronomyInput.Latitude+","+astronomyInput.Longitude, date)
+	remote, err := srvc.Search(ctx)
+	if err != nil {
+		spanCalculate.RecordError(err)
+		spanCalculate.AddEvent("cross check not available")
+		return output, nil
+	}
+
+	output.CrossCheck = &dto.AstronomyCrossCheck{
+		Provider:         "weatherapi",
+		Sunrise:          remote.Sunrise.Format(time.RFC3339),
+		Sunset:           remote.Sunset.Format(time.RFC3339),
+		MoonPhase:        remote.MoonPhase,
+		MoonIllumination: remote.MoonIllumination,
+	}
+	if !sun.Sunrise.IsZero() {
+		output.CrossCheck.SunriseDiffMinutes = math.Round(sun.Sunrise.Sub(remote.Sunrise).Minutes()*10) / 10
+		output.CrossCheck.SunsetDiffMinutes = math.Round(sun.Sunset.Sub(remote.Sunset).Minutes()*10) / 10
+	}
+
+	spanCalculate.AddEvent(
+		"cross check success",
+		trace.WithAttributes(
+			attribute.String("sunrise_diff", fmt.Sprintf("%.1fm", output.CrossCheck.SunriseDiffMinutes)),
+			attribute.String("sunset_diff", fmt.Sprintf("%.1fm", output.CrossCheck.SunsetDiffMinutes)),
+		),
+	)
+
+	return output, nil
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type GetForecastUseCase struct {
+	key string
+}
+
+func NewGetForecastUseCase(key string) *GetForecastUseCase {
+	return &GetForecastUseCase{
+		key: key,
+	}
+}
+
+// Execute - previsão diária pela latitude e longitude
+func (c *GetForecastUseCase) Execute(ctx context.Context, forecastInput dto.ForecastInput) (output []dto.ForecastDay, err error) {
+	ctx, spanSearch := tracer.Start(ctx, "service_search_forecast")
+	defer spanSearch.End()
+
+	spanSearch.AddEvent(
+		"forecast input",
+		trace.WithAttributes(
+			attribute.String("latitude", forecastInput.Latitude),
+			attribute.String("longitude", forecastInput.Longitude),
+			attribute.Int("days", forecastInput.Days),
+		),
+	)
+
+	srvc := service.NewWeatherAPIForecastService(c.key, forecastInput.Latitude+","+forecastInput.Longitude, forecastInput.Days, forecastInput.Lang)
+	output, err = srvc.Search(ctx)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search")
+		return output, err
+	}
+
+	spanSearch.AddEvent("search success", trace.WithAttributes(attribute.Int("days", len(output))))
+
+	return output, nil
+}
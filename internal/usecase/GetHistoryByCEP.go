@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/history"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type GetHistoryByCEPUseCase struct {
+	store *history.Store
+}
+
+// NewGetHistoryByCEPUseCase - cria o usecase sobre o histórico local; store nil quando o serviço não
+// tem watchlist, e então nenhum CEP tem histórico
+func NewGetHistoryByCEPUseCase(store *history.Store) *GetHistoryByCEPUseCase {
+	return &GetHistoryByCEPUseCase{
+		store: store,
+	}
+}
+
+// Execute - leituras guardadas do CEP no período, agregadas em intervalos de input.Step
+func (c *GetHistoryByCEPUseCase) Execute(ctx context.Context, input dto.HistoryInput) (output dto.HistoryOutput, err error) {
+	ctx, spanSearch := tracer.Start(ctx, "search_history")
+	defer spanSearch.End()
+
+	spanSearch.AddEvent(
+		"history input",
+		trace.WithAttributes(
+			attribute.String("zipcode", input.CEP),
+			attribute.String("from", input.From.Format(time.RFC3339)),
+			attribute.String("to", input.To.Format(time.RFC3339)),
+			attribute.String("step", input.Step.String()),
+		),
+	)
+
+	if c.store == nil {
+		return output, apperror.New(apperror.CodeNotFound, "histórico desabilitado: watchlist vazia")
+	}
+
+	readings, err := c.store.Range(input.CEP, input.From, input.To)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on read history")
+		return output, apperror.Wrap(apperror.CodeInternal, "falha ao ler histórico", err)
+	}
+	if readings == nil {
+		spanSearch.AddEvent("zipcode without history")
+		return output, apperror.New(apperror.CodeNotFound, "CEP fora da watchlist: "+input.CEP)
+	}
+
+	output = dto.HistoryOutput{
+		CEP:    input.CEP,
+		From:   input.From.UTC().Format(time.RFC3339),
+		To:     input.To.UTC().Format(time.RFC3339),
+		Points: history.Downsample(readings, input.Step),
+	}
+	if input.Step > 0 {
+		output.Step = formatStep(input.Step)
+	}
+
+	spanSearch.AddEvent("search success", trace.WithAttributes(attribute.Int("readings", len(readings)), attribute.Int("points", len(output.Points))))
+
+	return output, nil
+}
+
+// formatStep - intervalo no formato aceito pela consulta: dias inteiros como 1d, os demais sem as
+// unidades zeradas (1h em vez de 1h0m0s)
+func formatStep(step time.Duration) string {
+	if step%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(step/(24*time.Hour))) + "d"
+	}
+
+	formatted := step.String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+
+	return formatted
+}
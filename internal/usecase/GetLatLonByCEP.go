@@ -5,8 +5,8 @@ import (
 
 	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
 	"github.com/nagahshi/pos_go_weather_otel/internal/service"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -18,7 +18,6 @@ func NewGetLatLonByCEPUseCase() *GetLatLonByCEP {
 
 // Execute - busca de latitude e longitude pelo CEP
 func (c *GetLatLonByCEP) Execute(ctx context.Context, CEP string) (output dto.CEPOutput, err error) {
-	tracer := otel.Tracer("useCase-GetLatLonByCEP-Execute")
 	ctx, spanSearch := tracer.Start(ctx, "service_search_zipcode")
 	defer spanSearch.End()
 
@@ -28,7 +27,8 @@ func (c *GetLatLonByCEP) Execute(ctx context.Context, CEP string) (output dto.CE
 	spanSearch.AddEvent("try search")
 	response, err := srvc.Search(ctx)
 	if err != nil {
-		spanSearch.AddEvent("error on search", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search")
 		return output, err
 	}
 
@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type GetWeatherByCEPUseCase struct {
+	getLatLonByCEP *GetLatLonByCEP
+	serviceB       *service.WeatherServiceB
+}
+
+// NewGetWeatherByCEPUseCase - cria o usecase do serviço A; hostServiceB é o endereço do serviço B
+func NewGetWeatherByCEPUseCase(hostServiceB string) *GetWeatherByCEPUseCase {
+	return &GetWeatherByCEPUseCase{
+		getLatLonByCEP: NewGetLatLonByCEPUseCase(),
+		serviceB:       service.NewWeatherServiceB(hostServiceB),
+	}
+}
+
+// Execute - localiza o CEP e busca o clima das coordenadas no serviço B, com a cidade do CEP; um CEP
+// sem coordenadas na BrasilAPI é ErrLocationNotFound, sem consulta ao serviço B
+func (c *GetWeatherByCEPUseCase) Execute(ctx context.Context, input dto.WeatherByCEPInput) (output dto.WeatherOutput, err error) {
+	ctx, spanSearch := tracer.Start(ctx, "search_weather_by_zipcode")
+	defer spanSearch.End()
+
+	spanSearch.AddEvent("search location by zipcode", trace.WithAttributes(attribute.String("zipcode", input.CEP)))
+	outputCEP, err := c.getLatLonByCEP.Execute(ctx, input.CEP)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search location")
+		return output, err
+	}
+	if outputCEP.Latitude == "" || outputCEP.Longitude == "" {
+		spanSearch.AddEvent("location without coordinates", trace.WithAttributes(attribute.String("zipcode", input.CEP)))
+		spanSearch.SetStatus(codes.Error, "error on search location")
+		return output, apperror.ErrLocationNotFound
+	}
+
+	spanSearch.AddEvent("search weather on service B")
+	output, err = c.serviceB.Search(ctx, outputCEP.Latitude, outputCEP.Longitude, input.Query, input.Lang)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search weather")
+		return output, err
+	}
+
+	// hidratando com a cidade do CEP
+	output.City = outputCEP.CIDADE
+
+	spanSearch.AddEvent(
+		"search success",
+		trace.WithAttributes(
+			attribute.String("city", output.City),
+			attribute.Float64("temp_C", output.C),
+		),
+	)
+
+	return output, nil
+}
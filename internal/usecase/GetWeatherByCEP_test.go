@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+func TestGetWeatherByCEPWithoutCoordinates(t *testing.T) {
+	tests := []struct {
+		name        string
+		coordinates string
+	}{
+		{name: "sem location", coordinates: ``},
+		{name: "sem latitude", coordinates: `,"location":{"coordinates":{"longitude":"-51.9"}}`},
+		{name: "sem longitude", coordinates: `,"location":{"coordinates":{"latitude":"-23.4"}}`},
+		{name: "coordenadas vazias", coordinates: `,"location":{"type":"Point","coordinates":{}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			brasilAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"cep":"87033080","city":"Maringá","state":"PR"` + test.coordinates + `}`))
+			}))
+			defer brasilAPI.Close()
+			t.Setenv("BRASILAPI_URL", brasilAPI.URL)
+
+			var calls atomic.Int32
+			serviceB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"city":"","temp_C":27.5}`))
+			}))
+			defer serviceB.Close()
+
+			_, err := NewGetWeatherByCEPUseCase(serviceB.URL).Execute(context.Background(), dto.WeatherByCEPInput{CEP: "87033080"})
+			if !errors.Is(err, apperror.ErrLocationNotFound) {
+				t.Errorf("err = %v, esperado ErrLocationNotFound", err)
+			}
+			if got := calls.Load(); got != 0 {
+				t.Errorf("serviço B consultado %d vezes, esperado nenhuma", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type GetWeatherByCoordinatesUseCase struct {
+	serviceB *service.WeatherServiceB
+}
+
+// NewGetWeatherByCoordinatesUseCase - cria o usecase do serviço A; hostServiceB é o endereço do serviço B
+func NewGetWeatherByCoordinatesUseCase(hostServiceB string) *GetWeatherByCoordinatesUseCase {
+	return &GetWeatherByCoordinatesUseCase{
+		serviceB: service.NewWeatherServiceB(hostServiceB),
+	}
+}
+
+// Execute - busca o clima das coordenadas no serviço B
+func (c *GetWeatherByCoordinatesUseCase) Execute(ctx context.Context, input dto.WeatherByCoordinatesInput) (output dto.WeatherOutput, err error) {
+	ctx, spanSearch := tracer.Start(ctx, "search_weather_by_coordinates")
+	defer spanSearch.End()
+
+	spanSearch.AddEvent(
+		"search weather on service B",
+		trace.WithAttributes(
+			attribute.String("latitude", input.Latitude),
+			attribute.String("longitude", input.Longitude),
+		),
+	)
+	output, err = c.serviceB.Search(ctx, input.Latitude, input.Longitude, input.Query, input.Lang)
+	if err != nil {
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search weather")
+		return output, err
+	}
+
+	spanSearch.AddEvent("search success", trace.WithAttributes(attribute.Float64("temp_C", output.C)))
+
+	return output, nil
+}
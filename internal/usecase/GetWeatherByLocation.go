@@ -2,35 +2,48 @@ package usecase
 
 import (
 	"context"
-	"errors"
+	"math"
 	"strings"
+	"time"
 
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/cache"
 	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/i18n"
+	"github.com/nagahshi/pos_go_weather_otel/internal/meteorology"
+	"github.com/nagahshi/pos_go_weather_otel/internal/metrics"
 	"github.com/nagahshi/pos_go_weather_otel/internal/service"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type GetWeatherUseCase struct {
-	key string
+	key   string
+	ttl   time.Duration
+	cache *cache.TTL[dto.WeatherOutput]
 }
 
-func NewGetWeatherUseCase(key string) *GetWeatherUseCase {
+// NewGetWeatherUseCase - cria o usecase; ttl é por quanto tempo uma observação é reaproveitada
+// por localidade e idioma, e zero desabilita o cache
+func NewGetWeatherUseCase(key string, ttl time.Duration) *GetWeatherUseCase {
 	return &GetWeatherUseCase{
-		key: key,
+		key:   key,
+		ttl:   ttl,
+		cache: cache.New[dto.WeatherOutput](ttl),
 	}
 }
 
 // Execute - busca de clima pelo local
 func (c *GetWeatherUseCase) Execute(ctx context.Context, weatherInput dto.WeatherInput) (output dto.WeatherOutput, err error) {
-	tracer := otel.Tracer("useCase-GetWeather-Execute")
 	ctx, spanSearch := tracer.Start(ctx, "service_search_weather")
 	defer spanSearch.End()
 
 	if c.key == "" {
-		spanSearch.AddEvent("key[WEATHER_API_KEY] not found")
-		return output, errors.New("chave de consulta [WEATHER_API_KEY] não encontrada")
+		err = apperror.New(apperror.CodeMissingKey, "chave de consulta [WEATHER_API_KEY] não encontrada")
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "key[WEATHER_API_KEY] not found")
+		return output, err
 	}
 
 	spanSearch.AddEvent(
@@ -50,22 +63,86 @@ func (c *GetWeatherUseCase) Execute(ctx context.Context, weatherInput dto.Weathe
 		local = strings.ToLower(weatherInput.CIDADE + "," + weatherInput.UF)
 	}
 
+	cacheKey := local + "|" + weatherInput.Lang
+	if weatherInput.AirQuality {
+		cacheKey += "|aqi"
+	}
+	cached, expiresAt, ok := c.cache.Get(cacheKey)
+	if c.cache.Enabled() {
+		metrics.RecordCache(ctx, "weather", ok)
+	}
+	if ok {
+		spanSearch.AddEvent("cache hit", trace.WithAttributes(attribute.String("cache_key", cacheKey)))
+		cached.ExpiresAt = c.freshUntil(cached, expiresAt)
+		return cached, nil
+	}
+
 	spanSearch.AddEvent("try search")
-	srvc := service.NewWeatherAPIService(c.key, local)
+	srvc := service.NewWeatherAPIService(c.key, local, weatherInput.Lang)
+	srvc.AirQuality = weatherInput.AirQuality
 	responseWeatherAPI, err := srvc.Search(ctx)
 	if err != nil {
-		spanSearch.AddEvent("error on search", trace.WithAttributes(attribute.String("error", err.Error())))
+		spanSearch.RecordError(err)
+		spanSearch.SetStatus(codes.Error, "error on search")
 		return output, err
 	}
 
+	// índices derivados calculados aqui, independente do provedor que entregou os dados
+	spanSearch.AddEvent("enrich weather")
+	enrichWeather(&responseWeatherAPI)
+	metrics.RecordTemperature(ctx, responseWeatherAPI.City, responseWeatherAPI.C)
+
+	expiresAt = c.cache.Set(cacheKey, responseWeatherAPI)
+	responseWeatherAPI.ExpiresAt = c.freshUntil(responseWeatherAPI, expiresAt)
+
 	spanSearch.AddEvent(
 		"search success",
 		trace.WithAttributes(
 			attribute.Float64("temp_C", responseWeatherAPI.C),
 			attribute.Float64("temp_F", responseWeatherAPI.F),
 			attribute.Float64("temp_K", responseWeatherAPI.K),
+			attribute.Float64("feels_like", responseWeatherAPI.FeelsLike),
+			attribute.Int("beaufort", responseWeatherAPI.Beaufort),
 		),
 	)
 
 	return responseWeatherAPI, err
 }
+
+// freshUntil - até quando a observação é considerada atual: o TTL contado a partir da observação
+// no provedor, sem passar da expiração da entrada no cache
+func (c *GetWeatherUseCase) freshUntil(output dto.WeatherOutput, cachedUntil time.Time) time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+
+	freshUntil := cachedUntil
+	if observedAt, err := time.Parse(time.RFC3339, output.ObservedAt); err == nil {
+		if observedUntil := observedAt.Add(c.ttl); observedUntil.Before(freshUntil) {
+			freshUntil = observedUntil
+		}
+	}
+
+	return freshUntil
+}
+
+// enrichWeather - preenche conversões e índices derivados a partir dos campos brutos do provedor
+func enrichWeather(output *dto.WeatherOutput) {
+	output.F = meteorology.CelsiusToFahrenheit(output.C)
+	output.K = meteorology.CelsiusToKelvin(output.C)
+
+	output.FeelsLike = meteorology.ApparentTemperature(output.C, output.Humidity, output.WindSpeed)
+	output.HeatIndex = meteorology.HeatIndex(output.C, output.Humidity)
+	output.WindChill = meteorology.WindChill(output.C, output.WindSpeed)
+	// sem umidade não há ponto de orvalho nem humidex, e os dois ficam ausentes
+	if dewPoint := meteorology.DewPoint(output.C, output.Humidity); !math.IsNaN(dewPoint) {
+		humidex := meteorology.Humidex(output.C, dewPoint)
+		output.DewPoint = &dewPoint
+		output.Humidex = &humidex
+	}
+	output.Beaufort = meteorology.Beaufort(output.WindSpeed)
+	output.BeaufortDescription = meteorology.BeaufortDescription(output.Beaufort)
+	if output.AirQuality != nil {
+		output.AirQuality.Category = i18n.AirQualityCategory(i18n.Default, output.AirQuality.USEPAIndex)
+	}
+}
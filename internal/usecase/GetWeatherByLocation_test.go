@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/units"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newWeatherAPIStub - WeatherAPI com a leitura atual de Maringá para qualquer consulta
+func newWeatherAPIStub(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"location":{"name":"Maringa","region":"Parana","tz_id":"America/Sao_Paulo"},"current":{"last_updated_epoch":1718900000,"temp_c":27.5,"humidity":60,"wind_kph":12,"pressure_mb":1014,"condition":{"text":"Sunny"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetWeatherRecordsTemperatureByCity(t *testing.T) {
+	// os instrumentos do pacote metrics usam o meter global, que repassa ao primeiro MeterProvider
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	t.Setenv("WEATHERAPI_URL", newWeatherAPIStub(t).URL)
+
+	output, err := NewGetWeatherUseCase("key", 0).Execute(context.Background(), dto.WeatherInput{Latitude: "-23.4", Longitude: "-51.9"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if output.City != "Maringa" {
+		t.Errorf("cidade = %q, esperado Maringa", output.City)
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	var points []metricdata.DataPoint[float64]
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if gauge, ok := m.Data.(metricdata.Gauge[float64]); ok && m.Name == "weather.temperature" {
+				points = append(points, gauge.DataPoints...)
+			}
+		}
+	}
+
+	if len(points) != 1 {
+		t.Fatalf("weather.temperature tem %d pontos, esperado 1", len(points))
+	}
+	if city, _ := points[0].Attributes.Value("city"); city.AsString() != "Maringa" || points[0].Value != 27.5 {
+		t.Errorf("ponto = %v em %q, esperado 27.5 em Maringa", points[0].Value, city.AsString())
+	}
+}
+
+func TestGetWeatherCachedConversionIsStable(t *testing.T) {
+	t.Setenv("WEATHERAPI_URL", newWeatherAPIStub(t).URL)
+
+	options, err := units.Parse("", "F", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usecase := NewGetWeatherUseCase("key", time.Minute)
+	input := dto.WeatherInput{Latitude: "-23.4", Longitude: "-51.9"}
+
+	var dewPoints []float64
+	for i := 0; i < 3; i++ {
+		output, err := usecase.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if output.DewPoint == nil || output.Humidex == nil {
+			t.Fatal("resposta sem ponto de orvalho ou humidex")
+		}
+		units.Apply(options, &output)
+		dewPoints = append(dewPoints, *output.DewPoint)
+	}
+
+	// a conversão de uma resposta não pode alterar a entrada do cache usada pelas seguintes
+	for i := 1; i < len(dewPoints); i++ {
+		if dewPoints[i] != dewPoints[0] {
+			t.Errorf("ponto de orvalho em °F nas consultas = %v, esperado o mesmo valor", dewPoints)
+			break
+		}
+	}
+	if dewPoints[0] < 50 || dewPoints[0] > 70 {
+		t.Errorf("ponto de orvalho = %v °F, esperado ~65 °F para 27.5 °C e 60%%", dewPoints[0])
+	}
+}
@@ -0,0 +1,6 @@
+package usecase
+
+import "go.opentelemetry.io/otel"
+
+// tracer - escopo de instrumentação dos spans dos casos de uso
+var tracer = otel.Tracer("github.com/nagahshi/pos_go_weather_otel/internal/usecase")
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// reservedPrefixes - faixas IPv4 que não são privadas pela RFC 1918, mas também não são públicas:
+// CGNAT (RFC 6598), atribuições de protocolo do IETF (RFC 6890) e testes de desempenho (RFC 2544)
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// publicAddress - indica se o endereço pode receber entregas: loopback, redes privadas, link-local,
+// multicast, o endereço não especificado e reservedPrefixes ficam de fora, para que uma assinatura
+// não use o serviço para alcançar a rede interna (SSRF)
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return false
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkHost - resolve o host da callback_url e exige que todos os endereços sejam públicos; é a
+// verificação da assinatura, repetida a cada conexão por dialControl
+func checkHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return fmt.Errorf("endereço %s não é público", addr)
+		}
+	}
+
+	return nil
+}
+
+// dialControl - recusa as conexões a endereços que não são públicos. Roda depois da resolução do
+// nome, então vale para o endereço realmente usado, mesmo que o DNS mude depois da assinatura (DNS
+// rebinding) ou que o destino redirecione a entrega.
+func dialControl(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("conexão a %s recusada: endereço não é público", address)
+	}
+
+	return nil
+}
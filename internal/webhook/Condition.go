@@ -0,0 +1,151 @@
+// Package webhook avalia periodicamente condições de clima por CEP e notifica os assinantes com
+// webhooks assinados por HMAC, com novas tentativas e uma fila de entregas que falharam (dead letter).
+package webhook
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+// ForecastDays - dias de previsão considerados nas condições sobre a previsão
+const ForecastDays = 3
+
+// rainExpected - condição nomeada "rain expected", equivalente a chance_of_rain >= 50
+const rainExpected = "rain expected"
+
+var conditionPattern = regexp.MustCompile(`^([a-zA-Z_]+)\s*(>=|<=|==|!=|>|<)\s*(-?[0-9]+(?:\.[0-9]+)?)$`)
+
+// currentFields - campos da leitura atual, pelo nome no JSON de WeatherOutput; as leituras são
+// consultadas em unidades métricas (°C, km/h e hPa)
+var currentFields = map[string]func(output dto.WeatherOutput) float64{
+	"temp_C":     func(output dto.WeatherOutput) float64 { return output.C },
+	"temp_F":     func(output dto.WeatherOutput) float64 { return output.F },
+	"temp_K":     func(output dto.WeatherOutput) float64 { return output.K },
+	"humidity":   func(output dto.WeatherOutput) float64 { return output.Humidity },
+	"wind_speed": func(output dto.WeatherOutput) float64 { return output.WindSpeed },
+	"pressure":   func(output dto.WeatherOutput) float64 { return output.Pressure },
+	"feels_like": func(output dto.WeatherOutput) float64 { return output.FeelsLike },
+	"heat_index": func(output dto.WeatherOutput) float64 { return output.HeatIndex },
+	"wind_chill": func(output dto.WeatherOutput) float64 { return output.WindChill },
+	"dew_point":  func(output dto.WeatherOutput) float64 { return valueOrNaN(output.DewPoint) },
+	"humidex":    func(output dto.WeatherOutput) float64 { return valueOrNaN(output.Humidex) },
+	"beaufort":   func(output dto.WeatherOutput) float64 { return float64(output.Beaufort) },
+}
+
+// valueOrNaN - valor do campo opcional; NaN, que não atende a nenhuma condição, quando ausente
+func valueOrNaN(value *float64) float64 {
+	if value == nil {
+		return math.NaN()
+	}
+
+	return *value
+}
+
+// forecastFields - campos da previsão diária, pelo nome no JSON de ForecastDay
+var forecastFields = map[string]func(day dto.ForecastDay) float64{
+	"max_temp_C":     func(day dto.ForecastDay) float64 { return day.MaxTempC },
+	"min_temp_C":     func(day dto.ForecastDay) float64 { return day.MinTempC },
+	"avg_temp_C":     func(day dto.ForecastDay) float64 { return day.AvgTempC },
+	"max_wind_speed": func(day dto.ForecastDay) float64 { return day.MaxWindSpeed },
+	"precipitation":  func(day dto.ForecastDay) float64 { return day.Precipitation },
+	"chance_of_rain": func(day dto.ForecastDay) float64 { return day.ChanceOfRain },
+	"uv":             func(day dto.ForecastDay) float64 { return day.UV },
+}
+
+// Condition - comparação de um campo da leitura atual ou da previsão com um limite (ex.: temp_C > 35)
+type Condition struct {
+	Field     string
+	Operator  string
+	Threshold float64
+}
+
+// Match - resultado da avaliação: o valor que atendeu à condição e, nas condições sobre a previsão, o dia
+type Match struct {
+	Value float64
+	Date  string
+}
+
+// ParseCondition - interpreta "campo operador valor" ou a condição nomeada "rain expected"
+func ParseCondition(expression string) (Condition, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.EqualFold(strings.Join(strings.Fields(expression), " "), rainExpected) {
+		return Condition{Field: "chance_of_rain", Operator: ">=", Threshold: 50}, nil
+	}
+
+	parts := conditionPattern.FindStringSubmatch(expression)
+	if parts == nil {
+		return Condition{}, fmt.Errorf("condição inválida: %q, use campo operador valor (ex.: temp_C > 35) ou %q", expression, rainExpected)
+	}
+
+	_, current := currentFields[parts[1]]
+	_, forecast := forecastFields[parts[1]]
+	if !current && !forecast {
+		return Condition{}, fmt.Errorf("campo desconhecido na condição: %q", parts[1])
+	}
+
+	threshold, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil {
+		return Condition{}, fmt.Errorf("limite inválido na condição: %q", parts[3])
+	}
+
+	return Condition{Field: parts[1], Operator: parts[2], Threshold: threshold}, nil
+}
+
+// String - forma normalizada da condição
+func (c Condition) String() string {
+	return c.Field + " " + c.Operator + " " + strconv.FormatFloat(c.Threshold, 'f', -1, 64)
+}
+
+// Forecast - indica se a condição é avaliada sobre a previsão em vez da leitura atual
+func (c Condition) Forecast() bool {
+	_, ok := forecastFields[c.Field]
+
+	return ok
+}
+
+// Evaluate - avalia a condição; sobre a previsão, basta um dos dias atender, e o mais próximo é o
+// devolvido, para o aviso chegar o quanto antes
+func (c Condition) Evaluate(weather dto.WeatherOutput, forecast []dto.ForecastDay) (Match, bool) {
+	if value, ok := currentFields[c.Field]; ok {
+		match := Match{Value: value(weather)}
+		return match, c.compare(match.Value)
+	}
+
+	value := forecastFields[c.Field]
+	for _, day := range forecast {
+		if c.compare(value(day)) {
+			return Match{Value: value(day), Date: day.Date}, true
+		}
+	}
+
+	return Match{}, false
+}
+
+func (c Condition) compare(value float64) bool {
+	if math.IsNaN(value) {
+		// campo ausente na leitura: nem != é atendido
+		return false
+	}
+
+	switch c.Operator {
+	case ">":
+		return value > c.Threshold
+	case ">=":
+		return value >= c.Threshold
+	case "<":
+		return value < c.Threshold
+	case "<=":
+		return value <= c.Threshold
+	case "==":
+		return value == c.Threshold
+	case "!=":
+		return value != c.Threshold
+	}
+
+	return false
+}
@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+)
+
+func TestConditionMissingField(t *testing.T) {
+	dewPoint := 18.5
+	withHumidity := dto.WeatherOutput{C: 25, Humidity: 60, DewPoint: &dewPoint}
+	withoutHumidity := dto.WeatherOutput{C: 25}
+
+	for _, expression := range []string{"dew_point > 10", "dew_point != 0", "humidex < 100", "humidex != 25"} {
+		condition, err := ParseCondition(expression)
+		if err != nil {
+			t.Fatalf("ParseCondition(%q): %v", expression, err)
+		}
+
+		if _, met := condition.Evaluate(withoutHumidity, nil); met {
+			t.Errorf("%s atendida sem a umidade", expression)
+		}
+	}
+
+	condition, _ := ParseCondition("dew_point > 10")
+	if match, met := condition.Evaluate(withHumidity, nil); !met || match.Value != dewPoint {
+		t.Errorf("dew_point > 10 = %v (%v), esperado atendida com %v", met, match.Value, dewPoint)
+	}
+}
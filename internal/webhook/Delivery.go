@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// EventConditionTriggered - tipo do evento entregue quando a condição passa a ser atendida
+	EventConditionTriggered = "condition.triggered"
+
+	// headers das entregas; a assinatura é HMAC-SHA256 de "timestamp.corpo" com o secret da assinatura
+	HeaderID        = "X-Webhook-ID"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	deliveryTimeout = 10 * time.Second
+)
+
+// Event - corpo do webhook; Date só vem nas condições sobre a previsão e TriggeredAt é RFC 3339 em UTC
+type Event struct {
+	ID             string            `json:"id"`
+	Type           string            `json:"type"`
+	SubscriptionID string            `json:"subscription_id"`
+	CEP            string            `json:"cep"`
+	Condition      string            `json:"condition"`
+	Value          float64           `json:"value"`
+	Date           string            `json:"date,omitempty"`
+	TriggeredAt    string            `json:"triggered_at"`
+	Weather        dto.WeatherOutput `json:"weather"`
+	TraceID        string            `json:"trace_id,omitempty"`
+}
+
+// Sign - assinatura enviada em X-Webhook-Signature ("sha256=" e o HMAC em hexadecimal)
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher - entrega os webhooks com até maxAttempts tentativas e espera exponencial a partir de
+// backoff; a entrega que esgota as tentativas, ou que o destino recusa com 4xx, vai para a dead letter.
+// Só conecta a endereços públicos, a não ser com allowPrivateNetworks.
+type Dispatcher struct {
+	store                *Store
+	client               *http.Client
+	maxAttempts          int
+	backoff              time.Duration
+	allowPrivateNetworks bool
+}
+
+// NewDispatcher - cria o dispatcher guardando as entregas que falharam em store; allowPrivateNetworks
+// libera as entregas à rede interna, para receptores no mesmo ambiente (ex.: docker-compose)
+func NewDispatcher(store *Store, maxAttempts int, backoff time.Duration, allowPrivateNetworks bool) *Dispatcher {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = dialControl
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// sem proxy: a conexão iria ao proxy, e o endereço verificado não seria o do destino
+	transport.Proxy = nil
+
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(transport),
+			Timeout:   deliveryTimeout,
+		},
+		maxAttempts:          maxAttempts,
+		backoff:              backoff,
+		allowPrivateNetworks: allowPrivateNetworks,
+	}
+}
+
+// checkCallback - verificação da callback_url na assinatura: o host precisa resolver só para
+// endereços públicos
+func (d *Dispatcher) checkCallback(ctx context.Context, host string) error {
+	if d.allowPrivateNetworks {
+		return nil
+	}
+
+	return checkHost(ctx, host)
+}
+
+// Deliver - envia o evento à URL da assinatura, aguardando as novas tentativas; devolve false
+// quando a entrega terminou na dead letter
+func (d *Dispatcher) Deliver(ctx context.Context, subscription Subscription, event Event) bool {
+	ctx, spanDeliver := tracer.Start(
+		ctx,
+		"webhook_delivery",
+		trace.WithAttributes(
+			attribute.String("webhook.subscription_id", subscription.ID),
+			attribute.String("webhook.delivery_id", event.ID),
+		),
+	)
+	defer spanDeliver.End()
+
+	body := bytes.Buffer{}
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(event); err != nil {
+		spanDeliver.RecordError(err)
+		spanDeliver.SetStatus(codes.Error, "error on encode event")
+		return false
+	}
+	payload := body.Bytes()
+
+	var status int
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		status, err = d.send(ctx, subscription, event.ID, payload)
+		if err == nil {
+			spanDeliver.AddEvent("delivery success", trace.WithAttributes(attribute.Int("attempt", attempt), attribute.Int("http.status_code", status)))
+			return true
+		}
+
+		spanDeliver.RecordError(err, trace.WithAttributes(attribute.Int("attempt", attempt)))
+		if attempt >= d.maxAttempts || !retryable(status) {
+			break
+		}
+
+		wait := d.backoff << (attempt - 1)
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("%w (tentativas interrompidas: %v)", err, ctx.Err())
+		case <-time.After(wait):
+			continue
+		}
+		break
+	}
+
+	spanDeliver.RecordError(err)
+	spanDeliver.SetStatus(codes.Error, "dead letter")
+	err = d.store.AddDeadLetter(DeadLetter{
+		ID:             event.ID,
+		SubscriptionID: subscription.ID,
+		CallbackURL:    subscription.CallbackURL,
+		Event:          event,
+		Attempts:       attempt,
+		LastStatus:     status,
+		LastError:      err.Error(),
+		FailedAt:       time.Now().UTC().Format(time.RFC3339),
+		Owner:          subscription.Owner,
+	})
+	if err != nil {
+		spanDeliver.RecordError(err)
+	}
+
+	return false
+}
+
+// send - uma tentativa de entrega; status é zero quando não houve resposta
+func (d *Dispatcher) send(ctx context.Context, subscription Subscription, deliveryID string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.CallbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "my-app/v1.0.0")
+	req.Header.Set(HeaderID, deliveryID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("destino respondeu status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// retryable - falhas de rede, 408, 429 e 5xx merecem nova tentativa; os demais 4xx não mudam com o tempo
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
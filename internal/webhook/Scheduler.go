@@ -0,0 +1,385 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/nagahshi/pos_go_weather_otel/internal/apperror"
+	"github.com/nagahshi/pos_go_weather_otel/internal/dto"
+	"github.com/nagahshi/pos_go_weather_otel/internal/usecase"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer - escopo de instrumentação das rodadas de avaliação e das entregas de webhooks
+var tracer = otel.Tracer("github.com/nagahshi/pos_go_weather_otel/internal/webhook")
+
+const (
+	// MaxSubscriptions - limite de assinaturas guardadas pelo serviço
+	MaxSubscriptions = 1000
+	// minSecretLength - tamanho mínimo do secret escolhido pelo cliente
+	minSecretLength = 16
+	// evaluateConcurrency - CEPs avaliados em paralelo em cada rodada
+	evaluateConcurrency = 4
+)
+
+// causas dos erros devolvidos pelo scheduler, sempre embrulhadas em um apperror com o código HTTP
+// adequado; o handler usa errors.Is com elas para escolher a mensagem
+var (
+	ErrInvalidCondition     = errors.New("condição inválida")
+	ErrInvalidCallbackURL   = errors.New("callback_url deve ser uma URL http ou https absoluta, de um endereço público")
+	ErrInvalidSecret        = errors.New("secret deve ter ao menos 16 caracteres")
+	ErrSubscriptionLimit    = errors.New("limite de assinaturas atingido")
+	ErrSubscriptionNotFound = errors.New("assinatura não encontrada")
+	ErrDeadLetterNotFound   = errors.New("dead letter não encontrada")
+)
+
+// Source - consultas usadas na avaliação das condições
+type Source interface {
+	Location(ctx context.Context, CEP string) (dto.CEPOutput, error)
+	Weather(ctx context.Context, latitude string, longitude string) (dto.WeatherOutput, error)
+	Forecast(ctx context.Context, latitude string, longitude string, days int) ([]dto.ForecastDay, error)
+}
+
+// UseCases - Source sobre os usecases do serviço A: CEP na BrasilAPI, clima no serviço B e previsão
+// na WeatherAPI, sempre em unidades métricas
+type UseCases struct {
+	GetLatLonByCEP          usecase.GetLatLonByCEP
+	GetWeatherByCoordinates usecase.GetWeatherByCoordinatesUseCase
+	GetForecast             usecase.GetForecastUseCase
+}
+
+func (u UseCases) Location(ctx context.Context, CEP string) (dto.CEPOutput, error) {
+	return u.GetLatLonByCEP.Execute(ctx, CEP)
+}
+
+func (u UseCases) Weather(ctx context.Context, latitude string, longitude string) (dto.WeatherOutput, error) {
+	return u.GetWeatherByCoordinates.Execute(ctx, dto.WeatherByCoordinatesInput{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Query:     url.Values{"preset": {"metric"}},
+	})
+}
+
+func (u UseCases) Forecast(ctx context.Context, latitude string, longitude string, days int) ([]dto.ForecastDay, error) {
+	return u.GetForecast.Execute(ctx, dto.ForecastInput{Latitude: latitude, Longitude: longitude, Days: days})
+}
+
+// Scheduler - guarda as assinaturas e avalia as condições a cada intervalo. A notificação é enviada
+// quando a condição passa a ser atendida; enquanto continuar atendida, não há nova entrega.
+type Scheduler struct {
+	store      *Store
+	source     Source
+	dispatcher *Dispatcher
+	interval   time.Duration
+
+	mu  sync.Mutex
+	met map[string]bool
+}
+
+// NewScheduler - cria o scheduler avaliando as assinaturas de store a cada interval
+func NewScheduler(store *Store, source Source, dispatcher *Dispatcher, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		store:      store,
+		source:     source,
+		dispatcher: dispatcher,
+		interval:   interval,
+		met:        map[string]bool{},
+	}
+}
+
+// Interval - intervalo entre as avaliações
+func (s *Scheduler) Interval() time.Duration {
+	return s.interval
+}
+
+// Subscribe - valida e guarda a assinatura de owner; sem secret, um aleatório é gerado. O host da
+// callback_url precisa resolver só para endereços públicos, o que o Dispatcher verifica de novo a
+// cada conexão.
+func (s *Scheduler) Subscribe(ctx context.Context, owner string, CEP string, expression string, callbackURL string, secret string) (Subscription, error) {
+	condition, err := ParseCondition(expression)
+	if err != nil {
+		return Subscription{}, apperror.Wrap(apperror.CodeInvalidRequest, err.Error(), ErrInvalidCondition)
+	}
+
+	callback, err := url.Parse(callbackURL)
+	if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+		return Subscription{}, apperror.Wrap(apperror.CodeInvalidRequest, "callback_url inválida", ErrInvalidCallbackURL)
+	}
+	if err := s.dispatcher.checkCallback(ctx, callback.Hostname()); err != nil {
+		return Subscription{}, apperror.Wrap(apperror.CodeInvalidRequest, "callback_url recusada: "+err.Error(), ErrInvalidCallbackURL)
+	}
+
+	if secret == "" {
+		secret = newID() + newID()
+	} else if len(secret) < minSecretLength {
+		return Subscription{}, apperror.Wrap(apperror.CodeInvalidRequest, "secret inválido", ErrInvalidSecret)
+	}
+
+	count, err := s.store.Len()
+	if err != nil {
+		return Subscription{}, apperror.Wrap(apperror.CodeInternal, "falha ao consultar as assinaturas", err)
+	}
+	if count >= MaxSubscriptions {
+		return Subscription{}, apperror.Wrap(apperror.CodeInvalidRequest, "assinatura recusada", ErrSubscriptionLimit)
+	}
+
+	createdAt := time.Now().UTC()
+	subscription := Subscription{
+		ID:          newID(),
+		CEP:         CEP,
+		Condition:   condition.String(),
+		CallbackURL: callback.String(),
+		Secret:      secret,
+		Owner:       owner,
+		CreatedAt:   createdAt.Format(time.RFC3339),
+		condition:   condition,
+		createdAt:   createdAt,
+	}
+	if err := s.store.Add(subscription); err != nil {
+		return Subscription{}, apperror.Wrap(apperror.CodeInternal, "falha ao guardar a assinatura", err)
+	}
+
+	return subscription, nil
+}
+
+// Subscription - assinatura de owner pelo id; a de outro dono não é encontrada
+func (s *Scheduler) Subscription(owner string, id string) (Subscription, error) {
+	subscription, ok, err := s.store.Get(id)
+	if err != nil {
+		return Subscription{}, apperror.Wrap(apperror.CodeInternal, "falha ao consultar a assinatura", err)
+	}
+	if !ok || subscription.Owner != owner {
+		return Subscription{}, apperror.Wrap(apperror.CodeNotFound, id, ErrSubscriptionNotFound)
+	}
+
+	return subscription, nil
+}
+
+// Subscriptions - assinaturas de owner em ordem de criação
+func (s *Scheduler) Subscriptions(owner string) ([]Subscription, error) {
+	subscriptions, err := s.store.List()
+	if err != nil {
+		return nil, apperror.Wrap(apperror.CodeInternal, "falha ao consultar as assinaturas", err)
+	}
+
+	owned := []Subscription{}
+	for _, subscription := range subscriptions {
+		if subscription.Owner == owner {
+			owned = append(owned, subscription)
+		}
+	}
+
+	return owned, nil
+}
+
+// Unsubscribe - remove a assinatura de owner e o estado da última avaliação
+func (s *Scheduler) Unsubscribe(owner string, id string) error {
+	deleted, err := s.store.Delete(id, owner)
+	if err != nil {
+		return apperror.Wrap(apperror.CodeInternal, "falha ao remover a assinatura", err)
+	}
+	if !deleted {
+		return apperror.Wrap(apperror.CodeNotFound, id, ErrSubscriptionNotFound)
+	}
+
+	s.mu.Lock()
+	delete(s.met, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// DeadLetters - entregas das assinaturas de owner que esgotaram as tentativas
+func (s *Scheduler) DeadLetters(owner string) ([]DeadLetter, error) {
+	deadLetters, err := s.store.DeadLetters()
+	if err != nil {
+		return nil, apperror.Wrap(apperror.CodeInternal, "falha ao consultar as dead letters", err)
+	}
+
+	owned := []DeadLetter{}
+	for _, deadLetter := range deadLetters {
+		if deadLetter.Owner == owner {
+			owned = append(owned, deadLetter)
+		}
+	}
+
+	return owned, nil
+}
+
+// Redeliver - tira a entrega de owner da dead letter e a reenvia em segundo plano, com as mesmas
+// tentativas; a assinatura ainda precisa existir, pois o secret dela assina o reenvio
+func (s *Scheduler) Redeliver(ctx context.Context, owner string, id string) error {
+	deadLetter, ok, err := s.store.TakeDeadLetter(id, owner)
+	if err != nil {
+		return apperror.Wrap(apperror.CodeInternal, "falha ao consultar as dead letters", err)
+	}
+	if !ok {
+		return apperror.Wrap(apperror.CodeNotFound, id, ErrDeadLetterNotFound)
+	}
+
+	subscription, ok, err := s.store.Get(deadLetter.SubscriptionID)
+	if err != nil || !ok || subscription.Owner != owner {
+		// a entrega volta para a dead letter
+		if addErr := s.store.AddDeadLetter(deadLetter); addErr != nil {
+			err = errors.Join(err, addErr)
+		}
+		if err != nil {
+			return apperror.Wrap(apperror.CodeInternal, "falha ao consultar a assinatura", err)
+		}
+		return apperror.Wrap(apperror.CodeNotFound, deadLetter.SubscriptionID, ErrSubscriptionNotFound)
+	}
+
+	go s.dispatcher.Deliver(context.WithoutCancel(ctx), subscription, deadLetter.Event)
+
+	return nil
+}
+
+// Run - avalia as assinaturas a cada intervalo até o cancelamento de ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Evaluate(ctx)
+		}
+	}
+}
+
+// Evaluate - uma rodada de avaliação: cada CEP é consultado uma única vez para todas as suas
+// assinaturas. Cada rodada é um trace próprio (webhook_evaluate).
+func (s *Scheduler) Evaluate(ctx context.Context) {
+	subscriptions, err := s.store.List()
+	if err != nil {
+		otel.Handle(err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	ctx, spanEvaluate := tracer.Start(
+		ctx,
+		"webhook_evaluate",
+		trace.WithNewRoot(),
+		trace.WithAttributes(attribute.Int("webhook.subscriptions", len(subscriptions))),
+	)
+	defer spanEvaluate.End()
+
+	byCEP := map[string][]Subscription{}
+	for _, subscription := range subscriptions {
+		byCEP[subscription.CEP] = append(byCEP[subscription.CEP], subscription)
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, evaluateConcurrency)
+	for CEP, group := range byCEP {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(CEP string, group []Subscription) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			s.evaluateCEP(ctx, CEP, group)
+		}(CEP, group)
+	}
+	wg.Wait()
+}
+
+// evaluateCEP - consulta o clima do CEP, e a previsão se alguma condição pedir, e notifica as
+// assinaturas cuja condição passou a ser atendida
+func (s *Scheduler) evaluateCEP(ctx context.Context, CEP string, subscriptions []Subscription) {
+	ctx, spanCEP := tracer.Start(ctx, "webhook_evaluate_cep", trace.WithAttributes(attribute.String("zipcode", CEP)))
+	defer spanCEP.End()
+
+	location, err := s.source.Location(ctx, CEP)
+	if err == nil && (location.Latitude == "" || location.Longitude == "") {
+		err = apperror.ErrLocationNotFound
+	}
+	if err != nil {
+		spanCEP.RecordError(err)
+		spanCEP.SetStatus(codes.Error, "error on search location")
+		return
+	}
+
+	weather, err := s.source.Weather(ctx, location.Latitude, location.Longitude)
+	if err != nil {
+		spanCEP.RecordError(err)
+		spanCEP.SetStatus(codes.Error, "error on search weather")
+		return
+	}
+
+	var forecast []dto.ForecastDay
+	var forecastErr error
+	for _, subscription := range subscriptions {
+		if subscription.condition.Forecast() {
+			forecast, forecastErr = s.source.Forecast(ctx, location.Latitude, location.Longitude, ForecastDays)
+			if forecastErr != nil {
+				spanCEP.RecordError(forecastErr)
+				spanCEP.SetStatus(codes.Error, "error on search forecast")
+			}
+			break
+		}
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.condition.Forecast() && forecastErr != nil {
+			continue
+		}
+
+		match, met := subscription.condition.Evaluate(weather, forecast)
+		if !s.transition(subscription.ID, met) {
+			continue
+		}
+
+		event := Event{
+			ID:             newID(),
+			Type:           EventConditionTriggered,
+			SubscriptionID: subscription.ID,
+			CEP:            subscription.CEP,
+			Condition:      subscription.Condition,
+			Value:          match.Value,
+			Date:           match.Date,
+			TriggeredAt:    time.Now().UTC().Format(time.RFC3339),
+			Weather:        weather,
+		}
+		if spanContext := spanCEP.SpanContext(); spanContext.HasTraceID() {
+			event.TraceID = spanContext.TraceID().String()
+		}
+
+		spanCEP.AddEvent(
+			"condition triggered",
+			trace.WithAttributes(
+				attribute.String("webhook.subscription_id", subscription.ID),
+				attribute.String("webhook.condition", subscription.Condition),
+				attribute.Float64("value", match.Value),
+			),
+		)
+		go s.dispatcher.Deliver(ctx, subscription, event)
+	}
+}
+
+// transition - guarda o resultado da avaliação; true apenas quando a condição passou a ser atendida
+// e a assinatura continua existindo
+func (s *Scheduler) transition(id string, met bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok, err := s.store.Get(id); err != nil || !ok {
+		return false
+	}
+
+	previous := s.met[id]
+	s.met[id] = met
+
+	return met && !previous
+}
@@ -0,0 +1,330 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets do arquivo: assinaturas pelo id e dead letters pela ordem de chegada
+var (
+	subscriptionsBucket = []byte("subscriptions")
+	deadLettersBucket   = []byte("dead_letters")
+)
+
+// Subscription - CEP, condição e URL notificada quando a condição passa a ser atendida; Secret
+// assina as entregas e só é devolvido na criação. Owner é o tenant que criou a assinatura, o único
+// que a enxerga.
+type Subscription struct {
+	ID          string `json:"id"`
+	CEP         string `json:"cep"`
+	Condition   string `json:"condition"`
+	CallbackURL string `json:"callback_url"`
+	Secret      string `json:"-"`
+	Owner       string `json:"-"`
+	// CreatedAt - momento da criação (RFC 3339, UTC)
+	CreatedAt string `json:"created_at"`
+
+	condition Condition
+	createdAt time.Time
+}
+
+// subscriptionRecord - assinatura como fica no arquivo, com o secret e o dono
+type subscriptionRecord struct {
+	ID          string    `json:"id"`
+	CEP         string    `json:"cep"`
+	Condition   string    `json:"condition"`
+	CallbackURL string    `json:"callback_url"`
+	Secret      string    `json:"secret"`
+	Owner       string    `json:"owner,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DeadLetter - entrega que esgotou as tentativas, guardada com o evento original para reenvio
+type DeadLetter struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	CallbackURL    string `json:"callback_url"`
+	Event          Event  `json:"event"`
+	Attempts       int    `json:"attempts"`
+	LastStatus     int    `json:"last_status,omitempty"`
+	LastError      string `json:"last_error"`
+	// FailedAt - momento da última tentativa (RFC 3339, UTC)
+	FailedAt string `json:"failed_at"`
+	Owner    string `json:"-"`
+}
+
+// deadLetterRecord - dead letter como fica no arquivo, com o dono
+type deadLetterRecord struct {
+	DeadLetter
+	Owner string `json:"owner,omitempty"`
+}
+
+// Store - assinaturas e dead letters em um arquivo bbolt, que sobrevivem a um restart do serviço;
+// seguro para uso concorrente. As dead letters mais antigas são descartadas acima de maxDeadLetters.
+type Store struct {
+	db             *bolt.DB
+	maxDeadLetters int
+}
+
+// Open - abre, ou cria, o arquivo das assinaturas em path, guardando até maxDeadLetters entregas
+// que falharam
+func Open(path string, maxDeadLetters int) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{subscriptionsBucket, deadLettersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db, maxDeadLetters: maxDeadLetters}, nil
+}
+
+// Close - fecha o arquivo das assinaturas
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add - guarda a assinatura
+func (s *Store) Add(subscription Subscription) error {
+	value, err := json.Marshal(subscriptionRecord{
+		ID:          subscription.ID,
+		CEP:         subscription.CEP,
+		Condition:   subscription.Condition,
+		CallbackURL: subscription.CallbackURL,
+		Secret:      subscription.Secret,
+		Owner:       subscription.Owner,
+		CreatedAt:   subscription.createdAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).Put([]byte(subscription.ID), value)
+	})
+}
+
+// decodeSubscription - assinatura guardada, com a condição já interpretada
+func decodeSubscription(value []byte) (Subscription, error) {
+	record := subscriptionRecord{}
+	if err := json.Unmarshal(value, &record); err != nil {
+		return Subscription{}, err
+	}
+
+	condition, err := ParseCondition(record.Condition)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	return Subscription{
+		ID:          record.ID,
+		CEP:         record.CEP,
+		Condition:   record.Condition,
+		CallbackURL: record.CallbackURL,
+		Secret:      record.Secret,
+		Owner:       record.Owner,
+		CreatedAt:   record.CreatedAt.UTC().Format(time.RFC3339),
+		condition:   condition,
+		createdAt:   record.CreatedAt,
+	}, nil
+}
+
+// Get - assinatura pelo id
+func (s *Store) Get(id string) (subscription Subscription, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(subscriptionsBucket).Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+
+		subscription, err = decodeSubscription(value)
+		ok = err == nil
+		return err
+	})
+
+	return subscription, ok, err
+}
+
+// Delete - remove a assinatura do dono; false se ela não existia ou é de outro dono
+func (s *Store) Delete(id string, owner string) (deleted bool, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(subscriptionsBucket)
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+
+		record := subscriptionRecord{}
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		if record.Owner != owner {
+			return nil
+		}
+
+		deleted = true
+		return bucket.Delete([]byte(id))
+	})
+
+	return deleted, err
+}
+
+// Len - quantidade de assinaturas
+func (s *Store) Len() (n int, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(subscriptionsBucket).Stats().KeyN
+		return nil
+	})
+
+	return n, err
+}
+
+// List - assinaturas em ordem de criação
+func (s *Store) List() ([]Subscription, error) {
+	subscriptions := []Subscription{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).ForEach(func(_ []byte, value []byte) error {
+			subscription, err := decodeSubscription(value)
+			if err != nil {
+				return err
+			}
+			subscriptions = append(subscriptions, subscription)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].createdAt.Equal(subscriptions[j].createdAt) {
+			return subscriptions[i].ID < subscriptions[j].ID
+		}
+		return subscriptions[i].createdAt.Before(subscriptions[j].createdAt)
+	})
+
+	return subscriptions, nil
+}
+
+// AddDeadLetter - guarda a entrega que falhou, descartando as mais antigas quando o limite é atingido
+func (s *Store) AddDeadLetter(deadLetter DeadLetter) error {
+	value, err := json.Marshal(deadLetterRecord{DeadLetter: deadLetter, Owner: deadLetter.Owner})
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(deadLettersBucket)
+
+		// chave sequencial big-endian, para a ordem das chaves ser a ordem de chegada
+		sequence, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, sequence)
+		if err := bucket.Put(k, value); err != nil {
+			return err
+		}
+
+		if s.maxDeadLetters <= 0 {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		count := 0
+		for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+			count++
+		}
+		for ; count > s.maxDeadLetters; count-- {
+			if k, _ := cursor.First(); k == nil {
+				break
+			}
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// decodeDeadLetter - dead letter guardada, com o dono
+func decodeDeadLetter(value []byte) (DeadLetter, error) {
+	record := deadLetterRecord{}
+	if err := json.Unmarshal(value, &record); err != nil {
+		return DeadLetter{}, err
+	}
+	record.DeadLetter.Owner = record.Owner
+
+	return record.DeadLetter, nil
+}
+
+// DeadLetters - entregas que falharam, da mais antiga para a mais recente
+func (s *Store) DeadLetters() ([]DeadLetter, error) {
+	deadLetters := []DeadLetter{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLettersBucket).ForEach(func(_ []byte, value []byte) error {
+			deadLetter, err := decodeDeadLetter(value)
+			if err != nil {
+				return err
+			}
+			deadLetters = append(deadLetters, deadLetter)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deadLetters, nil
+}
+
+// TakeDeadLetter - remove e devolve a dead letter do dono pelo id, para reenvio
+func (s *Store) TakeDeadLetter(id string, owner string) (deadLetter DeadLetter, ok bool, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(deadLettersBucket).Cursor()
+		for k, value := cursor.First(); k != nil; k, value = cursor.Next() {
+			candidate, err := decodeDeadLetter(value)
+			if err != nil {
+				return err
+			}
+			if candidate.ID != id || candidate.Owner != owner {
+				continue
+			}
+
+			deadLetter, ok = candidate, true
+			return cursor.Delete()
+		}
+
+		return nil
+	})
+
+	return deadLetter, ok, err
+}
+
+// newID - identificador aleatório de 16 bytes em hexadecimal
+func newID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestScheduler - scheduler sobre um arquivo novo, com o dispatcher verificando os endereços
+func newTestScheduler(t *testing.T, path string) (*Scheduler, *Store) {
+	t.Helper()
+
+	store, err := Open(path, 2)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return NewScheduler(store, nil, NewDispatcher(store, 1, time.Millisecond, false), time.Minute), store
+}
+
+func TestStorePersistsSubscriptionsAndDeadLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.db")
+	scheduler, store := newTestScheduler(t, path)
+
+	subscription, err := scheduler.Subscribe(context.Background(), "acme", "87033080", "temp_C > 35", "https://93.184.215.14/hooks", "")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	for i, id := range []string{"d1", "d2", "d3"} {
+		err := store.AddDeadLetter(DeadLetter{ID: id, SubscriptionID: subscription.ID, Attempts: i + 1, Owner: "acme"})
+		if err != nil {
+			t.Fatalf("AddDeadLetter: %v", err)
+		}
+	}
+	store.Close()
+
+	reopened, err := Open(path, 2)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer reopened.Close()
+
+	got, ok, err := reopened.Get(subscription.ID)
+	if err != nil || !ok {
+		t.Fatalf("assinatura perdida no restart: ok = %v, err = %v", ok, err)
+	}
+	if got.Secret != subscription.Secret || got.Owner != "acme" || got.CreatedAt != subscription.CreatedAt {
+		t.Errorf("assinatura = %+v, esperado %+v", got, subscription)
+	}
+	if got.condition.String() != "temp_C > 35" {
+		t.Errorf("condição = %q, esperado temp_C > 35", got.condition.String())
+	}
+
+	deadLetters, err := reopened.DeadLetters()
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	// o limite de 2 descarta a mais antiga
+	if len(deadLetters) != 2 || deadLetters[0].ID != "d2" || deadLetters[1].ID != "d3" || deadLetters[0].Owner != "acme" {
+		t.Errorf("dead letters = %+v, esperado d2 e d3 de acme", deadLetters)
+	}
+}
+
+func TestSchedulerOwner(t *testing.T) {
+	scheduler, store := newTestScheduler(t, filepath.Join(t.TempDir(), "webhooks.db"))
+	ctx := context.Background()
+
+	subscription, err := scheduler.Subscribe(ctx, "acme", "87033080", "rain expected", "https://93.184.215.14/hooks", "")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := store.AddDeadLetter(DeadLetter{ID: "d1", SubscriptionID: subscription.ID, Owner: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if subscriptions, _ := scheduler.Subscriptions("globex"); len(subscriptions) != 0 {
+		t.Errorf("globex enxerga %d assinaturas de acme", len(subscriptions))
+	}
+	if subscriptions, _ := scheduler.Subscriptions("acme"); len(subscriptions) != 1 {
+		t.Errorf("acme enxerga %d assinaturas, esperado 1", len(subscriptions))
+	}
+	if _, err := scheduler.Subscription("", subscription.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("consulta sem tenant = %v, esperado %v", err, ErrSubscriptionNotFound)
+	}
+	if deadLetters, _ := scheduler.DeadLetters("globex"); len(deadLetters) != 0 {
+		t.Errorf("globex enxerga %d dead letters de acme", len(deadLetters))
+	}
+	if err := scheduler.Redeliver(ctx, "globex", "d1"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("reenvio por globex = %v, esperado %v", err, ErrDeadLetterNotFound)
+	}
+	if err := scheduler.Unsubscribe("globex", subscription.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("cancelamento por globex = %v, esperado %v", err, ErrSubscriptionNotFound)
+	}
+	if err := scheduler.Unsubscribe("acme", subscription.ID); err != nil {
+		t.Errorf("cancelamento por acme: %v", err)
+	}
+}
+
+func TestSubscribeCallbackAddress(t *testing.T) {
+	scheduler, _ := newTestScheduler(t, filepath.Join(t.TempDir(), "webhooks.db"))
+
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://93.184.215.14/hooks", true},
+		{"http://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:8080/hooks", true},
+		{"http://127.0.0.1:8080/hooks", false},
+		{"http://localhost/hooks", false},
+		{"http://10.0.0.5/hooks", false},
+		{"http://192.168.1.10/hooks", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://0.0.0.0/hooks", false},
+		{"http://[::1]/hooks", false},
+		{"http://[::ffff:127.0.0.1]/hooks", false},
+		{"http://[fd00::1]/hooks", false},
+		{"http://100.64.0.1/hooks", false},
+		{"http://192.0.0.170/hooks", false},
+		{"http://198.18.0.1/hooks", false},
+		{"ftp://93.184.215.14/hooks", false},
+	}
+
+	for _, test := range tests {
+		_, err := scheduler.Subscribe(context.Background(), "", "87033080", "temp_C > 35", test.url, "")
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("Subscribe(%s) err = %v, aceito esperado %v", test.url, err, test.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidCallbackURL) {
+			t.Errorf("Subscribe(%s) err = %v, esperado %v", test.url, err, ErrInvalidCallbackURL)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		ok      bool
+	}{
+		{"93.184.215.14:443", true},
+		{"127.0.0.1:80", false},
+		{"172.16.0.1:80", false},
+		{"[fe80::1]:80", false},
+		{"[::ffff:10.0.0.1]:80", false},
+		// CGNAT, atribuições do IETF e testes de desempenho, nas bordas das faixas
+		{"100.63.255.255:443", true},
+		{"100.64.0.0:443", false},
+		{"100.127.255.255:443", false},
+		{"100.128.0.0:443", true},
+		{"192.0.0.8:443", false},
+		{"192.0.1.1:443", true},
+		{"198.17.255.255:443", true},
+		{"198.18.0.0:443", false},
+		{"198.19.255.255:443", false},
+		{"198.20.0.0:443", true},
+		{"[::ffff:100.64.0.1]:443", false},
+	}
+
+	for _, test := range tests {
+		if err := dialControl("tcp", test.address, nil); (err == nil) != test.ok {
+			t.Errorf("dialControl(%s) = %v, aceito esperado %v", test.address, err, test.ok)
+		}
+	}
+
+	if publicAddress(netip.Addr{}) {
+		t.Error("endereço vazio aceito")
+	}
+}
+
+func TestDispatcherRefusesPrivateAddress(t *testing.T) {
+	delivered := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+	}))
+	defer receiver.Close()
+
+	store, err := Open(filepath.Join(t.TempDir(), "webhooks.db"), 10)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	// assinatura guardada como se o host tivesse resolvido para um endereço público na assinatura
+	subscription := Subscription{ID: "s1", CallbackURL: receiver.URL, Secret: "0123456789abcdef", Owner: "acme"}
+	if NewDispatcher(store, 1, time.Millisecond, false).Deliver(context.Background(), subscription, Event{ID: "e1"}) {
+		t.Fatal("entrega a 127.0.0.1 aceita")
+	}
+	if delivered {
+		t.Error("o receptor em 127.0.0.1 recebeu a entrega")
+	}
+	if deadLetters, _ := store.DeadLetters(); len(deadLetters) != 1 || deadLetters[0].Owner != "acme" {
+		t.Errorf("dead letters = %+v, esperado a entrega recusada, de acme", deadLetters)
+	}
+
+	if !NewDispatcher(store, 1, time.Millisecond, true).Deliver(context.Background(), subscription, Event{ID: "e2"}) || !delivered {
+		t.Error("entrega à rede interna recusada com allowPrivateNetworks")
+	}
+}